@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	_ "time/tzdata" // embedded zone database for EventDates.TZ lookups
 
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/config"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,14 +18,41 @@ func main() {
 	// Initialize database connection
 	db := config.InitDatabase(cfg)
 
+	// Initialize storage, realtime updates and rate limiting
+	svc := config.InitServices(cfg)
+
+	// Deliver outbound email from the persistent queue
+	config.StartEmailQueue(context.Background(), db, cfg, svc)
+
+	// Start background Instagram ingestion, if configured
+	config.StartIngestion(context.Background(), db, cfg, svc)
+
+	// Start the newsletter digests, if scheduled
+	config.StartDigest(context.Background(), db, cfg, svc)
+
 	// Create Gin router
 	router := gin.Default()
 
+	// Only believe client IP headers our own proxies set; otherwise clients
+	// could pick their rate limit keys with X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.TrustedPlatform = cfg.TrustedPlatformHeader
+
 	// Setup middleware
-	// TODO: Add CORS, rate limiting, authentication middleware
+	router.Use(middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}))
+	config.InitAuth(cfg)
 
 	// Register routes
-	config.RegisterRoutes(router, db)
+	config.RegisterRoutes(router, db, cfg, svc)
 
 	// Start server
 	port := os.Getenv("PORT")
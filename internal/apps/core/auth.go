@@ -1,12 +1,25 @@
 package core
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Context keys set by the JWT middlewares
+const (
+	ContextUserID = "user_id"
+	ContextClaims = "claims"
+)
+
+// RoleAdmin is the Claims.Role value AdminRequired accepts
+const RoleAdmin = "admin"
+
 // Claims represents the JWT claims structure
 type Claims struct {
 	UserID string `json:"user_id"`
@@ -15,49 +28,214 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// JWTRequired is a middleware that requires valid JWT authentication
+// AuthConfig configures token verification
+type AuthConfig struct {
+	JWKSURL  string        // JWKS endpoint for RS/ES-signed tokens (e.g. Clerk)
+	Secret   string        // HMAC secret, used only when JWKSURL is empty (local development)
+	Issuer   string        // expected iss; skipped when empty
+	Audience string        // expected aud; skipped when empty
+	Leeway   time.Duration // clock skew tolerated on exp/nbf
+	CacheTTL time.Duration // JWKS cache lifetime
+}
+
+// Verifier validates bearer tokens and extracts their claims
+type Verifier struct {
+	config AuthConfig
+	jwks   *JWKS
+	parser *jwt.Parser
+}
+
+// NewVerifier creates a verifier from cfg
+func NewVerifier(cfg AuthConfig) *Verifier {
+	options := []jwt.ParserOption{
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &Verifier{config: cfg}
+	if cfg.JWKSURL != "" {
+		v.jwks = NewJWKS(cfg.JWKSURL, cfg.CacheTTL)
+		options = append(options, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	} else {
+		options = append(options, jwt.WithValidMethods([]string{"HS256"}))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v
+}
+
+// Verify checks the token's signature, expiry, not-before, issuer and
+// audience, and returns its claims
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, v.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clerk puts the user ID in sub; custom templates may set user_id instead
+	if claims.UserID == "" {
+		claims.UserID = claims.Subject
+	}
+	if claims.UserID == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if v.jwks == nil {
+		if v.config.Secret == "" {
+			return nil, errors.New("no verification key configured")
+		}
+		return []byte(v.config.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+	return v.jwks.Key(kid)
+}
+
+// verifier is the process-wide verifier used by the middlewares
+var verifier *Verifier
+
+// ConfigureAuth installs the verifier used by JWTRequired and OptionalJWT.
+// Call it once at startup, before routes start serving.
+func ConfigureAuth(cfg AuthConfig) {
+	verifier = NewVerifier(cfg)
+}
+
+// JWTRequired is a middleware that requires valid JWT authentication.
+// Claims already set by an earlier auth middleware are reused.
 func JWTRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: Implement JWT validation
-		// 1. Extract token from Authorization header
-		// 2. Validate token signature with Clerk public key
-		// 3. Parse claims
-		// 4. Set user_id in context
-		// 5. Call next handler or abort with 401
-
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(401, gin.H{"error": "Missing or invalid authorization header"})
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+
+		token, ok := bearerToken(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid authorization header"})
 			c.Abort()
 			return
 		}
 
-		// token := strings.TrimPrefix(authHeader, "Bearer ")
-		// TODO: Validate token and extract claims
+		claims, err := verifyToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
 
+		setClaims(c, claims)
 		c.Next()
 	}
 }
 
-// OptionalJWT is a middleware that optionally validates JWT if present
+// OptionalJWT is a middleware that optionally validates JWT if present.
+// Requests without a valid token continue anonymously.
 func OptionalJWT() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: Implement optional JWT validation
-		// Similar to JWTRequired but doesn't abort if token is missing
-		// Just sets user_id in context if valid token is present
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+		if token, ok := bearerToken(c); ok {
+			if claims, err := verifyToken(token); err == nil {
+				setClaims(c, claims)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// OptionalJWTQuery is OptionalJWT that also accepts the token in the query
+// parameter param, for clients that can't set headers (WebSocket, EventSource)
+func OptionalJWTQuery(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); ok {
+			c.Next()
+			return
+		}
+		token, ok := bearerToken(c)
+		if !ok {
+			token = c.Query(param)
+		}
+		if token != "" {
+			if claims, err := verifyToken(token); err == nil {
+				setClaims(c, claims)
+			}
+		}
 
 		c.Next()
 	}
 }
 
-// AdminRequired is a middleware that requires admin role
+// AdminRequired is a middleware that requires admin role.
+// It must run after JWTRequired.
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: Implement admin role check
-		// 1. Check if user is authenticated (should be used after JWTRequired)
-		// 2. Check if user role is "admin"
-		// 3. Call next handler or abort with 403
+		claims, ok := GetClaims(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if claims.Role != RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
 }
+
+// GetClaims returns the claims set by JWTRequired/OptionalJWT, if any
+func GetClaims(c *gin.Context) (*Claims, bool) {
+	value, ok := c.Get(ContextClaims)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
+
+// IsAdmin reports whether the request was authenticated as an admin
+func IsAdmin(c *gin.Context) bool {
+	claims, ok := GetClaims(c)
+	return ok && claims.Role == RoleAdmin
+}
+
+func verifyToken(token string) (*Claims, error) {
+	if verifier == nil {
+		return nil, fmt.Errorf("authentication is not configured")
+	}
+	return verifier.Verify(token)
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	return token, token != ""
+}
+
+func setClaims(c *gin.Context, claims *Claims) {
+	c.Set(ContextClaims, claims)
+	c.Set(ContextUserID, claims.UserID)
+}
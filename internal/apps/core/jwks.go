@@ -0,0 +1,225 @@
+package core
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultJWKSCacheTTL is how long fetched keys are trusted before a refresh
+	defaultJWKSCacheTTL = time.Hour
+
+	// jwksMinRefreshInterval throttles refetches, so tokens with bogus kids
+	// can't be used to hammer the JWKS endpoint
+	jwksMinRefreshInterval = time.Minute
+)
+
+// ErrUnknownKey is returned when no JWKS key matches a token's kid
+var ErrUnknownKey = errors.New("signing key not found in JWKS")
+
+// JWKS fetches and caches the signing keys published at a JSON Web Key Set URL
+// (e.g. Clerk's https://<frontend-api>/.well-known/jwks.json). Keys are
+// refreshed when the cache expires, or early when a token references a key ID
+// that isn't cached yet, which is how key rotation shows up.
+type JWKS struct {
+	URL    string
+	TTL    time.Duration
+	Client *http.Client
+
+	// fetchMu serializes refreshes; mu guards the fields below and is never
+	// held during a fetch, so verifying with cached keys doesn't wait on one
+	fetchMu     sync.Mutex
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	now         func() time.Time
+}
+
+// NewJWKS creates a key set backed by url; keys are fetched lazily
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	if ttl <= 0 {
+		ttl = defaultJWKSCacheTTL
+	}
+	return &JWKS{
+		URL:    url,
+		TTL:    ttl,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		keys:   map[string]crypto.PublicKey{},
+		now:    time.Now,
+	}
+}
+
+// Key returns the public key for kid, refreshing the set if it is stale or
+// the kid is unknown
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	cached, ok := j.keys[kid]
+	stale := j.now().Sub(j.fetchedAt) > j.TTL
+	j.mu.RUnlock()
+
+	if ok && !stale {
+		return cached, nil
+	}
+
+	// With a cached key, don't wait for a refresh another request is running
+	refreshErr := j.refresh(!ok)
+
+	j.mu.RLock()
+	key, found := j.keys[kid]
+	j.mu.RUnlock()
+
+	switch {
+	case found:
+		return key, nil
+	case ok && refreshErr != nil:
+		// Keep serving a known key through a failed refresh. A successful
+		// one that dropped the kid means the key was revoked.
+		return cached, nil
+	case refreshErr != nil:
+		return nil, refreshErr
+	default:
+		return nil, ErrUnknownKey
+	}
+}
+
+// refresh refetches the key set. Attempts are throttled to one per
+// jwksMinRefreshInterval; a throttled call is a no-op, as is one that finds
+// another refresh running when wait is false.
+func (j *JWKS) refresh(wait bool) error {
+	if wait {
+		j.fetchMu.Lock()
+	} else if !j.fetchMu.TryLock() {
+		return nil
+	}
+	defer j.fetchMu.Unlock()
+
+	j.mu.Lock()
+	now := j.now()
+	throttled := !j.lastAttempt.IsZero() && now.Sub(j.lastAttempt) < jwksMinRefreshInterval
+	if !throttled {
+		j.lastAttempt = now
+	}
+	j.mu.Unlock()
+	if throttled {
+		return nil
+	}
+
+	keys, err := j.fetch()
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = now
+	j.mu.Unlock()
+	return nil
+}
+
+// fetch downloads and parses the key set
+func (j *JWKS) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := j.Client.Get(j.URL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys we can't use rather than failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// jsonWebKey is a single RFC 7517 key; only RSA and EC signing keys are supported
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,320 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksServer is a local stand-in for a JWKS endpoint whose key set and
+// availability tests can change
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	failing bool
+	fetches int
+	// stall, when set, holds each request until it's closed, after signalling
+	// stalled
+	stall   chan struct{}
+	stalled chan struct{}
+}
+
+func newJWKSServer(t *testing.T, kids ...string) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		s.addKey(t, kid)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	stall, stalled := s.stall, s.stalled
+	s.mu.Unlock()
+	if stall != nil {
+		stalled <- struct{}{}
+		<-stall
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetches++
+	if s.failing {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	keys := []jsonWebKey{}
+	for kid, key := range s.keys {
+		keys = append(keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = key
+	return key
+}
+
+func (s *jwksServer) removeKey(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, kid)
+}
+
+func (s *jwksServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+// stallRequests holds requests until release is first called, and returns a
+// channel signalled as each one arrives
+func (s *jwksServer) stallRequests() (stalled <-chan struct{}, release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stall = make(chan struct{})
+	s.stalled = make(chan struct{}, 10)
+	stall := s.stall
+	var once sync.Once
+	return s.stalled, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.stall = nil
+			s.mu.Unlock()
+			close(stall)
+		})
+	}
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func (s *jwksServer) sign(t *testing.T, kid string, claims jwt.Claims) string {
+	t.Helper()
+	s.mu.Lock()
+	key := s.keys[kid]
+	s.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// fakeClock is a settable time source for JWKS
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestJWKS(url string) (*JWKS, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	j := NewJWKS(url, time.Hour)
+	j.now = clock.now
+	return j, clock
+}
+
+func TestJWKSRotation(t *testing.T) {
+	server := newJWKSServer(t, "old")
+	j, clock := newTestJWKS(server.URL)
+
+	if _, err := j.Key("old"); err != nil {
+		t.Fatalf("Key(old) = %v", err)
+	}
+
+	server.addKey(t, "new")
+
+	// An unknown kid within the throttle window doesn't refetch
+	if _, err := j.Key("new"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("throttled Key(new) error = %v, want ErrUnknownKey", err)
+	}
+	if got := server.fetchCount(); got != 1 {
+		t.Fatalf("fetches = %d, want 1", got)
+	}
+
+	clock.advance(jwksMinRefreshInterval)
+	if _, err := j.Key("new"); err != nil {
+		t.Fatalf("Key(new) after rotation = %v", err)
+	}
+	if _, err := j.Key("old"); err != nil {
+		t.Fatalf("Key(old) while still published = %v", err)
+	}
+	if got := server.fetchCount(); got != 2 {
+		t.Fatalf("fetches = %d, want 2", got)
+	}
+}
+
+func TestJWKSRevocation(t *testing.T) {
+	server := newJWKSServer(t, "revoked", "current")
+	j, clock := newTestJWKS(server.URL)
+
+	if _, err := j.Key("revoked"); err != nil {
+		t.Fatalf("Key(revoked) = %v", err)
+	}
+
+	server.removeKey("revoked")
+
+	// Cached keys are trusted until the set goes stale
+	clock.advance(30 * time.Minute)
+	if _, err := j.Key("revoked"); err != nil {
+		t.Fatalf("Key(revoked) before TTL = %v", err)
+	}
+
+	clock.advance(time.Hour)
+	if _, err := j.Key("revoked"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Key(revoked) after refresh = %v, want ErrUnknownKey", err)
+	}
+	if _, err := j.Key("current"); err != nil {
+		t.Fatalf("Key(current) = %v", err)
+	}
+}
+
+func TestJWKSRefreshFailure(t *testing.T) {
+	server := newJWKSServer(t, "cached")
+	j, clock := newTestJWKS(server.URL)
+
+	if _, err := j.Key("cached"); err != nil {
+		t.Fatalf("Key(cached) = %v", err)
+	}
+
+	server.setFailing(true)
+	clock.advance(2 * time.Hour)
+
+	if _, err := j.Key("cached"); err != nil {
+		t.Fatalf("Key(cached) during outage = %v, want the cached key", err)
+	}
+
+	clock.advance(jwksMinRefreshInterval)
+	_, err := j.Key("unknown")
+	if err == nil || errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Key(unknown) during outage = %v, want the fetch error", err)
+	}
+
+	server.setFailing(false)
+	clock.advance(jwksMinRefreshInterval)
+	if _, err := j.Key("cached"); err != nil {
+		t.Fatalf("Key(cached) after recovery = %v", err)
+	}
+}
+
+func TestVerifierLeeway(t *testing.T) {
+	server := newJWKSServer(t, "kid")
+	v := NewVerifier(AuthConfig{JWKSURL: server.URL, Leeway: time.Minute})
+
+	tests := []struct {
+		name    string
+		expires time.Duration
+		ok      bool
+	}{
+		{"valid", time.Hour, true},
+		{"expired within leeway", -30 * time.Second, true},
+		{"expired beyond leeway", -2 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := server.sign(t, "kid", jwt.RegisteredClaims{
+				Subject:   "user_1",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(tt.expires)),
+			})
+			claims, err := v.Verify(token)
+			if tt.ok && (err != nil || claims.UserID != "user_1") {
+				t.Fatalf("Verify = %v, %v; want user_1", claims, err)
+			}
+			if !tt.ok && !errors.Is(err, jwt.ErrTokenExpired) {
+				t.Fatalf("Verify error = %v, want ErrTokenExpired", err)
+			}
+		})
+	}
+
+	t.Run("not before within leeway", func(t *testing.T) {
+		token := server.sign(t, "kid", jwt.RegisteredClaims{
+			Subject:   "user_1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			NotBefore: jwt.NewNumericDate(time.Now().Add(30 * time.Second)),
+		})
+		if _, err := v.Verify(token); err != nil {
+			t.Fatalf("Verify = %v", err)
+		}
+	})
+}
+
+func TestJWKSRefreshDoesNotBlockCachedKeys(t *testing.T) {
+	server := newJWKSServer(t, "cached")
+	j, clock := newTestJWKS(server.URL)
+	if _, err := j.Key("cached"); err != nil {
+		t.Fatalf("Key(cached) = %v", err)
+	}
+
+	// A token with a new kid starts a refresh that hangs
+	server.addKey(t, "new")
+	clock.advance(j.TTL + time.Minute)
+	stalled, release := server.stallRequests()
+	defer release()
+
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := j.Key("new")
+		refreshed <- err
+	}()
+	select {
+	case <-stalled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("refresh didn't start")
+	}
+
+	// Meanwhile the cached key, though stale, still verifies at once
+	done := make(chan error, 1)
+	go func() {
+		_, err := j.Key("cached")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Key(cached) during a refresh = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Key(cached) waited for the refresh")
+	}
+
+	release()
+	if err := <-refreshed; err != nil {
+		t.Errorf("Key(new) = %v", err)
+	}
+}
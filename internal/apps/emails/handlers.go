@@ -0,0 +1,88 @@
+package emails
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler holds dependencies for email template handlers
+type Handler struct {
+	Options
+}
+
+// Options configures the email template handlers
+type Options struct {
+	// Samples returns preview data for each template by name; templates
+	// without one render with no data
+	Samples map[string]func() map[string]interface{}
+}
+
+// NewHandler creates a new email template handler
+func NewHandler(opts Options) *Handler {
+	return &Handler{Options: opts}
+}
+
+// ListTemplates handles GET /api/emails/templates - list email templates and their locales
+// Requires: Admin authentication
+func (h *Handler) ListTemplates(c *gin.Context) {
+	type template struct {
+		Name      string   `json:"name"`
+		Locales   []string `json:"locales"`
+		HasSample bool     `json:"hasSample"`
+	}
+
+	var templates []template
+	for name, locales := range services.EmailTemplateNames() {
+		templates = append(templates, template{Name: name, Locales: locales, HasSample: h.Samples[name] != nil})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"locales":   services.EmailLocales,
+	})
+}
+
+// PreviewTemplate handles GET /api/emails/templates/:name/preview - render a template with sample data
+// Requires: Admin authentication
+// Query params:
+//   - locale: e.g. "fr" (default "en")
+//   - format: json (default, subject, HTML and text), html or text
+func (h *Handler) PreviewTemplate(c *gin.Context) {
+	name := c.Param("name")
+	if _, ok := services.EmailTemplateNames()[name]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email template not found"})
+		return
+	}
+
+	locale := c.DefaultQuery("locale", services.DefaultLocale)
+	if !services.SupportedLocale(locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+		return
+	}
+
+	var data map[string]interface{}
+	if sample := h.Samples[name]; sample != nil {
+		data = sample()
+	}
+	email, err := services.RenderTemplate(name, locale, data)
+	if err != nil {
+		// Admin-only, and the template error is what they need to see
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, email)
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(email.Text))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, html or text"})
+	}
+}
@@ -0,0 +1,17 @@
+package emails
+
+import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers email template routes
+func RegisterRoutes(rg *gin.RouterGroup, opts Options) {
+	handler := NewHandler(opts)
+
+	emails := rg.Group("/emails", core.JWTRequired(), core.AdminRequired())
+	{
+		emails.GET("/templates", handler.ListTemplates)
+		emails.GET("/templates/:name/preview", handler.PreviewTemplate)
+	}
+}
@@ -0,0 +1,497 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// duplicateThreshold is the score at which a candidate is flagged
+	duplicateThreshold = 0.6
+
+	// duplicateWindow bounds how far apart two occurrences may start for
+	// their events to be compared at all
+	duplicateWindow = 12 * time.Hour
+
+	// maxDuplicateCandidates caps how many events are scored per check
+	maxDuplicateCandidates = 50
+
+	// maxFlagsPerEvent caps how many flags one event can raise
+	maxFlagsPerEvent = 5
+
+	// maxPosterDistance is the largest perceptual hash distance at which two
+	// posters count as the same image
+	maxPosterDistance = 10
+)
+
+// DuplicateMatch is a scored duplicate candidate
+type DuplicateMatch struct {
+	Event   Events   `json:"event"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// scoreDuplicate rates how likely a and b (with EventDates loaded) are the
+// same event, from 0 to 1, and says why
+func scoreDuplicate(a, b *Events) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	title := utils.TextSimilarity(deref(a.Title), deref(b.Title))
+	score += 0.45 * title
+	if title >= 0.6 {
+		reasons = append(reasons, "similar title")
+	}
+
+	switch gap := closestStartGap(a.EventDates, b.EventDates); {
+	case gap <= time.Hour:
+		score += 0.25
+		reasons = append(reasons, "same start time")
+	case gap <= duplicateWindow:
+		score += 0.125
+		reasons = append(reasons, "same day")
+	}
+
+	location := utils.TextSimilarity(deref(a.Location), deref(b.Location))
+	score += 0.15 * location
+	if location >= 0.6 {
+		reasons = append(reasons, "similar location")
+	}
+
+	if handle := utils.NormalizeHandle(deref(a.IGHandle)); handle != "" && handle == utils.NormalizeHandle(deref(b.IGHandle)) {
+		score += 0.1
+		reasons = append(reasons, "same Instagram handle")
+	}
+
+	if a.ImagePHash != nil && b.ImagePHash != nil {
+		if distance, err := services.HammingDistance(*a.ImagePHash, *b.ImagePHash); err == nil && distance <= maxPosterDistance {
+			score += 0.2
+			reasons = append(reasons, "same poster image")
+		}
+	}
+
+	return math.Min(math.Round(score*100)/100, 1), reasons
+}
+
+// closestStartGap is the smallest distance between any two occurrence starts
+func closestStartGap(a, b []EventDates) time.Duration {
+	closest := time.Duration(math.MaxInt64)
+	for _, x := range a {
+		for _, y := range b {
+			gap := x.DtstartUTC.Sub(y.DtstartUTC)
+			if gap < 0 {
+				gap = -gap
+			}
+			closest = min(closest, gap)
+		}
+	}
+	return closest
+}
+
+// findDuplicates scores live events that share an occurrence window or a
+// poster with event (EventDates loaded) and returns matches at or above
+// duplicateThreshold, best first
+func findDuplicates(db *gorm.DB, event *Events) ([]DuplicateMatch, error) {
+	if len(event.EventDates) == 0 {
+		return nil, nil
+	}
+
+	overlaps := make([]string, 0, len(event.EventDates))
+	args := make([]interface{}, 0, 2*len(event.EventDates)+1)
+	for _, date := range event.EventDates {
+		overlaps = append(overlaps, "dd.dtstart_utc BETWEEN ? AND ?")
+		args = append(args, date.DtstartUTC.Add(-duplicateWindow), date.DtstartUTC.Add(duplicateWindow))
+	}
+	condition := "EXISTS (SELECT 1 FROM event_dates dd WHERE dd.event_id = events.id AND dd.deleted_at IS NULL AND (" +
+		strings.Join(overlaps, " OR ") + "))"
+	if event.ImagePHash != nil {
+		condition += " OR events.image_phash = ?"
+		args = append(args, *event.ImagePHash)
+	}
+
+	var candidates []Events
+	err := db.Where("events.id <> ?", event.ID).
+		Where("events.status IS DISTINCT FROM ?", EventStatusRejected).
+		Where(condition, args...).
+		Preload("EventDates").
+		Order("events.id DESC").
+		Limit(maxDuplicateCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []DuplicateMatch
+	for i := range candidates {
+		score, reasons := scoreDuplicate(event, &candidates[i])
+		if score >= duplicateThreshold {
+			matches = append(matches, DuplicateMatch{Event: candidates[i], Score: score, Reasons: reasons})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+
+	return matches, nil
+}
+
+// flagDuplicates records DuplicateFlags for event's likely duplicates.
+// Call it whenever a new event arrives (submission, ingestion).
+func flagDuplicates(tx *gorm.DB, event *Events) ([]DuplicateMatch, error) {
+	matches, err := findDuplicates(tx, event)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	if len(matches) > maxFlagsPerEvent {
+		matches = matches[:maxFlagsPerEvent]
+	}
+
+	flags := make([]DuplicateFlag, len(matches))
+	for i, match := range matches {
+		flags[i] = DuplicateFlag{
+			EventID:       event.ID,
+			DuplicateOfID: match.Event.ID,
+			Score:         match.Score,
+			Reasons:       match.Reasons,
+			Status:        DuplicateStatusOpen,
+		}
+	}
+	err = tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&flags).Error
+	return matches, err
+}
+
+// duplicateScoredFields are the SubmitEvent fields scoreDuplicate and
+// findDuplicates read; editing any of them calls for new flags
+var duplicateScoredFields = []string{"title", "location", "ig_handle", "occurrences", "source_image_url"}
+
+// reflagDuplicates replaces event's open duplicate flags with ones for its
+// current details, after an edit. Dismissed and merged flags are kept, so an
+// admin's decision isn't raised again.
+func reflagDuplicates(tx *gorm.DB, event *Events) error {
+	if err := tx.Where("event_id = ? AND status = ?", event.ID, DuplicateStatusOpen).Delete(&DuplicateFlag{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("event_id = ?", event.ID).Find(&event.EventDates).Error; err != nil {
+		return err
+	}
+	_, err := flagDuplicates(tx, event)
+	return err
+}
+
+// ListDuplicateFlags handles GET /api/events/duplicates - duplicate review queue
+// Requires: Admin authentication
+// Query params:
+//   - status: open (default), dismissed, merged or all
+//   - limit: number of results (default 20)
+func (h *Handler) ListDuplicateFlags(c *gin.Context) {
+	query := h.DB.Model(&DuplicateFlag{})
+	switch status := c.DefaultQuery("status", DuplicateStatusOpen); status {
+	case "all":
+	case DuplicateStatusOpen, DuplicateStatusDismissed, DuplicateStatusMerged:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	limit := defaultEventPageSize
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(parsed, maxEventPageSize)
+	}
+
+	var flags []DuplicateFlag
+	err := query.
+		Preload("Event", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Event.EventDates").
+		Preload("DuplicateOf", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("DuplicateOf.EventDates").
+		Order("score DESC, id ASC").
+		Limit(limit).
+		Find(&flags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duplicate flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": flags})
+}
+
+// GetEventDuplicates handles GET /api/events/:id/duplicates - score likely
+// duplicates of an event on demand
+// Requires: Admin authentication
+func (h *Handler) GetEventDuplicates(c *gin.Context) {
+	var event Events
+	if err := h.DB.Preload("EventDates").First(&event, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	matches, err := findDuplicates(h.DB, &event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicates"})
+		return
+	}
+	if matches == nil {
+		matches = []DuplicateMatch{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": matches})
+}
+
+// DismissDuplicateFlag handles POST /api/events/duplicates/:id/dismiss -
+// mark a flag as not a duplicate
+// Requires: Admin authentication
+func (h *Handler) DismissDuplicateFlag(c *gin.Context) {
+	now := time.Now()
+	actor := c.GetString(core.ContextUserID)
+
+	result := h.DB.Model(&DuplicateFlag{}).
+		Where("id = ? AND status = ?", c.Param("id"), DuplicateStatusOpen).
+		Updates(map[string]interface{}{"status": DuplicateStatusDismissed, "resolved_by": actor, "resolved_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss duplicate flag"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Open duplicate flag not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Duplicate flag dismissed"})
+}
+
+// MergeEvents handles POST /api/events/merge - fold one event into another
+// Requires: Admin authentication
+// Body: { "keep_id": 1, "merge_id": 2 }
+//
+// The kept event gains the merged event's dates (identical starts are
+// collapsed), interested users, reaction counts, categories and any details
+// it lacks. The merged event is soft-deleted, its open flags resolved and
+// its pending submission rejected as a duplicate.
+func (h *Handler) MergeEvents(c *gin.Context) {
+	var body struct {
+		KeepID  uint `json:"keep_id"`
+		MergeID uint `json:"merge_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.KeepID == 0 || body.MergeID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep_id and merge_id are required"})
+		return
+	}
+	if body.KeepID == body.MergeID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot merge an event into itself"})
+		return
+	}
+
+	var loser *Events
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		loser, err = mergeEvents(tx, body.KeepID, body.MergeID, c.GetString(core.ContextUserID))
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge events"})
+		return
+	}
+
+	var merged Events
+	if err := h.DB.Preload("EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).First(&merged, body.KeepID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load merged event"})
+		return
+	}
+
+	// Only published events were ever announced publicly
+	deleted := gin.H{"event_id": body.MergeID, "merged_into": body.KeepID}
+	if loser.Status != nil && *loser.Status == EventStatusConfirmed {
+		publish(h.Realtime, "event.deleted", deleted, realtime.TopicEvents, realtime.EventTopic(body.MergeID))
+	} else {
+		publish(h.Realtime, "event.deleted", deleted, realtime.TopicModeration)
+	}
+	if merged.Status != nil && *merged.Status == EventStatusConfirmed {
+		publishEvent(h.Realtime, "event.updated", merged)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"event": merged})
+}
+
+// mergeEvents folds loserID into winnerID and returns the deleted loser; see
+// MergeEvents
+func mergeEvents(tx *gorm.DB, winnerID, loserID uint, actor string) (*Events, error) {
+	// Lock both rows in id order so concurrent merges can't deadlock
+	var locked []Events
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", []uint{winnerID, loserID}).
+		Order("id ASC").
+		Find(&locked).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(locked) != 2 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	winner, loser := locked[0], locked[1]
+	if winner.ID != winnerID {
+		winner, loser = loser, winner
+	}
+
+	// Dates: move the loser's, dropping those the winner already has
+	err = tx.Where("event_id = ? AND dtstart_utc IN (?)", loser.ID,
+		tx.Model(&EventDates{}).Select("dtstart_utc").Where("event_id = ?", winner.ID)).
+		Delete(&EventDates{}).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&EventDates{}).Where("event_id = ?", loser.ID).Update("event_id", winner.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// Interests: (event_id, user_id) is unique even across soft-deleted rows,
+	// so revive the winner's rows for users interested in the loser, move the
+	// rest and retire the loser's leftovers
+	err = tx.Exec(`UPDATE event_interests SET deleted_at = NULL, updated_at = ?
+		WHERE event_id = ? AND deleted_at IS NOT NULL
+		AND user_id IN (SELECT user_id FROM event_interests WHERE event_id = ? AND deleted_at IS NULL)`,
+		time.Now(), winner.ID, loser.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Exec(`UPDATE event_interests SET event_id = ?, updated_at = ?
+		WHERE event_id = ? AND user_id NOT IN (SELECT user_id FROM event_interests WHERE event_id = ?)`,
+		winner.ID, time.Now(), loser.ID, winner.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("event_id = ?", loser.ID).Delete(&EventInterest{}).Error; err != nil {
+		return nil, err
+	}
+
+	// Reactions and details
+	mergeEventDetails(&winner, &loser)
+	if err := mergeReactions(tx, &winner, &loser); err != nil {
+		return nil, err
+	}
+	if err := tx.Omit(clause.Associations).Save(&winner).Error; err != nil {
+		return nil, err
+	}
+
+	// Resolve flags touching the loser; flags between the pair count as merged
+	now := time.Now()
+	err = tx.Model(&DuplicateFlag{}).
+		Where("status = ? AND (event_id = ? OR duplicate_of_id = ?)", DuplicateStatusOpen, loser.ID, loser.ID).
+		Updates(map[string]interface{}{"status": DuplicateStatusMerged, "resolved_by": actor, "resolved_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// A pending submission of the loser can no longer be approved
+	var submissions []EventSubmission
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("created_event_id = ? AND status = ?", loser.ID, SubmissionStatusPending).
+		Find(&submissions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, submission := range submissions {
+		reason := fmt.Sprintf("Duplicate of event %d", winner.ID)
+		submission.Status = SubmissionStatusRejected
+		submission.RejectionReason = &reason
+		submission.ReviewedAt = &now
+		submission.ReviewedBy = &actor
+		if err := tx.Omit(clause.Associations).Save(&submission).Error; err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, submission.ID, "merged", actor, map[string]any{"merged_into": winner.ID}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &loser, tx.Delete(&loser).Error
+}
+
+// mergeEventDetails sums reactions, unions categories and fills the
+// winner's empty fields from the loser
+func mergeEventDetails(winner, loser *Events) {
+	if len(loser.Reactions) > 0 {
+		if winner.Reactions == nil {
+			winner.Reactions = map[string]int{}
+		}
+		for emoji, count := range loser.Reactions {
+			winner.Reactions[emoji] += count
+		}
+	}
+
+	seen := make(map[string]bool, len(winner.Categories))
+	for _, category := range winner.Categories {
+		seen[category] = true
+	}
+	for _, category := range loser.Categories {
+		if !seen[category] {
+			seen[category] = true
+			winner.Categories = append(winner.Categories, category)
+		}
+	}
+
+	for _, field := range []struct{ winner, loser **string }{
+		{&winner.Description, &loser.Description},
+		{&winner.Location, &loser.Location},
+		{&winner.SourceURL, &loser.SourceURL},
+		{&winner.Food, &loser.Food},
+		{&winner.School, &loser.School},
+		{&winner.ClubType, &loser.ClubType},
+		{&winner.IGHandle, &loser.IGHandle},
+		{&winner.DiscordHandle, &loser.DiscordHandle},
+		{&winner.XHandle, &loser.XHandle},
+		{&winner.TiktokHandle, &loser.TiktokHandle},
+		{&winner.FBHandle, &loser.FBHandle},
+		{&winner.OtherHandle, &loser.OtherHandle},
+	} {
+		if deref(*field.winner) == "" && deref(*field.loser) != "" {
+			*field.winner = *field.loser
+		}
+	}
+
+	if winner.SourceImageURL == nil && loser.SourceImageURL != nil {
+		winner.SourceImageURL = loser.SourceImageURL
+		winner.ImageVariants = loser.ImageVariants
+		winner.ImageSizes = loser.ImageSizes
+		winner.ImagePHash = loser.ImagePHash
+	}
+	if winner.Price == nil {
+		winner.Price = loser.Price
+	}
+	if winner.PostedAt == nil {
+		winner.PostedAt = loser.PostedAt
+	}
+	winner.Registration = winner.Registration || loser.Registration
+	winner.LikesCount = max(winner.LikesCount, loser.LikesCount)
+	winner.CommentsCount = max(winner.CommentsCount, loser.CommentsCount)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,380 @@
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// scoredEvent builds an unsaved event with the fields scoreDuplicate reads
+func scoredEvent(title, location, handle, phash string, starts ...time.Time) *Events {
+	event := &Events{Title: &title}
+	if location != "" {
+		event.Location = &location
+	}
+	if handle != "" {
+		event.IGHandle = &handle
+	}
+	if phash != "" {
+		event.ImagePHash = &phash
+	}
+	for _, start := range starts {
+		event.EventDates = append(event.EventDates, EventDates{DtstartUTC: start})
+	}
+	return event
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	start := time.Date(2026, 11, 5, 23, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	base := scoredEvent("Games Night", "SLC Great Hall", "@uwgamesclub", "0000000000000000", start)
+
+	tests := []struct {
+		name    string
+		other   *Events
+		score   float64
+		reasons string
+	}{
+		{
+			name:    "identical",
+			other:   scoredEvent("Games Night", "SLC Great Hall", "uwgamesclub", "0000000000000000", start),
+			score:   1,
+			reasons: "similar title, same start time, similar location, same Instagram handle, same poster image",
+		},
+		{
+			name:    "same title and start",
+			other:   scoredEvent("🎉 GAMES NIGHT!!", "", "", "", start.Add(30*time.Minute)),
+			score:   0.7,
+			reasons: "similar title, same start time",
+		},
+		{
+			name:    "same title, hours apart",
+			other:   scoredEvent("Games Night", "", "", "", start.Add(6*time.Hour)),
+			score:   0.57,
+			reasons: "similar title, same day",
+		},
+		{
+			name:    "same title and place, hours apart",
+			other:   scoredEvent("Games Night", "SLC Great Hall", "", "", start.Add(6*time.Hour)),
+			score:   0.73,
+			reasons: "similar title, same day, similar location",
+		},
+		{
+			name:    "same title, a day apart",
+			other:   scoredEvent("Games Night", "SLC Great Hall", "", "", start.Add(13*time.Hour)),
+			score:   0.6,
+			reasons: "similar title, similar location",
+		},
+		{
+			name:    "another event in the same slot",
+			other:   scoredEvent("Bake Sale", "SLC Great Hall", "https://www.instagram.com/UWGamesClub/", "", start),
+			score:   0.5,
+			reasons: "same start time, similar location, same Instagram handle",
+		},
+		{
+			name:    "poster within the distance",
+			other:   scoredEvent("Bake Sale", "", "", "00000000000003ff", start),
+			score:   0.45,
+			reasons: "same start time, same poster image",
+		},
+		{
+			name:    "poster beyond the distance",
+			other:   scoredEvent("Bake Sale", "", "", "0000000000000fff", start),
+			score:   0.25,
+			reasons: "same start time",
+		},
+		{
+			name:    "closest of several occurrences",
+			other:   scoredEvent("Games Night", "", "", "", start.Add(week+30*time.Minute), start.Add(-week)),
+			score:   0.45,
+			reasons: "similar title",
+		},
+		{
+			name:    "invalid poster hash",
+			other:   scoredEvent("Games Night", "", "", "not-a-hash", start),
+			score:   0.7,
+			reasons: "similar title, same start time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := scoreDuplicate(base, tt.other)
+			if score != tt.score || strings.Join(reasons, ", ") != tt.reasons {
+				t.Errorf("score = %v (%s), want %v (%s)", score, strings.Join(reasons, ", "), tt.score, tt.reasons)
+			}
+			if reverse, _ := scoreDuplicate(tt.other, base); reverse != score {
+				t.Errorf("scores %v one way and %v the other", score, reverse)
+			}
+		})
+	}
+
+	// Recurring events are compared by their closest occurrences
+	recurring := scoredEvent("Games Night", "", "", "", start.Add(-week), start.Add(week))
+	if score, _ := scoreDuplicate(recurring, scoredEvent("Games Night", "", "", "", start.Add(week+time.Hour))); score < duplicateThreshold {
+		t.Errorf("recurring match scored %v, want at least %v", score, duplicateThreshold)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	db := openEventsDB(t)
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+	phash := "0f0f0f0f0f0f0f0f"
+
+	event := createEvent(t, db, "Games Night", EventStatusPending, nil, start)
+	db.Model(event).Updates(&Events{Location: strPtr("SLC"), ImagePHash: &phash})
+
+	sameSlot := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start.Add(time.Hour))
+	db.Model(sameSlot).Update("location", "SLC")
+	sameDay := createEvent(t, db, "Games Night", EventStatusPending, nil, start.Add(8*time.Hour))
+	db.Model(sameDay).Update("location", "SLC")
+	// Outside the window, but found by its poster
+	samePoster := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start.Add(14*24*time.Hour))
+	db.Model(samePoster).Update("image_phash", phash)
+
+	createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start.Add(7*24*time.Hour))
+	createEvent(t, db, "Games Night", EventStatusRejected, nil, start)
+	createEvent(t, db, "Karaoke", EventStatusConfirmed, &past, start)
+	deleted := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start)
+	db.Delete(deleted)
+
+	db.Preload("EventDates").First(event, event.ID)
+	matches, err := findDuplicates(db, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint
+	for _, match := range matches {
+		got = append(got, match.Event.ID)
+		if match.Score < duplicateThreshold {
+			t.Errorf("event %d matched with score %v", match.Event.ID, match.Score)
+		}
+	}
+	want := []uint{sameSlot.ID, sameDay.ID, samePoster.ID}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("matches = %v, want %v, best first", got, want)
+	}
+
+	// Without dates there's nothing to compare
+	if matches, _ := findDuplicates(db, &Events{Title: strPtr("Games Night")}); matches != nil {
+		t.Errorf("matched %d events for an event with no dates", len(matches))
+	}
+}
+
+func TestFlagDuplicatesCapsAndIsIdempotent(t *testing.T) {
+	db := openEventsDB(t)
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+	for i := 0; i < maxFlagsPerEvent+2; i++ {
+		createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start.Add(time.Duration(i)*time.Minute))
+	}
+	event := createEvent(t, db, "Games Night", EventStatusPending, nil, start)
+
+	for i := 0; i < 2; i++ {
+		if _, err := flagDuplicates(db, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var flags []DuplicateFlag
+	db.Where("event_id = ?", event.ID).Find(&flags)
+	if len(flags) != maxFlagsPerEvent {
+		t.Errorf("%d flags, want %d", len(flags), maxFlagsPerEvent)
+	}
+}
+
+// recordingPublisher records the topics each message was published to
+type recordingPublisher struct {
+	messages []string
+}
+
+func (p *recordingPublisher) Publish(msgType string, data interface{}, topics ...string) {
+	p.messages = append(p.messages, msgType+" "+strings.Join(topics, ","))
+}
+
+func mergeBody(keepID, mergeID uint) string {
+	return fmt.Sprintf(`{"keep_id": %d, "merge_id": %d}`, keepID, mergeID)
+}
+
+// merge calls MergeEvents with body as admin_1
+func merge(h *Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/events/merge", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(core.ContextUserID, "admin_1")
+	h.MergeEvents(c)
+	return w
+}
+
+// liveInterests returns the users interested in eventID, sorted
+func liveInterests(t *testing.T, db *gorm.DB, eventID uint) []string {
+	t.Helper()
+	var users []string
+	if err := db.Model(&EventInterest{}).Where("event_id = ?", eventID).Order("user_id").Pluck("user_id", &users).Error; err != nil {
+		t.Fatal(err)
+	}
+	return users
+}
+
+func TestMergeEvents(t *testing.T) {
+	db := openEventsDB(t)
+	publisher := &recordingPublisher{}
+	h := NewHandler(db, Options{Realtime: publisher})
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+
+	winner := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start)
+	loser := createEvent(t, db, "Games Night!", EventStatusConfirmed, &past, start, start.Add(7*24*time.Hour))
+	other := createEvent(t, db, "Game Night", EventStatusPending, nil, start)
+	db.Model(winner).Updates(&Events{Location: strPtr("SLC"), Categories: []string{"Social"}, Reactions: map[string]int{"🔥": 2}})
+	db.Model(loser).Updates(&Events{
+		Location:    strPtr("Student Life Centre"),
+		Description: strPtr("Bring a friend"),
+		Categories:  []string{"Games", "Social"},
+		Reactions:   map[string]int{"🔥": 2, "🎉": 1},
+		LikesCount:  40,
+	})
+
+	db.Create(&[]EventReaction{
+		{EventID: winner.ID, UserID: "u1", Emoji: "🔥"},
+		{EventID: winner.ID, UserID: "u2", Emoji: "🔥"},
+		{EventID: loser.ID, UserID: "u1", Emoji: "🔥"},
+		{EventID: loser.ID, UserID: "u3", Emoji: "🔥"},
+		{EventID: loser.ID, UserID: "u3", Emoji: "🎉"},
+	})
+	db.Create(&[]EventInterest{
+		{EventID: winner.ID, UserID: "both"},
+		{EventID: winner.ID, UserID: "lapsed"},
+		{EventID: loser.ID, UserID: "both"},
+		{EventID: loser.ID, UserID: "lapsed"},
+		{EventID: loser.ID, UserID: "loser-only"},
+	})
+	db.Where("event_id = ? AND user_id = ?", winner.ID, "lapsed").Delete(&EventInterest{})
+
+	db.Create(&[]DuplicateFlag{
+		{EventID: loser.ID, DuplicateOfID: winner.ID, Score: 0.9, Status: DuplicateStatusOpen},
+		{EventID: other.ID, DuplicateOfID: loser.ID, Score: 0.8, Status: DuplicateStatusOpen},
+		{EventID: other.ID, DuplicateOfID: winner.ID, Score: 0.8, Status: DuplicateStatusOpen},
+	})
+	submission := &EventSubmission{SubmittedBy: "user_1", SubmittedAt: time.Now(), Status: SubmissionStatusPending, CreatedEventID: loser.ID}
+	db.Omit("CreatedEvent").Create(submission)
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{`{}`, http.StatusBadRequest},
+		{`{"keep_id": 1}`, http.StatusBadRequest},
+		{`{"keep_id": 1, "merge_id": 1}`, http.StatusBadRequest},
+		{`{"keep_id": 1, "merge_id": 999}`, http.StatusNotFound},
+	} {
+		if w := merge(h, tt.body); w.Code != tt.code {
+			t.Errorf("merge %s = %d, want %d", tt.body, w.Code, tt.code)
+		}
+	}
+
+	if w := merge(h, mergeBody(winner.ID, loser.ID)); w.Code != http.StatusOK {
+		t.Fatalf("merge = %d %s", w.Code, w.Body)
+	}
+
+	merged := mustEvent(t, db, winner.ID)
+	if *merged.Location != "SLC" || deref(merged.Description) != "Bring a friend" || merged.LikesCount != 40 {
+		t.Errorf("merged details = %q, %q, %d; want the winner's location and the loser's description and likes",
+			*merged.Location, deref(merged.Description), merged.LikesCount)
+	}
+	if strings.Join(merged.Categories, ",") != "Social,Games" {
+		t.Errorf("categories = %v", merged.Categories)
+	}
+
+	var dates []EventDates
+	db.Where("event_id = ?", winner.ID).Order("dtstart_utc").Find(&dates)
+	if len(dates) != 2 || !dates[0].DtstartUTC.Equal(start) || !dates[1].DtstartUTC.Equal(start.Add(7*24*time.Hour)) {
+		t.Errorf("dates = %+v, want the shared start once and the loser's other date", dates)
+	}
+
+	// Counters match the rows behind them: u1's 🔥 on both counts once
+	var reactions []EventReaction
+	db.Where("event_id = ?", winner.ID).Find(&reactions)
+	counted := map[string]int{}
+	for _, reaction := range reactions {
+		counted[reaction.Emoji]++
+	}
+	if len(reactions) != 4 || counted["🔥"] != 3 || counted["🎉"] != 1 ||
+		len(merged.Reactions) != 2 || merged.Reactions["🔥"] != 3 || merged.Reactions["🎉"] != 1 {
+		t.Errorf("reactions = %v with rows %v, want 🔥 3 and 🎉 1", merged.Reactions, counted)
+	}
+
+	if users := liveInterests(t, db, winner.ID); strings.Join(users, ",") != "both,lapsed,loser-only" {
+		t.Errorf("interested users = %v", users)
+	}
+	// Each user keeps one row, and none are left interested in the loser
+	var winnerRows int64
+	db.Unscoped().Model(&EventInterest{}).Where("event_id = ?", winner.ID).Count(&winnerRows)
+	if winnerRows != 3 {
+		t.Errorf("winner has %d interest rows, want one per user", winnerRows)
+	}
+	if users := liveInterests(t, db, loser.ID); len(users) != 0 {
+		t.Errorf("users %v are still interested in the merged-away event", users)
+	}
+
+	var flags []DuplicateFlag
+	db.Order("id").Find(&flags)
+	statuses := []string{flags[0].Status, flags[1].Status, flags[2].Status}
+	if strings.Join(statuses, ",") != "merged,merged,open" || flags[0].ResolvedBy == nil || *flags[0].ResolvedBy != "admin_1" {
+		t.Errorf("flag statuses = %v, want flags touching the loser merged", statuses)
+	}
+
+	got := reloadSubmission(t, db, submission.ID)
+	if got.Status != SubmissionStatusRejected || deref(got.RejectionReason) != fmt.Sprintf("Duplicate of event %d", winner.ID) {
+		t.Errorf("loser's submission = %s (%s), want rejected as a duplicate", got.Status, deref(got.RejectionReason))
+	}
+	if audit := auditTrail(t, db, submission.ID); len(audit) != 1 || audit[0].Action != "merged" {
+		t.Errorf("audit = %+v", audit)
+	}
+
+	if err := db.First(&Events{}, loser.ID).Error; err == nil {
+		t.Error("merged-away event is still live")
+	}
+	if w := merge(h, mergeBody(winner.ID, loser.ID)); w.Code != http.StatusNotFound {
+		t.Errorf("merging it again = %d, want 404", w.Code)
+	}
+
+	sort.Strings(publisher.messages)
+	if len(publisher.messages) != 2 ||
+		publisher.messages[0] != "event.deleted "+realtime.TopicEvents+","+realtime.EventTopic(loser.ID) ||
+		!strings.HasPrefix(publisher.messages[1], "event.updated "+realtime.TopicEvents+","+realtime.EventTopic(winner.ID)) {
+		t.Errorf("published %q", publisher.messages)
+	}
+}
+
+func TestMergeUnpublishedEventStaysPrivate(t *testing.T) {
+	db := openEventsDB(t)
+	publisher := &recordingPublisher{}
+	h := NewHandler(db, Options{Realtime: publisher})
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+
+	winner := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, start)
+	loser := createEvent(t, db, "Games Night", EventStatusPending, nil, start)
+	if w := merge(h, mergeBody(winner.ID, loser.ID)); w.Code != http.StatusOK {
+		t.Fatalf("merge = %d %s", w.Code, w.Body)
+	}
+	for _, message := range publisher.messages {
+		if strings.HasPrefix(message, "event.deleted") && message != "event.deleted "+realtime.TopicModeration {
+			t.Errorf("deleting a pending event was announced as %q", message)
+		}
+	}
+}
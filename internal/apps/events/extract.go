@@ -0,0 +1,188 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxScreenshotSize caps uploaded screenshots
+	maxScreenshotSize = 10 << 20
+
+	// extractionModel is the vision model used for screenshot extraction
+	extractionModel = "gpt-4o-mini"
+
+	// extractionTimeout bounds the vision model call
+	extractionTimeout = 60 * time.Second
+)
+
+// screenshotTypes maps accepted (sniffed) image types to file extensions
+var screenshotTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// ExtractEventFromScreenshot handles POST /api/events/extract/ - extract event from screenshot
+// Requires: JWT authentication, rate limiting
+// Body: multipart/form-data with screenshot file
+//
+// Upload problems are 400s. Storage or model failures still answer 200 with
+// whatever could be extracted, complete=false and warnings, so the client can
+// fall back to manual entry. Each draft in events is a SubmitEvent body.
+func (h *Handler) ExtractEventFromScreenshot(c *gin.Context) {
+	data, contentType, err := readScreenshot(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var warnings []string
+
+	image := &services.StoredImage{}
+	if stored, err := h.storeScreenshot(c, data); err != nil {
+		warnings = append(warnings, "the screenshot could not be saved")
+	} else {
+		image = stored
+	}
+
+	var results []services.EventExtractionResult
+	extracted := false
+	if h.OpenAI == nil {
+		warnings = append(warnings, "event extraction is not available")
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), extractionTimeout)
+		results, err = h.OpenAI.ExtractEventsFromImage(ctx, data, contentType, extractionModel)
+		cancel()
+		if err != nil {
+			warnings = append(warnings, "event details could not be extracted from the screenshot")
+		} else {
+			extracted = true
+		}
+	}
+
+	drafts := make([]gin.H, 0, len(results))
+	for _, result := range results {
+		drafts = append(drafts, extractionDraft(result, image))
+		warnings = append(warnings, result.Warnings...)
+	}
+	if len(drafts) == 0 {
+		if extracted {
+			warnings = append(warnings, "no event was found in the screenshot")
+		}
+		drafts = append(drafts, extractionDraft(services.EventExtractionResult{}, image))
+	}
+
+	// The first draft is repeated at the top level for single-event clients
+	response := gin.H{}
+	for key, value := range drafts[0] {
+		response[key] = value
+	}
+	response["events"] = drafts
+	response["complete"] = len(warnings) == 0
+	response["warnings"] = append([]string{}, warnings...)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readScreenshot reads the "screenshot" (or "file") form file, enforcing the
+// size limit and checking its sniffed content type
+func readScreenshot(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxScreenshotSize+1<<20)
+
+	header, err := c.FormFile("screenshot")
+	if err != nil {
+		header, err = c.FormFile("file")
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("a screenshot file is required")
+	}
+	if header.Size > maxScreenshotSize {
+		return nil, "", fmt.Errorf("screenshot must be at most %d MB", maxScreenshotSize>>20)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read the screenshot")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxScreenshotSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read the screenshot")
+	}
+	if len(data) > maxScreenshotSize {
+		return nil, "", fmt.Errorf("screenshot must be at most %d MB", maxScreenshotSize>>20)
+	}
+
+	// Trust the bytes, not the client's Content-Type
+	contentType := http.DetectContentType(data)
+	if _, ok := screenshotTypes[contentType]; !ok {
+		return nil, "", fmt.Errorf("screenshot must be a JPEG, PNG, WebP or GIF image")
+	}
+
+	return data, contentType, nil
+}
+
+// storeScreenshot processes and stores the screenshot's variants under a
+// random key prefix, and records the upload so SubmitEvent can attach them
+func (h *Handler) storeScreenshot(c *gin.Context, data []byte) (*services.StoredImage, error) {
+	if h.Images == nil {
+		return nil, fmt.Errorf("image storage is not configured")
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("screenshots/%s/%s", time.Now().UTC().Format("2006/01/02"), hex.EncodeToString(raw))
+
+	stored, err := h.Images.StoreImage(c.Request.Context(), prefix, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.DB.Create(&UploadedImage{
+		URL:        stored.URL,
+		Variants:   stored.Variants,
+		Sizes:      stored.Sizes,
+		PHash:      stored.PHash,
+		UploadedBy: c.GetString(core.ContextUserID),
+	}).Error
+	return stored, err
+}
+
+// extractionDraft renders an extraction result as a SubmitEvent body
+func extractionDraft(result services.EventExtractionResult, image *services.StoredImage) gin.H {
+	occurrences := result.Occurrences
+	if occurrences == nil {
+		occurrences = []map[string]interface{}{}
+	}
+	categories := result.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+
+	return gin.H{
+		"source_image_url": image.URL,
+		"image_variants":   image.Variants,
+		"title":            result.Title,
+		"description":      result.Description,
+		"location":         result.Location,
+		"price":            result.Price,
+		"food":             result.Food,
+		"registration":     result.Registration,
+		"categories":       categories,
+		"occurrences":      occurrences,
+	}
+}
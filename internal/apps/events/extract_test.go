@@ -0,0 +1,117 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// stubVision is a VisionClient answering every request with reply or err
+type stubVision struct {
+	reply string
+	err   error
+}
+
+func (s stubVision) Complete(ctx context.Context, req services.VisionRequest) (string, error) {
+	return s.reply, s.err
+}
+
+// screenshotRequest builds a multipart upload of a small PNG
+func screenshotRequest(t *testing.T) *http.Request {
+	t.Helper()
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("screenshot", "poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write(img.Bytes())
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/extract/", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func extractScreenshot(t *testing.T, client services.VisionClient) map[string]interface{} {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, Options{OpenAI: &services.OpenAIService{Client: client}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = screenshotRequest(t)
+	h.ExtractEventFromScreenshot(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestExtractEventFromScreenshotPartialResult(t *testing.T) {
+	response := extractScreenshot(t, stubVision{reply: `{"events": [
+		{"title": "Bake Sale", "location": "SLC", "occurrences": [{"dtstart": "soon"}]},
+		{"title": "", "location": "DC", "occurrences": [{"dtstart": "2026-03-05T12:00:00"}]}
+	]}`})
+
+	if response["complete"] != false {
+		t.Errorf("complete = %v, want false", response["complete"])
+	}
+	if response["title"] != "Bake Sale" {
+		t.Errorf("top-level title = %v, want the first draft's", response["title"])
+	}
+	if events, _ := response["events"].([]interface{}); len(events) != 2 {
+		t.Errorf("events = %v, want both drafts", response["events"])
+	}
+
+	want := []interface{}{
+		"the screenshot could not be saved",
+		"occurrence 1: could not read the start time",
+		"no dates found",
+		"no title found",
+	}
+	warnings, _ := response["warnings"].([]interface{})
+	if len(warnings) != len(want) {
+		t.Fatalf("warnings = %v, want %v", warnings, want)
+	}
+	for i := range want {
+		if warnings[i] != want[i] {
+			t.Errorf("warnings[%d] = %v, want %v", i, warnings[i], want[i])
+		}
+	}
+}
+
+func TestExtractEventFromScreenshotModelFailure(t *testing.T) {
+	response := extractScreenshot(t, stubVision{err: errors.New("openai response: status 500")})
+
+	if response["complete"] != false {
+		t.Errorf("complete = %v, want false", response["complete"])
+	}
+	if events, _ := response["events"].([]interface{}); len(events) != 1 {
+		t.Errorf("events = %v, want one empty draft for manual entry", response["events"])
+	}
+	warnings, _ := response["warnings"].([]interface{})
+	if len(warnings) != 2 || warnings[1] != "event details could not be extracted from the screenshot" {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// feedRefreshInterval is advertised to subscribed calendar clients
+const feedRefreshInterval = time.Hour
+
+// GetMyCalendarFeed handles GET /api/events/feeds/me - get (or create) the
+// caller's private calendar feed URL
+// Requires: JWT authentication
+func (h *Handler) GetMyCalendarFeed(c *gin.Context) {
+	userID := c.GetString(core.ContextUserID)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	feed, err := h.issueFeedToken(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.feedResponse(feed))
+}
+
+// RotateMyCalendarFeed handles POST /api/events/feeds/me/rotate - revoke the
+// caller's current feed token and issue a new one
+// Requires: JWT authentication
+func (h *Handler) RotateMyCalendarFeed(c *gin.Context) {
+	userID := c.GetString(core.ContextUserID)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	var feed CalendarFeedToken
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&CalendarFeedToken{}).Error; err != nil {
+			return err
+		}
+		var err error
+		feed, err = h.issueFeedToken(tx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.feedResponse(feed))
+}
+
+// RevokeMyCalendarFeed handles DELETE /api/events/feeds/me - revoke the
+// caller's feed token so existing subscriptions stop updating
+// Requires: JWT authentication
+func (h *Handler) RevokeMyCalendarFeed(c *gin.Context) {
+	userID := c.GetString(core.ContextUserID)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := h.DB.Where("user_id = ?", userID).Delete(&CalendarFeedToken{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar feed revoked"})
+}
+
+// UserCalendarFeed handles GET /api/events/feeds/user/:token - subscribable
+// calendar of the token owner's interested events
+func (h *Handler) UserCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feed CalendarFeedToken
+	if err := h.DB.Where("token = ?", token).First(&feed).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar feed not found"})
+		return
+	}
+
+	now := time.Now()
+	h.DB.Model(&feed).UpdateColumn("last_used_at", now)
+
+	query := h.DB.Model(&Events{}).
+		Joins("JOIN event_interests ei ON ei.event_id = events.id AND ei.deleted_at IS NULL").
+		Where("ei.user_id = ?", feed.UserID)
+	query = withRecentDates(query, now.Add(-calendarLookback))
+
+	h.serveCalendarFeed(c, query, "My Wat2Do Events", "private")
+}
+
+// ClubCalendarFeed handles GET /api/events/feeds/club/:id - subscribable
+// calendar of a club's events
+func (h *Handler) ClubCalendarFeed(c *gin.Context) {
+	var club clubs.Clubs
+	if err := h.DB.First(&club, strings.TrimSuffix(c.Param("id"), ".ics")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+		return
+	}
+	if club.IG == nil || utils.NormalizeHandle(*club.IG) == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Club has no linked Instagram handle"})
+		return
+	}
+
+	query := h.DB.Model(&Events{}).Where("LOWER(events.ig_handle) = ?", utils.NormalizeHandle(*club.IG))
+	query = withRecentDates(query, time.Now().Add(-calendarLookback))
+
+	h.serveCalendarFeed(c, query, club.ClubName, "public")
+}
+
+// FilteredCalendarFeed handles GET /api/events/feeds/events.ics - subscribable
+// calendar of events matching the same filters as GetEvents
+// (search, categories, club_type, food, price, registration, school)
+func (h *Handler) FilteredCalendarFeed(c *gin.Context) {
+	filter := eventFilterFromQuery(c)
+	query := filter.ApplyEventFilters(h.DB.Model(&Events{}))
+	query = withRecentDates(query, time.Now().Add(-calendarLookback))
+
+	h.serveCalendarFeed(c, query, "Wat2Do Events", "public")
+}
+
+// serveCalendarFeed answers conditional requests from a cheap version query
+// and only loads and renders the calendar when it has changed
+func (h *Handler) serveCalendarFeed(c *gin.Context, query *gorm.DB, name, cacheScope string) {
+	query = query.Where("events.status = ?", EventStatusConfirmed).Session(&gorm.Session{})
+
+	etag, lastModified, err := feedVersion(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("%s, max-age=%d", cacheScope, int(feedRefreshInterval/time.Second)/4))
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	events, err := h.calendarEvents(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(h.buildCalendar(events, name, feedRefreshInterval)))
+}
+
+// feedVersion derives a strong ETag and Last-Modified time for the events a
+// feed query selects. The ETag covers every event's id, last update and date
+// count, so edits, additions, removals and rescheduling all change it.
+func feedVersion(query *gorm.DB) (string, time.Time, error) {
+	var rows []struct {
+		ID        uint
+		UpdatedAt time.Time
+		DateCount int
+	}
+	err := query.
+		Select("events.id, GREATEST(events.updated_at, COALESCE(MAX(fd.updated_at), events.updated_at)) AS updated_at, COUNT(fd.id) AS date_count").
+		Joins("LEFT JOIN event_dates fd ON fd.event_id = events.id AND fd.deleted_at IS NULL").
+		Group("events.id").
+		Order("events.id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	hash := sha256.New()
+	var lastModified time.Time
+	for _, row := range rows {
+		fmt.Fprintf(hash, "%d:%d:%d;", row.ID, row.UpdatedAt.UnixNano(), row.DateCount)
+		if row.UpdatedAt.After(lastModified) {
+			lastModified = row.UpdatedAt
+		}
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`, lastModified, nil
+}
+
+// notModified evaluates If-None-Match and, failing that, If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil {
+			return !lastModified.Truncate(time.Second).After(t)
+		}
+	}
+
+	return false
+}
+
+// issueFeedToken returns userID's live feed token, creating a random one if
+// they have none. A user has at most one live token, so concurrent requests
+// all get the same one.
+func (h *Handler) issueFeedToken(db *gorm.DB, userID string) (CalendarFeedToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return CalendarFeedToken{}, err
+	}
+
+	feed := CalendarFeedToken{
+		UserID: userID,
+		Token:  base64.RawURLEncoding.EncodeToString(raw),
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(&feed).Error
+	if err != nil {
+		return CalendarFeedToken{}, err
+	}
+
+	var live CalendarFeedToken
+	return live, db.Where("user_id = ?", userID).First(&live).Error
+}
+
+// feedResponse describes a feed token with ready-to-use subscription URLs
+func (h *Handler) feedResponse(feed CalendarFeedToken) gin.H {
+	feedURL := fmt.Sprintf("%s/api/events/feeds/user/%s.ics", strings.TrimRight(h.APIURL, "/"), feed.Token)
+
+	return gin.H{
+		"url":        feedURL,
+		"webcalUrl":  "webcal://" + strings.SplitN(feedURL, "://", 2)[1],
+		"createdAt":  feed.CreatedAt,
+		"lastUsedAt": feed.LastUsedAt,
+	}
+}
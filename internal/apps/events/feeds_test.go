@@ -0,0 +1,106 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+)
+
+type feedInfo struct {
+	URL       string `json:"url"`
+	WebcalURL string `json:"webcalUrl"`
+}
+
+// callFeedEndpoint calls a /feeds/me handler as userID from a client that
+// claims to be on another host
+func callFeedEndpoint(t *testing.T, handler gin.HandlerFunc, method, userID string) feedInfo {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "http://attacker.example/api/events/feeds/me", nil)
+	c.Request.Header.Set("X-Forwarded-Proto", "http")
+	c.Request.Header.Set("X-Forwarded-Host", "attacker.example")
+	c.Set(core.ContextUserID, userID)
+	handler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s = %d %s", method, w.Code, w.Body)
+	}
+	var info feedInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestCalendarFeedURLsUseAPIURL(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{APIURL: "https://api.wat2do.ca/"})
+
+	info := callFeedEndpoint(t, h.GetMyCalendarFeed, http.MethodGet, "user_1")
+	var feed CalendarFeedToken
+	if err := db.Where("user_id = ?", "user_1").First(&feed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://api.wat2do.ca/api/events/feeds/user/" + feed.Token + ".ics"; info.URL != want {
+		t.Errorf("url = %q, want %q", info.URL, want)
+	}
+	if want := "webcal://api.wat2do.ca/api/events/feeds/user/" + feed.Token + ".ics"; info.WebcalURL != want {
+		t.Errorf("webcalUrl = %q, want %q", info.WebcalURL, want)
+	}
+}
+
+func TestGetMyCalendarFeedKeepsOneLiveToken(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{APIURL: "https://api.wat2do.ca"})
+	liveTokens := func() int64 {
+		var count int64
+		db.Model(&CalendarFeedToken{}).Where("user_id = ?", "user_1").Count(&count)
+		return count
+	}
+
+	first := callFeedEndpoint(t, h.GetMyCalendarFeed, http.MethodGet, "user_1")
+	if again := callFeedEndpoint(t, h.GetMyCalendarFeed, http.MethodGet, "user_1"); again.URL != first.URL {
+		t.Errorf("second request got %q, want the existing %q", again.URL, first.URL)
+	}
+	if other := callFeedEndpoint(t, h.GetMyCalendarFeed, http.MethodGet, "user_2"); other.URL == first.URL {
+		t.Error("another user got the same feed")
+	}
+
+	// A request that lost the race to create the token gets the winner's
+	if _, err := h.issueFeedToken(db, "user_1"); err != nil {
+		t.Fatalf("issueFeedToken with a live token: %v", err)
+	}
+	if n := liveTokens(); n != 1 {
+		t.Fatalf("live tokens = %d, want 1", n)
+	}
+	if err := db.Create(&CalendarFeedToken{UserID: "user_1", Token: "duplicate"}).Error; err == nil {
+		t.Error("created a second live token")
+	}
+
+	rotated := callFeedEndpoint(t, h.RotateMyCalendarFeed, http.MethodPost, "user_1")
+	if rotated.URL == first.URL {
+		t.Error("rotating kept the old token")
+	}
+	if again := callFeedEndpoint(t, h.GetMyCalendarFeed, http.MethodGet, "user_1"); again.URL != rotated.URL {
+		t.Errorf("after rotating got %q, want %q", again.URL, rotated.URL)
+	}
+	if n := liveTokens(); n != 1 {
+		t.Errorf("live tokens after rotating = %d, want 1", n)
+	}
+
+	// The old token no longer serves the feed
+	oldToken := strings.TrimSuffix(first.URL[strings.LastIndex(first.URL, "/")+1:], ".ics")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/events/feeds/user/"+oldToken+".ics", nil)
+	c.Params = gin.Params{{Key: "token", Value: oldToken + ".ics"}}
+	h.UserCalendarFeed(c)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("rotated-out token = %d, want 404", w.Code)
+	}
+}
@@ -1,20 +1,61 @@
 package events
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	defaultEventPageSize = 20
+	maxEventPageSize     = 100
+
+	// liveWindowWithoutEnd is how long an occurrence with no end time stays live
+	liveWindowWithoutEnd = 90 * time.Minute
+)
+
+// eventSortKey is the keyset the event listing is ordered by
+type eventSortKey struct {
+	ID              uint
+	EarliestDtstart sortTime
+}
+
 // Handler holds dependencies for event handlers
 type Handler struct {
 	DB *gorm.DB
+	Options
+}
+
+// Options configures the events handlers
+type Options struct {
+	SiteURL string                  // public frontend URL used for event links and calendar UIDs
+	APIURL  string                  // public URL of this API, for feed subscription and self links
+	Images  *services.ImageService  // processes and stores uploaded screenshots
+	OpenAI  *services.OpenAIService // vision model used for screenshot extraction
+
+	// Realtime receives live updates such as reaction counts; may be nil
+	Realtime realtime.Publisher
+
+	// RateLimiter enforces the per-route limits; nil disables them
+	RateLimiter middleware.Limiter
 }
 
 // NewHandler creates a new events handler
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *gorm.DB, opts Options) *Handler {
+	return &Handler{DB: db, Options: opts}
 }
 
 // GetLatestUpdate handles GET /api/events/latest-update/ - get latest event timestamp
@@ -30,59 +71,428 @@ func (h *Handler) GetLatestUpdate(c *gin.Context) {
 	})
 }
 
+// EventListItem is an event annotated with the occurrence the listing resolved
+// for it: the one currently live, otherwise the next upcoming one
+type EventListItem struct {
+	Events
+	DtstartUTC    *time.Time `json:"dtstart_utc"`
+	DtendUTC      *time.Time `json:"dtend_utc"`
+	TZ            *string    `json:"tz"`
+	IsLive        bool       `json:"is_live"`
+	DisplayHandle string     `json:"display_handle"`
+	InterestCount int64      `json:"interest_count"`
+	IsInterested  bool       `json:"is_interested"`
+}
+
+// newEventListItem resolves the displayed occurrence for an event whose dates
+// are sorted by dtstart_utc, as of now
+func newEventListItem(event Events, from *time.Time, now time.Time) EventListItem {
+	item := EventListItem{
+		Events: event,
+		DisplayHandle: utils.DetermineDisplayHandle(event.IGHandle, event.DiscordHandle,
+			event.XHandle, event.TiktokHandle, event.FBHandle, event.OtherHandle),
+	}
+
+	if occurrence := nextOccurrence(event.EventDates, from, now); occurrence != nil {
+		start := occurrence.DtstartUTC
+		item.DtstartUTC = &start
+		item.DtendUTC = occurrence.DtendUTC
+		item.TZ = occurrence.TZ
+		item.IsLive = occurrenceLive(*occurrence, now)
+	}
+
+	// The listing only exposes the resolved occurrence; GetEvent returns them all
+	item.EventDates = nil
+	return item
+}
+
+// nextOccurrence returns the occurrence to display for an event: the first one
+// starting at or after from when the caller supplied a start date, otherwise
+// the first one that is live or still upcoming at now. It must agree with
+// occurrenceWindow, which picked the event for the page.
+func nextOccurrence(dates []EventDates, from *time.Time, now time.Time) *EventDates {
+	for i := range dates {
+		if from != nil {
+			if !dates[i].DtstartUTC.Before(*from) {
+				return &dates[i]
+			}
+			continue
+		}
+		if !dates[i].DtstartUTC.Before(now) || occurrenceLive(dates[i], now) {
+			return &dates[i]
+		}
+	}
+
+	return nil
+}
+
+// eventFilterFromQuery builds an EventFilter from the listing query parameters.
+// Feeds share it, so the shorter category=... and free=true forms are accepted
+// alongside categories=a,b and price=free.
+func eventFilterFromQuery(c *gin.Context) utils.EventFilter {
+	filter := utils.EventFilter{
+		Search:   strings.TrimSpace(c.Query("search")),
+		ClubType: c.Query("club_type"),
+		School:   c.Query("school"),
+	}
+
+	for _, raw := range append(c.QueryArray("categories"), c.QueryArray("category")...) {
+		for _, category := range strings.Split(raw, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				filter.Categories = append(filter.Categories, category)
+			}
+		}
+	}
+
+	if food, err := strconv.ParseBool(c.Query("food")); err == nil {
+		filter.HasFood = &food
+	}
+
+	if free, _ := strconv.ParseBool(c.Query("free")); free || c.Query("price") == "free" || c.Query("price") == "0" {
+		isFree := true
+		filter.IsFree = &isFree
+	}
+
+	if registration, err := strconv.ParseBool(c.Query("registration")); err == nil {
+		filter.Registration = &registration
+	}
+
+	return filter
+}
+
 // GetEvents handles GET /api/events/ - retrieve events with pagination and filtering
 // Query params:
 //   - search: search term
 //   - dtstart_utc: filter by start date
 //   - cursor: pagination cursor
-//   - limit: number of results (default 20)
-//   - all: return all events without pagination
-//   - food, price, registration, club_type: filters
+//   - limit: number of results (default 20, at most 100)
+//   - food, price, registration, club_type, categories, school: filters
 func (h *Handler) GetEvents(c *gin.Context) {
-	// TODO: Implement event retrieval logic
-	// 1. Parse query parameters
-	// 2. Build base query filtering by status=CONFIRMED and school
-	// 3. Apply date filters (upcoming events, live events)
-	// 4. Apply search and other filters
-	// 5. Annotate with earliest_dtstart from event_dates
-	// 6. Apply cursor-based pagination
-	// 7. Prefetch event_dates
-	// 8. For each event, determine next upcoming or live occurrence
-	// 9. Return paginated response
+	now := time.Now().UTC()
+
+	var cursor *eventCursor
+	if token := c.Query("cursor"); token != "" {
+		decoded, err := decodeEventCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cursor = &decoded
+		now = decoded.AsOf
+	}
+
+	limit := defaultEventPageSize
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(parsed, maxEventPageSize)
+	}
+
+	var from *time.Time
+	if raw := c.Query("dtstart_utc"); raw != "" {
+		parsed, err := utils.ParseUTCDateTime(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dtstart_utc"})
+			return
+		}
+		from = &parsed
+	}
+
+	filter := eventFilterFromQuery(c)
+
+	// Only events published before the snapshot time are listed, so
+	// totalCount and page boundaries don't shift when events are added or
+	// approved mid-pagination. added_at is set on publication; rows from
+	// before it was tracked fall back to created_at.
+	query := h.DB.Model(&Events{}).
+		Joins("JOIN (?) AS occ ON occ.event_id = events.id", h.occurrenceWindow(now, from)).
+		Where("events.status = ?", EventStatusConfirmed).
+		Where("COALESCE(events.added_at, events.created_at) <= ?", now)
+	query = filter.ApplyEventFilters(query).Session(&gorm.Session{})
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count events"})
+		return
+	}
+
+	page := query.Select("events.id, occ.earliest_dtstart").
+		Order("occ.earliest_dtstart ASC, events.id ASC")
+	if cursor != nil {
+		page = page.Where("(occ.earliest_dtstart, events.id) > (?, ?)", cursor.EarliestDtstart, cursor.ID)
+	}
+	page = page.Limit(limit + 1)
+
+	var keys []eventSortKey
+	if err := page.Scan(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	hasMore := len(keys) > limit
+	if hasMore {
+		keys = keys[:limit]
+	}
+
+	results, err := h.loadListItems(keys, from, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+	if err := h.annotateInterest(results, c.GetString(core.ContextUserID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+
+	var nextCursor *string
+	if hasMore {
+		last := keys[len(keys)-1]
+		token := eventCursor{EarliestDtstart: last.EarliestDtstart.Time, ID: last.ID, AsOf: now}.encode()
+		nextCursor = &token
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"results":    []Events{},
-		"nextCursor": nil,
-		"hasMore":    false,
-		"totalCount": 0,
+		"results":    results,
+		"nextCursor": nextCursor,
+		"hasMore":    hasMore,
+		"totalCount": totalCount,
 	})
 }
 
+// occurrenceWindow builds a subquery yielding (event_id, earliest_dtstart) for every
+// event with an occurrence in the listing window: upcoming or currently live
+// relative to now, or starting at/after from when the caller supplied a start date
+func (h *Handler) occurrenceWindow(now time.Time, from *time.Time) *gorm.DB {
+	query := h.DB.Model(&EventDates{}).
+		Select("event_id, MIN(dtstart_utc) AS earliest_dtstart").
+		Group("event_id")
+
+	if from != nil {
+		return query.Where("dtstart_utc >= ?", *from)
+	}
+
+	// Upcoming, or live as in occurrenceLive: an occurrence without an end
+	// time counts as live for liveWindowWithoutEnd after it starts
+	return query.Where(
+		"dtstart_utc >= ? OR (dtend_utc IS NOT NULL AND dtstart_utc <= ? AND dtend_utc > ?) OR (dtend_utc IS NULL AND dtstart_utc > ?)",
+		now, now, now, now.Add(-liveWindowWithoutEnd),
+	)
+}
+
+// occurrenceLive reports whether an occurrence is live at now, with the same
+// bounds occurrenceWindow uses in SQL
+func occurrenceLive(date EventDates, now time.Time) bool {
+	if date.DtstartUTC.After(now) {
+		return false
+	}
+	if date.DtendUTC != nil {
+		return date.DtendUTC.After(now)
+	}
+	return date.DtstartUTC.After(now.Add(-liveWindowWithoutEnd))
+}
+
+// loadListItems fetches the events for a page of sort keys with their dates
+// prefetched, preserving the order of keys, resolving occurrences as of now
+func (h *Handler) loadListItems(keys []eventSortKey, from *time.Time, now time.Time) ([]EventListItem, error) {
+	items := make([]EventListItem, 0, len(keys))
+	if len(keys) == 0 {
+		return items, nil
+	}
+
+	ids := make([]uint, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+	}
+
+	var events []Events
+	err := h.DB.Preload("EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).Where("id IN ?", ids).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]Events, len(events))
+	for _, event := range events {
+		byID[event.ID] = event
+	}
+
+	for _, key := range keys {
+		if event, ok := byID[key.ID]; ok {
+			items = append(items, newEventListItem(event, from, now))
+		}
+	}
+
+	return items, nil
+}
+
+// EventDetail is a single event with all its occurrences
+type EventDetail struct {
+	Events
+	DisplayHandle string `json:"display_handle"`
+	InterestCount int64  `json:"interest_count"`
+	IsInterested  bool   `json:"is_interested"`
+	IsSubmitter   bool   `json:"is_submitter"`
+}
+
 // GetEvent handles GET /api/events/:id - retrieve a single event by ID
+// Unconfirmed events are only visible to admins and their submitter.
 func (h *Handler) GetEvent(c *gin.Context) {
-	// TODO: Implement single event retrieval
-	// 1. Parse event ID from URL parameter
-	// 2. Query event by ID
-	// 3. Fetch all event_dates ordered by dtstart_utc
-	// 4. Check if user is submitter (if authenticated)
-	// 5. Return event data with occurrences
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":    0,
-		"title": "",
+	var event Events
+	err = h.DB.Preload("EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).Take(&event, id).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	userID := c.GetString(core.ContextUserID)
+	isSubmitter := false
+	if userID != "" {
+		var count int64
+		err := h.DB.Model(&EventSubmission{}).
+			Where("created_event_id = ? AND submitted_by = ?", event.ID, userID).
+			Count(&count).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+			return
+		}
+		isSubmitter = count > 0
+	}
+
+	if (event.Status == nil || *event.Status != EventStatusConfirmed) && !isSubmitter && !core.IsAdmin(c) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	counts, err := interestCounts(h.DB, []uint{event.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+	interested, err := interestedEventIDs(h.DB, userID, []uint{event.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, EventDetail{
+		Events: event,
+		DisplayHandle: utils.DetermineDisplayHandle(event.IGHandle, event.DiscordHandle,
+			event.XHandle, event.TiktokHandle, event.FBHandle, event.OtherHandle),
+		InterestCount: counts[event.ID],
+		IsInterested:  interested[event.ID],
+		IsSubmitter:   isSubmitter,
 	})
 }
 
 // ExportEventsICS handles GET /api/events/export/ics - export events as .ics file
-// Query params: ids (comma-separated list of event IDs)
+// Query params (one of):
+//   - ids: comma-separated list of event IDs
+//   - club: club ID; exports the club's recent and upcoming events
 func (h *Handler) ExportEventsICS(c *gin.Context) {
-	// TODO: Implement ICS export
-	// 1. Parse event IDs from query parameter
-	// 2. Fetch events with event_dates
-	// 3. Generate ICS file content (VCALENDAR format)
-	// 4. Return with Content-Type: text/calendar
+	query := h.DB.Model(&Events{})
+	name := "Wat2Do Events"
+	filename := "wat2do-events.ics"
+
+	switch {
+	case c.Query("ids") != "":
+		ids, err := parseIDList(c.Query("ids"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("events.id IN ?", ids)
+
+	case c.Query("club") != "":
+		var club clubs.Clubs
+		if err := h.DB.First(&club, c.Query("club")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Club not found"})
+			return
+		}
+		if club.IG == nil || utils.NormalizeHandle(*club.IG) == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Club has no linked Instagram handle"})
+			return
+		}
+		query = withRecentDates(query.Where("LOWER(events.ig_handle) = ?", utils.NormalizeHandle(*club.IG)),
+			time.Now().Add(-calendarLookback))
+		name = club.ClubName
+		filename = fmt.Sprintf("club-%d.ics", club.ID)
 
-	c.String(http.StatusOK, "BEGIN:VCALENDAR\nEND:VCALENDAR")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids or club is required"})
+		return
+	}
+
+	events, err := h.calendarEvents(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	h.writeICS(c, h.buildCalendar(events, name, 0), filename)
+}
+
+// ExportInterestedEventsICS handles GET /api/events/export/ics/interested - export
+// the caller's interested events as .ics file
+// Requires: JWT authentication
+func (h *Handler) ExportInterestedEventsICS(c *gin.Context) {
+	userID := c.GetString(core.ContextUserID)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	query := h.DB.Model(&Events{}).
+		Joins("JOIN event_interests ei ON ei.event_id = events.id AND ei.deleted_at IS NULL").
+		Where("ei.user_id = ?", userID)
+	events, err := h.calendarEvents(withRecentDates(query, time.Now().Add(-calendarLookback)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	h.writeICS(c, h.buildCalendar(events, "My Wat2Do Events", 0), "my-wat2do-events.ics")
+}
+
+// writeICS sends a rendered calendar as a file download
+func (h *Handler) writeICS(c *gin.Context, calendar, filename string) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(calendar))
+}
+
+// parseIDList parses a comma-separated list of event IDs
+func parseIDList(raw string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event id %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("no event ids given")
+	}
+	if len(ids) > maxEventPageSize {
+		return nil, fmt.Errorf("at most %d event ids may be requested", maxEventPageSize)
+	}
+	return ids, nil
 }
 
 // GetGoogleCalendarURLs handles GET /api/events/google-calendar-urls - generate Google Calendar URLs
@@ -100,59 +510,87 @@ func (h *Handler) GetGoogleCalendarURLs(c *gin.Context) {
 }
 
 // RSSFeed handles GET /rss.xml - RSS feed of upcoming events
+// Query params: same filters as GetEvents (club_type, category, free, food, ...)
 func (h *Handler) RSSFeed(c *gin.Context) {
-	// TODO: Implement RSS feed generation
-	// 1. Query upcoming events (dtstart_utc >= now, status=CONFIRMED)
-	// 2. Limit to 50 events, ordered by earliest_dtstart
-	// 3. Generate RSS XML with event items
-	// 4. Return with Content-Type: application/rss+xml
+	items, err := h.feedItems(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
 
-	c.Header("Content-Type", "application/rss+xml")
-	c.String(http.StatusOK, "<?xml version=\"1.0\"?><rss version=\"2.0\"></rss>")
+	h.writeXML(c, "application/rss+xml; charset=utf-8", h.buildRSS(c, items))
 }
 
-// ExtractEventFromScreenshot handles POST /api/events/extract/ - extract event from screenshot
-// Requires: JWT authentication, rate limiting
-// Body: multipart/form-data with screenshot file
-func (h *Handler) ExtractEventFromScreenshot(c *gin.Context) {
-	// TODO: Implement screenshot event extraction
-	// 1. Validate authentication (JWT required)
-	// 2. Parse uploaded screenshot file
-	// 3. Upload image to S3 storage
-	// 4. Call OpenAI service to extract event data from image
-	// 5. Return extracted event data (title, description, location, occurrences, etc.)
+// AtomFeed handles GET /atom.xml - Atom feed of upcoming events
+// Query params: same filters as GetEvents (club_type, category, free, food, ...)
+func (h *Handler) AtomFeed(c *gin.Context) {
+	items, err := h.feedItems(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"source_image_url": "",
-		"title":            "",
-		"description":      "",
-		"location":         "",
-		"occurrences":      []map[string]interface{}{},
-	})
+	h.writeXML(c, "application/atom+xml; charset=utf-8", h.buildAtom(c, items))
 }
 
 // SubmitEvent handles POST /api/events/submit/ - submit event for review
 // Requires: JWT authentication, rate limiting
-// Body: JSON with event data and source_image_url
+// Body: JSON with event data (title, location, occurrences, ...) and source_image_url
 func (h *Handler) SubmitEvent(c *gin.Context) {
-	// TODO: Implement event submission
-	// 1. Validate authentication (JWT required)
-	// 2. Parse and validate event data from request body
-	// 3. Create Events record with status=PENDING
-	// 4. Create EventDates records for each occurrence
-	// 5. Create EventSubmission record
-	// 6. Return success response
+	userID := c.GetString(core.ContextUserID)
+
+	var body map[string]interface{}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	data, err := utils.ValidateEventData(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := EventStatusPending
+	event := Events{Status: &status}
+	applyEventData(&event, data)
+	event.EventDates = newEventDates(0, data["occurrences"].([]utils.EventOccurrence))
+
+	submission := EventSubmission{
+		SubmittedBy: userID,
+		SubmittedAt: time.Now(),
+		Status:      SubmissionStatusPending,
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := attachUploadedImage(tx, &event); err != nil {
+			return err
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		if _, err := flagDuplicates(tx, &event); err != nil {
+			return err
+		}
+		submission.CreatedEventID = event.ID
+		if err := tx.Omit(clause.Associations).Create(&submission).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, submission.ID, "submitted", userID, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit event"})
+		return
+	}
 
+	submission.CreatedEvent = event
+	publishSubmission(h.Realtime, "submission.created", &submission)
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Event submitted successfully",
+		"message":    "Event submitted successfully",
+		"submission": submission,
 	})
 }
 
 // TODO: Add more handlers as needed:
 // - UpdateEvent (PUT /api/events/:id) - admin only
 // - DeleteEvent (DELETE /api/events/:id) - admin only
-// - AddEventInterest (POST /api/events/:id/interest) - JWT required
-// - RemoveEventInterest (DELETE /api/events/:id/interest) - JWT required
-// - GetMySubmissions (GET /api/events/my-submissions) - JWT required
-// - ApproveSubmission (POST /api/events/submissions/:id/approve) - admin only
-// - RejectSubmission (POST /api/events/submissions/:id/reject) - admin only
@@ -0,0 +1,186 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// openEventsDB opens a test database with the events app's tables
+func openEventsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	return testutil.OpenDB(t, &Events{}, &EventDates{}, &EventInterest{}, &EventReaction{},
+		&EventSubmission{}, &SubmissionAuditEntry{}, &DuplicateFlag{}, &CalendarFeedToken{})
+}
+
+// createEvent stores an event with status, published at addedAt if set,
+// occurring at each of starts for an hour
+func createEvent(t *testing.T, db *gorm.DB, title, status string, addedAt *time.Time, starts ...time.Time) *Events {
+	t.Helper()
+	event := &Events{Title: &title, Status: &status, AddedAt: addedAt}
+	for _, start := range starts {
+		end := start.Add(time.Hour)
+		event.EventDates = append(event.EventDates, EventDates{DtstartUTC: start.UTC(), DtendUTC: &end})
+	}
+	if err := db.Create(event).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// eventPage is a GetEvents response
+type eventPage struct {
+	Results []struct {
+		ID         uint      `json:"id"`
+		DtstartUTC time.Time `json:"dtstart_utc"`
+	} `json:"results"`
+	NextCursor *string `json:"nextCursor"`
+	HasMore    bool    `json:"hasMore"`
+	TotalCount int64   `json:"totalCount"`
+}
+
+func getEvents(t *testing.T, h *Handler, query url.Values) eventPage {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/events/?"+query.Encode(), nil)
+	h.GetEvents(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetEvents(%s) = %d %s", query.Encode(), w.Code, w.Body)
+	}
+	var page eventPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+// listAll pages through the listing limit events at a time, returning the
+// IDs in order and the total count each page reported
+func listAll(t *testing.T, h *Handler, limit string, between func(page int)) ([]uint, []int64) {
+	t.Helper()
+	var ids []uint
+	var totals []int64
+	query := url.Values{"limit": {limit}}
+	for page := 0; ; page++ {
+		result := getEvents(t, h, query)
+		for _, item := range result.Results {
+			ids = append(ids, item.ID)
+		}
+		totals = append(totals, result.TotalCount)
+		if !result.HasMore {
+			if result.NextCursor != nil {
+				t.Errorf("page %d has a cursor but no more results", page)
+			}
+			return ids, totals
+		}
+		query.Set("cursor", *result.NextCursor)
+		if between != nil {
+			between(page)
+		}
+	}
+}
+
+func TestGetEventsKeysetOrder(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	base := time.Now().UTC().Truncate(time.Second).Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	// Events sharing a start are ordered by ID; an event is placed by its
+	// earliest upcoming occurrence
+	c := createEvent(t, db, "C", EventStatusConfirmed, &past, base.Add(2*time.Hour))
+	a := createEvent(t, db, "A", EventStatusConfirmed, &past, base)
+	b := createEvent(t, db, "B", EventStatusConfirmed, &past, base)
+	d := createEvent(t, db, "D", EventStatusConfirmed, &past, base.Add(-48*time.Hour), base.Add(3*time.Hour))
+	e := createEvent(t, db, "E", EventStatusConfirmed, &past, base.Add(time.Hour), base.Add(5*time.Hour))
+	createEvent(t, db, "Pending", EventStatusPending, nil, base)
+	createEvent(t, db, "Over", EventStatusConfirmed, &past, base.Add(-48*time.Hour))
+
+	want := []uint{a.ID, b.ID, e.ID, c.ID, d.ID}
+	for _, limit := range []string{"1", "2", "3", "100"} {
+		ids, totals := listAll(t, h, limit, nil)
+		if len(ids) != len(want) {
+			t.Fatalf("limit %s: ids = %v, want %v", limit, ids, want)
+		}
+		for i := range want {
+			if ids[i] != want[i] {
+				t.Fatalf("limit %s: ids = %v, want %v", limit, ids, want)
+			}
+		}
+		for _, total := range totals {
+			if total != int64(len(want)) {
+				t.Errorf("limit %s: totalCount = %d, want %d", limit, total, len(want))
+			}
+		}
+	}
+
+	page := getEvents(t, h, url.Values{"limit": {"5"}})
+	if got := page.Results[4]; got.ID != d.ID || !got.DtstartUTC.Equal(base.Add(3*time.Hour)) {
+		t.Errorf("D resolved to %+v, want its upcoming occurrence", got)
+	}
+}
+
+func TestGetEventsCursorIsStable(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	base := time.Now().UTC().Truncate(time.Second).Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	var want []uint
+	for i := 0; i < 5; i++ {
+		want = append(want, createEvent(t, db, "Listed", EventStatusConfirmed, &past, base.Add(time.Duration(i)*time.Hour)).ID)
+	}
+	// Submitted long ago, approved while the client pages
+	pending := createEvent(t, db, "Approved later", EventStatusPending, nil, base.Add(90*time.Minute))
+
+	ids, totals := listAll(t, h, "2", func(page int) {
+		if page != 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+		later := time.Now()
+		createEvent(t, db, "Added later", EventStatusConfirmed, &later, base.Add(30*time.Minute))
+		db.Model(pending).Updates(map[string]interface{}{"status": EventStatusConfirmed, "added_at": later})
+	})
+
+	if len(ids) != len(want) {
+		t.Fatalf("ids = %v, want the snapshot's %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("ids = %v, want the snapshot's %v", ids, want)
+		}
+	}
+	for page, total := range totals {
+		if total != 5 {
+			t.Errorf("page %d totalCount = %d, want 5", page, total)
+		}
+	}
+
+	// A fresh listing sees both
+	if page := getEvents(t, h, url.Values{"limit": {"10"}}); page.TotalCount != 7 {
+		t.Errorf("new listing totalCount = %d, want 7", page.TotalCount)
+	}
+}
+
+func TestGetEventsRejectsBadParameters(t *testing.T) {
+	h := NewHandler(openEventsDB(t), Options{})
+	for _, query := range []string{"cursor=not-a-cursor", "limit=0", "limit=ten", "dtstart_utc=soon"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/events/?"+query, nil)
+		h.GetEvents(c)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GetEvents(%s) = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package events
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	icsProdID = "-//Wat2Do//Events//EN"
+
+	// calendarLookback is how far back club and interest calendars reach, so
+	// recently finished events don't vanish from subscribers' calendars
+	calendarLookback = 30 * 24 * time.Hour
+)
+
+// calendarEvents loads confirmed events matching query with their dates
+// sorted by dtstart_utc
+func (h *Handler) calendarEvents(query *gorm.DB) ([]Events, error) {
+	var events []Events
+	err := query.
+		Where("events.status = ?", EventStatusConfirmed).
+		Preload("EventDates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("dtstart_utc ASC")
+		}).
+		Order("events.id ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// withRecentDates restricts an events query to events with an occurrence
+// starting after since
+func withRecentDates(query *gorm.DB, since time.Time) *gorm.DB {
+	return query.Where(
+		"EXISTS (SELECT 1 FROM event_dates ed WHERE ed.event_id = events.id AND ed.deleted_at IS NULL AND ed.dtstart_utc >= ?)",
+		since,
+	)
+}
+
+// buildCalendar renders events as an iCalendar stream. Each event is one
+// series under a UID derived from its ID alone: a VEVENT at the first
+// occurrence repeating via an RRULE (regular spacing) or RDATE list, plus a
+// RECURRENCE-ID override for every occurrence whose timezone or duration
+// differs from the first. Occurrences are identified by their UTC start, never
+// by EventDates row IDs, which change whenever dates are edited. A non-zero
+// refresh advertises how often subscribed clients should poll.
+func (h *Handler) buildCalendar(events []Events, name string, refresh time.Duration) string {
+	b := utils.NewICalBuilder(icsProdID, name)
+	if refresh > 0 {
+		b.Line("REFRESH-INTERVAL;VALUE=DURATION", utils.FormatICalDuration(refresh))
+		b.Line("X-PUBLISHED-TTL", utils.FormatICalDuration(refresh))
+	}
+	locations := newLocationCache()
+
+	// One VTIMEZONE per zone referenced, spanning every occurrence in it
+	type span struct{ from, to time.Time }
+	spans := map[string]*span{}
+	for _, event := range events {
+		for _, date := range event.EventDates {
+			loc := locations.get(date.TZ)
+			if loc == nil {
+				continue
+			}
+			s, ok := spans[loc.String()]
+			if !ok {
+				spans[loc.String()] = &span{from: date.DtstartUTC, to: date.DtstartUTC}
+				continue
+			}
+			if date.DtstartUTC.Before(s.from) {
+				s.from = date.DtstartUTC
+			}
+			if date.DtstartUTC.After(s.to) {
+				s.to = date.DtstartUTC
+			}
+		}
+	}
+
+	zones := make([]string, 0, len(spans))
+	for zone := range spans {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	for _, zone := range zones {
+		b.Timezone(locations.get(&zone), spans[zone].from, spans[zone].to)
+	}
+
+	domain := h.uidDomain()
+	for _, event := range events {
+		if len(event.EventDates) == 0 {
+			continue
+		}
+
+		dates := distinctStarts(event.EventDates)
+		uid := fmt.Sprintf("event-%d@%s", event.ID, domain)
+		rule, rdates := detectRecurrence(dates, locations)
+		h.writeVEvent(b, event, uid, dates[0], locations, rule, rdates, false)
+
+		for _, date := range dates[1:] {
+			if !sameShape(dates[0], date) {
+				h.writeVEvent(b, event, uid, date, locations, "", nil, true)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// writeVEvent writes a single VEVENT starting at date, optionally repeating
+// via rule or rdates. An override replaces the series occurrence starting at
+// date, identified by its UTC start.
+func (h *Handler) writeVEvent(b *utils.ICalBuilder, event Events, uid string, date EventDates, locations *locationCache, rule string, rdates []time.Time, override bool) {
+	loc := locations.get(date.TZ)
+
+	stamp := event.UpdatedAt
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	b.Begin("VEVENT")
+	b.Line("UID", uid)
+	b.Line("DTSTAMP", utils.FormatICalUTC(stamp))
+	b.Line("LAST-MODIFIED", utils.FormatICalUTC(stamp))
+	if override {
+		b.Line("RECURRENCE-ID", utils.FormatICalUTC(date.DtstartUTC))
+	}
+	b.Line(dateTimeProperty("DTSTART", date.DtstartUTC, loc))
+
+	if date.DtendUTC != nil {
+		b.Line(dateTimeProperty("DTEND", *date.DtendUTC, loc))
+	} else if date.Duration != nil {
+		b.Line("DURATION", utils.FormatICalDuration(*date.Duration))
+	}
+
+	if rule != "" {
+		b.Line("RRULE", rule)
+	}
+	if len(rdates) > 0 {
+		values := make([]string, len(rdates))
+		for i, rdate := range rdates {
+			_, values[i] = dateTimeProperty("", rdate, loc)
+		}
+		name, _ := dateTimeProperty("RDATE", rdates[0], loc)
+		b.Line(name, strings.Join(values, ","))
+	}
+
+	if event.Title != nil {
+		b.Text("SUMMARY", *event.Title)
+	}
+	if event.Description != nil && *event.Description != "" {
+		b.Text("DESCRIPTION", *event.Description)
+	}
+	if event.Location != nil && *event.Location != "" {
+		b.Text("LOCATION", *event.Location)
+	}
+	if len(event.Categories) > 0 {
+		escaped := make([]string, len(event.Categories))
+		for i, category := range event.Categories {
+			escaped[i] = utils.EscapeICalText(category)
+		}
+		b.Line("CATEGORIES", strings.Join(escaped, ","))
+	}
+	b.Line("URL", utils.BuildEventURL(event.ID, h.SiteURL))
+	b.Line("STATUS", "CONFIRMED")
+	b.End("VEVENT")
+}
+
+// uidDomain is the right-hand side of generated UIDs; it must stay fixed so
+// calendar clients update existing entries on re-import instead of duplicating
+func (h *Handler) uidDomain() string {
+	if parsed, err := url.Parse(h.SiteURL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return "wat2do.ca"
+}
+
+// dateTimeProperty returns a property name and DATE-TIME value, using a TZID
+// parameter and local time when the occurrence has a known timezone
+func dateTimeProperty(name string, t time.Time, loc *time.Location) (string, string) {
+	if loc == nil {
+		return name, utils.FormatICalUTC(t)
+	}
+	return name + ";TZID=" + loc.String(), utils.FormatICalLocal(t, loc)
+}
+
+// detectRecurrence describes how the occurrences after the first repeat it.
+// When every occurrence shares the first's timezone and duration and they're
+// evenly spaced at the same wall-clock time an RRULE is returned; otherwise
+// the later starts are returned as RDATEs, and those that differ in shape get
+// overrides from buildCalendar.
+func detectRecurrence(dates []EventDates, locations *locationCache) (string, []time.Time) {
+	if len(dates) == 1 {
+		return "", nil
+	}
+
+	first := dates[0]
+	uniform := true
+	for _, date := range dates[1:] {
+		uniform = uniform && sameShape(first, date)
+	}
+
+	loc := locations.get(first.TZ)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if step := civilDaysBetween(first.DtstartUTC, dates[1].DtstartUTC, loc); uniform && step > 0 {
+		regular := true
+		for i := 1; i < len(dates) && regular; i++ {
+			prev, cur := dates[i-1].DtstartUTC.In(loc), dates[i].DtstartUTC.In(loc)
+			regular = civilDaysBetween(prev, cur, loc) == step &&
+				prev.Hour() == cur.Hour() && prev.Minute() == cur.Minute() && prev.Second() == cur.Second()
+		}
+
+		if regular {
+			freq, interval := "DAILY", step
+			if step%7 == 0 {
+				freq, interval = "WEEKLY", step/7
+			}
+			rule := "FREQ=" + freq
+			if interval > 1 {
+				rule += fmt.Sprintf(";INTERVAL=%d", interval)
+			}
+			return rule + fmt.Sprintf(";COUNT=%d", len(dates)), nil
+		}
+	}
+
+	rdates := make([]time.Time, 0, len(dates)-1)
+	for _, date := range dates[1:] {
+		rdates = append(rdates, date.DtstartUTC)
+	}
+	return "", rdates
+}
+
+// distinctStarts drops occurrences starting at the same instant as the one
+// before, since an occurrence's start is its identity within the series.
+// dates must be sorted by dtstart_utc.
+func distinctStarts(dates []EventDates) []EventDates {
+	distinct := dates[:1:1]
+	for _, date := range dates[1:] {
+		if !date.DtstartUTC.Equal(distinct[len(distinct)-1].DtstartUTC) {
+			distinct = append(distinct, date)
+		}
+	}
+	return distinct
+}
+
+// sameShape reports whether two occurrences share a timezone and duration
+func sameShape(a, b EventDates) bool {
+	da, db := occurrenceDuration(a), occurrenceDuration(b)
+	return sameZone(a.TZ, b.TZ) && (da == nil) == (db == nil) && (da == nil || *da == *db)
+}
+
+// occurrenceDuration returns the length of an occurrence, if known
+func occurrenceDuration(date EventDates) *time.Duration {
+	if date.DtendUTC != nil {
+		d := date.DtendUTC.Sub(date.DtstartUTC)
+		return &d
+	}
+	return date.Duration
+}
+
+// civilDaysBetween counts calendar days between a and b as observed in loc
+func civilDaysBetween(a, b time.Time, loc *time.Location) int {
+	ay, am, ad := a.In(loc).Date()
+	by, bm, bd := b.In(loc).Date()
+	da := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	db := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da) / (24 * time.Hour))
+}
+
+func sameZone(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// locationCache memoises time.LoadLocation for EventDates.TZ values; unknown or
+// empty zones resolve to nil and are written as UTC
+type locationCache struct {
+	byName map[string]*time.Location
+}
+
+func newLocationCache() *locationCache {
+	return &locationCache{byName: map[string]*time.Location{}}
+}
+
+func (lc *locationCache) get(tz *string) *time.Location {
+	if tz == nil || *tz == "" || *tz == "UTC" {
+		return nil
+	}
+
+	loc, ok := lc.byName[*tz]
+	if !ok {
+		loc, _ = time.LoadLocation(*tz)
+		lc.byName[*tz] = loc
+	}
+	return loc
+}
@@ -0,0 +1,289 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the zones below don't depend on the host's zone database
+)
+
+var toronto = "America/Toronto"
+
+// occurrences returns dates starting at each of starts (UTC), lasting length
+// and observed in tz when it's set
+func occurrences(tz *string, length time.Duration, starts ...time.Time) []EventDates {
+	dates := make([]EventDates, len(starts))
+	for i, start := range starts {
+		end := start.Add(length)
+		dates[i] = EventDates{DtstartUTC: start.UTC(), DtendUTC: &end, TZ: tz}
+	}
+	return dates
+}
+
+// wallClock returns the UTC instant of a wall-clock time in Toronto
+func wallClock(t *testing.T, year int, month time.Month, day, hour int) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation(toronto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(year, month, day, hour, 0, 0, 0, loc).UTC()
+}
+
+func TestDetectRecurrence(t *testing.T) {
+	utc := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	longer := occurrences(nil, 3*time.Hour, utc(1, 8, 18))[0]
+
+	tests := []struct {
+		name   string
+		dates  []EventDates
+		rule   string
+		rdates int
+	}{
+		{"single", occurrences(nil, time.Hour, utc(1, 1, 18)), "", 0},
+		{"daily", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 2, 18), utc(1, 3, 18)), "FREQ=DAILY;COUNT=3", 0},
+		{"every third day", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 4, 18), utc(1, 7, 18)), "FREQ=DAILY;INTERVAL=3;COUNT=3", 0},
+		{"weekly", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 8, 18)), "FREQ=WEEKLY;COUNT=2", 0},
+		{"fortnightly", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 15, 18), utc(1, 29, 18)), "FREQ=WEEKLY;INTERVAL=2;COUNT=3", 0},
+		{"irregular", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 8, 18), utc(1, 10, 18)), "", 2},
+		{"same day, different hour", occurrences(nil, time.Hour, utc(1, 1, 18), utc(1, 8, 19)), "", 1},
+		{"twice a day", occurrences(nil, time.Hour, utc(1, 1, 10), utc(1, 1, 18)), "", 1},
+		{"different duration", append(occurrences(nil, time.Hour, utc(1, 1, 18)), longer), "", 1},
+		{
+			// 6pm every Monday in Toronto: the UTC start moves an hour when
+			// DST begins, but the wall clock doesn't, so it's still a rule
+			"weekly across DST, same wall clock",
+			occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18), wallClock(t, 2026, 3, 9, 18), wallClock(t, 2026, 3, 16, 18)),
+			"FREQ=WEEKLY;COUNT=3", 0,
+		},
+		{
+			"weekly across the fall change, same wall clock",
+			occurrences(&toronto, time.Hour, wallClock(t, 2026, 10, 26, 18), wallClock(t, 2026, 11, 2, 18)),
+			"FREQ=WEEKLY;COUNT=2", 0,
+		},
+		{
+			// The same UTC time each week is 6pm then 7pm in Toronto
+			"weekly across DST, same UTC time",
+			occurrences(&toronto, time.Hour, utc(3, 2, 23), utc(3, 9, 23), utc(3, 16, 23)),
+			"", 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, rdates := detectRecurrence(tt.dates, newLocationCache())
+			if rule != tt.rule || len(rdates) != tt.rdates {
+				t.Fatalf("detectRecurrence = %q, %d RDATEs; want %q, %d", rule, len(rdates), tt.rule, tt.rdates)
+			}
+			for i, rdate := range rdates {
+				if !rdate.Equal(tt.dates[i+1].DtstartUTC) {
+					t.Errorf("RDATE %d = %s, want %s", i, rdate, tt.dates[i+1].DtstartUTC)
+				}
+			}
+		})
+	}
+}
+
+// vevents returns the unfolded content lines of each VEVENT in a calendar
+func vevents(calendar string) [][]string {
+	var found [][]string
+	var current []string
+	for _, line := range strings.Split(strings.ReplaceAll(calendar, "\r\n ", ""), "\r\n") {
+		switch line {
+		case "BEGIN:VEVENT":
+			current = nil
+		case "END:VEVENT":
+			found = append(found, current)
+		default:
+			current = append(current, line)
+		}
+	}
+	return found
+}
+
+// property returns the value of the first line in lines named name (which may
+// include parameters)
+func property(lines []string, name string) string {
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, name+":"); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestBuildCalendarRecurrence(t *testing.T) {
+	h := NewHandler(nil, Options{SiteURL: "https://wat2do.ca"})
+	title := "Board Games"
+
+	tests := []struct {
+		name  string
+		dates []EventDates
+		// want are lines of the series' VEVENT
+		want []string
+		// overrides are the RECURRENCE-IDs expected after it
+		overrides []string
+	}{
+		{
+			name:  "one-off in UTC",
+			dates: occurrences(nil, 2*time.Hour, time.Date(2026, 1, 5, 23, 0, 0, 0, time.UTC)),
+			want:  []string{"DTSTART:20260105T230000Z", "DTEND:20260106T010000Z"},
+		},
+		{
+			name:  "weekly across DST",
+			dates: occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18), wallClock(t, 2026, 3, 9, 18), wallClock(t, 2026, 3, 16, 18)),
+			want: []string{
+				"DTSTART;TZID=America/Toronto:20260302T180000",
+				"DTEND;TZID=America/Toronto:20260302T190000",
+				"RRULE:FREQ=WEEKLY;COUNT=3",
+			},
+		},
+		{
+			name:  "fixed UTC time across DST",
+			dates: occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18), wallClock(t, 2026, 3, 9, 19)),
+			want: []string{
+				"DTSTART;TZID=America/Toronto:20260302T180000",
+				"RDATE;TZID=America/Toronto:20260309T190000",
+			},
+		},
+		{
+			name: "one longer occurrence",
+			dates: append(
+				occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18), wallClock(t, 2026, 3, 9, 18)),
+				occurrences(&toronto, 3*time.Hour, wallClock(t, 2026, 3, 16, 18))...,
+			),
+			want: []string{
+				"DTSTART;TZID=America/Toronto:20260302T180000",
+				"RDATE;TZID=America/Toronto:20260309T180000,20260316T180000",
+			},
+			overrides: []string{"20260316T220000Z"},
+		},
+		{
+			name: "one occurrence in another zone",
+			dates: append(
+				occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18)),
+				occurrences(nil, time.Hour, time.Date(2026, 3, 9, 22, 0, 0, 0, time.UTC))...,
+			),
+			want:      []string{"RDATE;TZID=America/Toronto:20260309T180000"},
+			overrides: []string{"20260309T220000Z"},
+		},
+		{
+			name: "duplicate starts",
+			dates: append(
+				occurrences(nil, time.Hour, time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC)),
+				occurrences(nil, time.Hour, time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC), time.Date(2026, 1, 6, 18, 0, 0, 0, time.UTC))...,
+			),
+			want: []string{"RRULE:FREQ=DAILY;COUNT=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := Events{ID: 42, Title: &title, EventDates: tt.dates}
+			events := vevents(h.buildCalendar([]Events{event}, "", 0))
+			if len(events) != 1+len(tt.overrides) {
+				t.Fatalf("%d VEVENTs, want the series and %d overrides:\n%q", len(events), len(tt.overrides), events)
+			}
+
+			series := strings.Join(events[0], "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(series, want+"\n") {
+					t.Errorf("series is missing %q:\n%s", want, series)
+				}
+			}
+			if property(events[0], "RECURRENCE-ID") != "" {
+				t.Error("the series has a RECURRENCE-ID")
+			}
+
+			for i, override := range events[1:] {
+				if uid := property(override, "UID"); uid != "event-42@wat2do.ca" {
+					t.Errorf("override UID = %q, want the series'", uid)
+				}
+				if id := property(override, "RECURRENCE-ID"); id != tt.overrides[i] {
+					t.Errorf("override RECURRENCE-ID = %q, want %q", id, tt.overrides[i])
+				}
+				if property(override, "RRULE") != "" || property(override, "RDATE") != "" {
+					t.Errorf("override repeats:\n%s", strings.Join(override, "\n"))
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCalendarTimezones(t *testing.T) {
+	h := NewHandler(nil, Options{SiteURL: "https://wat2do.ca"})
+	sydney := "Australia/Sydney"
+	title := "Event"
+	calendar := h.buildCalendar([]Events{
+		{ID: 1, Title: &title, EventDates: occurrences(&toronto, time.Hour, wallClock(t, 2026, 3, 2, 18), wallClock(t, 2027, 3, 1, 18))},
+		{ID: 2, Title: &title, EventDates: occurrences(&toronto, time.Hour, wallClock(t, 2026, 6, 2, 18))},
+		{ID: 3, Title: &title, EventDates: occurrences(&sydney, time.Hour, time.Date(2026, 6, 2, 8, 0, 0, 0, time.UTC))},
+		{ID: 4, Title: &title, EventDates: occurrences(nil, time.Hour, time.Date(2026, 6, 2, 8, 0, 0, 0, time.UTC))},
+	}, "", 0)
+
+	unfolded := strings.ReplaceAll(calendar, "\r\n ", "")
+	// One per zone referenced, sorted, before the events
+	if got := strings.Count(unfolded, "BEGIN:VTIMEZONE"); got != 2 {
+		t.Errorf("%d VTIMEZONEs, want 2", got)
+	}
+	torontoAt, sydneyAt := strings.Index(unfolded, "TZID:America/Toronto\r\n"), strings.Index(unfolded, "TZID:Australia/Sydney\r\n")
+	if torontoAt < 0 || sydneyAt < 0 || torontoAt > sydneyAt || sydneyAt > strings.Index(unfolded, "BEGIN:VEVENT") {
+		t.Errorf("VTIMEZONEs aren't sorted ahead of the events:\n%s", calendar)
+	}
+	// Toronto's spans both years of its occurrences
+	for _, onset := range []string{"DTSTART:20260308T020000", "DTSTART:20261101T020000", "DTSTART:20270314T020000", "DTSTART:20271107T020000"} {
+		if !strings.Contains(unfolded, onset+"\r\n") {
+			t.Errorf("VTIMEZONE is missing the %s transition", onset)
+		}
+	}
+}
+
+func TestBuildCalendarText(t *testing.T) {
+	h := NewHandler(nil, Options{SiteURL: "https://wat2do.ca"})
+	title := "Pizza, Pop; & Games \\o/"
+	description := "Bring:\n- a friend\n- " + strings.Repeat("snacks 🍕 for everyone, ", 6)
+	location := "MC 4020; Math & Computer"
+	event := Events{
+		ID:          7,
+		Title:       &title,
+		Description: &description,
+		Location:    &location,
+		Categories:  []string{"Games", "Food, Drink"},
+		EventDates:  occurrences(nil, time.Hour, time.Date(2026, 1, 5, 23, 0, 0, 0, time.UTC)),
+	}
+	calendar := h.buildCalendar([]Events{event}, "Wat2Do; UW", time.Hour)
+
+	for _, line := range strings.Split(calendar, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+	}
+	if !strings.HasSuffix(calendar, "END:VCALENDAR\r\n") {
+		t.Error("calendar isn't CRLF terminated")
+	}
+
+	lines := strings.Split(strings.ReplaceAll(calendar, "\r\n ", ""), "\r\n")
+	for name, want := range map[string]string{
+		"X-WR-CALNAME":                    `Wat2Do\; UW`,
+		"REFRESH-INTERVAL;VALUE=DURATION": "PT1H",
+		"X-PUBLISHED-TTL":                 "PT1H",
+	} {
+		if got := property(lines, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	vevent := vevents(calendar)[0]
+	for name, want := range map[string]string{
+		"UID":         "event-7@wat2do.ca",
+		"SUMMARY":     `Pizza\, Pop\; & Games \\o/`,
+		"DESCRIPTION": `Bring:\n- a friend\n- ` + strings.Repeat(`snacks 🍕 for everyone\, `, 6),
+		"LOCATION":    `MC 4020\; Math & Computer`,
+		"CATEGORIES":  `Games,Food\, Drink`,
+		"URL":         "https://wat2do.ca/events/7",
+	} {
+		if got := property(vevent, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
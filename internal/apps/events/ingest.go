@@ -0,0 +1,309 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InstagramPost is a scraped Instagram post as delivered by a PostSource
+type InstagramPost struct {
+	Shortcode     string    `json:"shortcode"`
+	Handle        string    `json:"handle"`
+	Caption       string    `json:"caption"`
+	ImageURL      string    `json:"image_url"`
+	PostedAt      time.Time `json:"posted_at"`
+	LikesCount    int       `json:"likes_count"`
+	CommentsCount int       `json:"comments_count"`
+}
+
+// URL is the post's public permalink
+func (p InstagramPost) URL() string {
+	return "https://www.instagram.com/p/" + p.Shortcode + "/"
+}
+
+// PostSource supplies posts to ingest, e.g. from a scraper or a recording
+type PostSource interface {
+	FetchPosts(ctx context.Context) ([]InstagramPost, error)
+}
+
+// FilePostSource reads posts from a JSON file holding an array of
+// InstagramPost, for offline runs from recorded scrapes
+type FilePostSource struct {
+	Path string
+}
+
+// FetchPosts reads and decodes the file
+func (s FilePostSource) FetchPosts(ctx context.Context) ([]InstagramPost, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var posts []InstagramPost
+	if err := json.Unmarshal(data, &posts); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", s.Path, err)
+	}
+	return posts, nil
+}
+
+// PostExtractor turns a post's caption and image into event data;
+// *services.OpenAIService implements it
+type PostExtractor interface {
+	ExtractEventsFromPost(ctx context.Context, caption, imageURL, model string) ([]services.EventExtractionResult, error)
+}
+
+// IngestReport counts what one ingestion pass did with each post
+type IngestReport struct {
+	Fetched  int `json:"fetched"`
+	Ignored  int `json:"ignored"`  // listed in ignored_posts
+	Existing int `json:"existing"` // already ingested
+	NoEvents int `json:"no_events"`
+	Created  int `json:"created"` // events, not posts
+	Failed   int `json:"failed"`
+}
+
+// Ingester turns scraped Instagram posts into events
+type Ingester struct {
+	DB        *gorm.DB
+	Source    PostSource
+	Extractor PostExtractor
+	Images    *services.ImageService // optional; copies post images to our storage
+	Realtime  realtime.Publisher     // optional; announces created events
+	Interval  time.Duration          // between Run passes
+}
+
+// Run ingests once per Interval until ctx is cancelled
+func (i *Ingester) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := i.IngestOnce(ctx)
+		if err != nil {
+			log.Printf("Instagram ingestion failed: %v", err)
+		} else {
+			log.Printf("Instagram ingestion: %+v", report)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// IngestOnce fetches posts from the source and ingests each one. A post that
+// fails is counted and left for the next pass; it doesn't stop the others.
+func (i *Ingester) IngestOnce(ctx context.Context) (IngestReport, error) {
+	var report IngestReport
+
+	posts, err := i.Source.FetchPosts(ctx)
+	if err != nil {
+		return report, fmt.Errorf("fetch posts: %w", err)
+	}
+	report.Fetched = len(posts)
+
+	clubsByHandle, err := i.clubsByHandle()
+	if err != nil {
+		return report, fmt.Errorf("load clubs: %w", err)
+	}
+
+	for _, post := range posts {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		if post.Shortcode == "" {
+			report.Failed++
+			continue
+		}
+
+		created, err := i.ingestPost(ctx, post, clubsByHandle[utils.NormalizeHandle(post.Handle)])
+		switch {
+		case errors.Is(err, errPostIgnored):
+			report.Ignored++
+		case errors.Is(err, errPostIngested):
+			report.Existing++
+		case err != nil:
+			log.Printf("Instagram ingestion: post %s: %v", post.Shortcode, err)
+			report.Failed++
+		case created == 0:
+			report.NoEvents++
+		default:
+			report.Created += created
+		}
+	}
+
+	return report, nil
+}
+
+var (
+	errPostIgnored  = errors.New("post is ignored")
+	errPostIngested = errors.New("post was already ingested")
+)
+
+// ingestPost extracts and stores the events of one post, returning how many
+// were created. Posts without events are added to ignored_posts so they
+// aren't extracted again.
+func (i *Ingester) ingestPost(ctx context.Context, post InstagramPost, club *clubs.Clubs) (int, error) {
+	var ignored int64
+	if err := i.DB.Model(&IgnoredPost{}).Where("shortcode = ?", post.Shortcode).Count(&ignored).Error; err != nil {
+		return 0, err
+	}
+	if ignored > 0 {
+		return 0, errPostIgnored
+	}
+
+	// Unscoped: a merged or deleted event still marks its post as ingested
+	var existing int64
+	if err := i.DB.Unscoped().Model(&Events{}).Where("source_url = ?", post.URL()).Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, errPostIngested
+	}
+
+	results, err := i.Extractor.ExtractEventsFromPost(ctx, post.Caption, post.ImageURL, extractionModel)
+	if err != nil {
+		return 0, fmt.Errorf("extract: %w", err)
+	}
+
+	events := make([]Events, 0, len(results))
+	for _, result := range results {
+		event, err := postEvent(post, club, result)
+		if err != nil {
+			// Usually a caption that mentions an event without a usable date
+			log.Printf("Instagram ingestion: post %s: skipping %q: %v", post.Shortcode, result.Title, err)
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		err := i.DB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&IgnoredPost{Shortcode: post.Shortcode, AddedAt: time.Now()}).Error
+		return 0, err
+	}
+
+	// Instagram CDN URLs expire, so keep our own copy of the image
+	if i.Images != nil && post.ImageURL != "" {
+		stored, err := i.Images.StoreImageFromURL(ctx, "instagram/"+post.Shortcode, post.ImageURL)
+		if err != nil {
+			log.Printf("Instagram ingestion: post %s: storing image: %v", post.Shortcode, err)
+		} else {
+			for j := range events {
+				events[j].SourceImageURL = &stored.URL
+				events[j].ImageVariants = stored.Variants
+				events[j].ImageSizes = stored.Sizes
+				events[j].ImagePHash = &stored.PHash
+			}
+		}
+	}
+
+	err = i.DB.Transaction(func(tx *gorm.DB) error {
+		for j := range events {
+			if err := tx.Create(&events[j]).Error; err != nil {
+				return err
+			}
+			if _, err := flagDuplicates(tx, &events[j]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if *event.Status == EventStatusConfirmed {
+			publishEvent(i.Realtime, "event.created", event)
+		} else {
+			publish(i.Realtime, "event.pending", gin.H{"event_id": event.ID, "title": event.Title}, realtime.TopicModeration)
+		}
+	}
+	return len(events), nil
+}
+
+// postEvent builds an event from one extraction result. Posts from a known
+// club are published directly; others wait for review.
+func postEvent(post InstagramPost, club *clubs.Clubs, result services.EventExtractionResult) (Events, error) {
+	draft := map[string]interface{}{
+		"title":        result.Title,
+		"description":  result.Description,
+		"location":     result.Location,
+		"food":         result.Food,
+		"registration": result.Registration,
+		"source_url":   post.URL(),
+		"ig_handle":    utils.NormalizeHandle(post.Handle),
+	}
+	if result.Price != nil {
+		draft["price"] = *result.Price
+	}
+	categories := make([]interface{}, len(result.Categories))
+	for i, category := range result.Categories {
+		categories[i] = category
+	}
+	draft["categories"] = categories
+	occurrences := make([]interface{}, len(result.Occurrences))
+	for i, occurrence := range result.Occurrences {
+		occurrences[i] = occurrence
+	}
+	draft["occurrences"] = occurrences
+	if post.ImageURL != "" {
+		draft["source_image_url"] = post.ImageURL
+	}
+
+	data, err := utils.ValidateEventData(draft)
+	if err != nil {
+		return Events{}, err
+	}
+
+	status := EventStatusPending
+	now := time.Now()
+	event := Events{Status: &status}
+	applyEventData(&event, data)
+	event.EventDates = newEventDates(0, data["occurrences"].([]utils.EventOccurrence))
+	event.LikesCount = post.LikesCount
+	event.CommentsCount = post.CommentsCount
+	if !post.PostedAt.IsZero() {
+		event.PostedAt = &post.PostedAt
+	}
+
+	if club != nil {
+		status = EventStatusConfirmed
+		event.AddedAt = &now
+		event.ClubType = club.ClubType
+	}
+
+	return event, nil
+}
+
+// clubsByHandle indexes clubs by normalised Instagram handle
+func (i *Ingester) clubsByHandle() (map[string]*clubs.Clubs, error) {
+	var rows []clubs.Clubs
+	if err := i.DB.Select("id", "club_name", "ig", "club_type").Where("ig IS NOT NULL AND ig <> ''").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	byHandle := make(map[string]*clubs.Clubs, len(rows))
+	for j := range rows {
+		if handle := utils.NormalizeHandle(*rows[j].IG); handle != "" {
+			byHandle[handle] = &rows[j]
+		}
+	}
+	return byHandle, nil
+}
@@ -0,0 +1,179 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+)
+
+// recordedExtractor is a PostExtractor answering from canned results keyed
+// by caption; unknown captions have no events
+type recordedExtractor struct {
+	results map[string][]services.EventExtractionResult
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (e *recordedExtractor) ExtractEventsFromPost(ctx context.Context, caption, imageURL, model string) ([]services.EventExtractionResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.calls == nil {
+		e.calls = map[string]int{}
+	}
+	e.calls[caption]++
+	return e.results[caption], nil
+}
+
+func (e *recordedExtractor) totalCalls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	total := 0
+	for _, n := range e.calls {
+		total += n
+	}
+	return total
+}
+
+func newTestIngester(t *testing.T) (*Ingester, *recordedExtractor) {
+	t.Helper()
+	db := testutil.OpenDB(t, &clubs.Clubs{}, &Events{}, &EventDates{}, &IgnoredPost{}, &DuplicateFlag{})
+
+	// Raw, since Create would read back Clubs.Categories, which has no serializer
+	err := db.Exec("INSERT INTO clubs (club_name, ig, club_type, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		"CS Club", "uwcsclub", "WUSA", time.Now(), time.Now()).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extractor := &recordedExtractor{results: map[string][]services.EventExtractionResult{
+		"Join us for Code Party this Friday 7pm in MC Comfy Lounge! Free pizza 🍕": {{
+			Title:      "Code Party",
+			Location:   "MC Comfy Lounge",
+			Food:       "Pizza",
+			Categories: []string{"Tech"},
+			Occurrences: []map[string]interface{}{
+				{"dtstart_utc": "2026-02-07T00:00:00Z", "dtend_utc": "2026-02-07T03:00:00Z", "tz": "America/Toronto"},
+			},
+		}},
+		"Intro meeting next Tuesday at 6pm, SLC Great Hall": {{
+			Title:    "Intro Meeting",
+			Location: "SLC Great Hall",
+			Occurrences: []map[string]interface{}{
+				{"dtstart_utc": "2026-02-10T23:00:00Z", "dtend_utc": nil, "tz": "America/Toronto"},
+			},
+		}},
+	}}
+
+	return &Ingester{
+		DB:        db,
+		Source:    FilePostSource{Path: "testdata/instagram_posts.json"},
+		Extractor: extractor,
+	}, extractor
+}
+
+func TestIngestOnceFromRecording(t *testing.T) {
+	ingester, extractor := newTestIngester(t)
+	ctx := context.Background()
+
+	report, err := ingester.IngestOnce(ctx)
+	if err != nil {
+		t.Fatalf("IngestOnce: %v", err)
+	}
+	want := IngestReport{Fetched: 4, Created: 2, NoEvents: 1, Failed: 1}
+	if report != want {
+		t.Fatalf("first pass = %+v, want %+v", report, want)
+	}
+
+	var events []Events
+	if err := ingester.DB.Preload("EventDates").Order("id").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %d, want 2", len(events))
+	}
+	club, unknown := events[0], events[1]
+	if *club.Status != EventStatusConfirmed || club.ClubType == nil || *club.ClubType != "WUSA" || club.AddedAt == nil {
+		t.Errorf("club post event = %+v, want a confirmed WUSA event", club)
+	}
+	if *club.SourceURL != "https://www.instagram.com/p/C0ClubPost1/" || club.LikesCount != 120 || len(club.EventDates) != 1 {
+		t.Errorf("club post event = %+v, want the post's URL, likes and date", club)
+	}
+	if *unknown.Status != EventStatusPending || *unknown.IGHandle != "newclub.uw" {
+		t.Errorf("unknown club event = %+v, want pending from newclub.uw", unknown)
+	}
+
+	var ignored IgnoredPost
+	if err := ingester.DB.Where("shortcode = ?", "C0NoEvent33").First(&ignored).Error; err != nil {
+		t.Errorf("post without events was not ignored: %v", err)
+	}
+
+	// A second pass extracts nothing again
+	calls := extractor.totalCalls()
+	report, err = ingester.IngestOnce(ctx)
+	if err != nil {
+		t.Fatalf("IngestOnce: %v", err)
+	}
+	want = IngestReport{Fetched: 4, Existing: 2, Ignored: 1, Failed: 1}
+	if report != want {
+		t.Errorf("second pass = %+v, want %+v", report, want)
+	}
+	if extractor.totalCalls() != calls {
+		t.Errorf("second pass called the extractor %d more times", extractor.totalCalls()-calls)
+	}
+}
+
+func TestIngestOnceSkipsDeletedEvents(t *testing.T) {
+	ingester, extractor := newTestIngester(t)
+	ctx := context.Background()
+
+	if _, err := ingester.IngestOnce(ctx); err != nil {
+		t.Fatalf("IngestOnce: %v", err)
+	}
+
+	// A merge or moderator soft-deletes the event; its post must stay ingested
+	var event Events
+	if err := ingester.DB.Where("source_url = ?", "https://www.instagram.com/p/C0Unknown22/").First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := ingester.DB.Delete(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ingester.IngestOnce(ctx)
+	if err != nil {
+		t.Fatalf("IngestOnce: %v", err)
+	}
+	if report.Created != 0 || report.Existing != 2 {
+		t.Errorf("pass after delete = %+v, want both posts existing", report)
+	}
+	if n := extractor.calls["Intro meeting next Tuesday at 6pm, SLC Great Hall"]; n != 1 {
+		t.Errorf("deleted event's post was extracted %d times, want 1", n)
+	}
+
+	var count int64
+	ingester.DB.Unscoped().Model(&Events{}).Where("source_url = ?", *event.SourceURL).Count(&count)
+	if count != 1 {
+		t.Errorf("events for the deleted post = %d, want 1", count)
+	}
+}
+
+// The recording decodes as the scraper writes it
+func TestFilePostSource(t *testing.T) {
+	posts, err := FilePostSource{Path: "testdata/instagram_posts.json"}.FetchPosts(context.Background())
+	if err != nil {
+		t.Fatalf("FetchPosts: %v", err)
+	}
+	if len(posts) != 4 || posts[0].Handle != "@UWCSClub" || posts[0].PostedAt.IsZero() {
+		t.Errorf("posts = %+v", posts)
+	}
+
+	if _, err := (FilePostSource{Path: "testdata/missing.json"}).FetchPosts(context.Background()); err == nil {
+		t.Error("FetchPosts of a missing file succeeded")
+	}
+}
@@ -0,0 +1,206 @@
+package events
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddEventInterest handles POST /api/events/:id/interest - mark the caller as
+// interested in an event. Repeating the request is a no-op.
+// Requires: JWT authentication
+func (h *Handler) AddEventInterest(c *gin.Context) {
+	h.setInterest(c, true)
+}
+
+// RemoveEventInterest handles DELETE /api/events/:id/interest - remove the
+// caller's interest in an event. Removing a missing interest is a no-op.
+// Requires: JWT authentication
+func (h *Handler) RemoveEventInterest(c *gin.Context) {
+	h.setInterest(c, false)
+}
+
+// setInterest adds or removes the caller's interest and responds with the
+// event's new interest count
+func (h *Handler) setInterest(c *gin.Context, interested bool) {
+	userID := c.GetString(core.ContextUserID)
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var event Events
+	err = h.DB.Select("id").Where("id = ? AND status = ?", eventID, EventStatusConfirmed).Take(&event).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	if interested {
+		// A removed interest is soft-deleted and still holds the unique
+		// (event_id, user_id) slot, so restore it instead of inserting
+		err = h.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "event_id"}, {Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "event_interests.deleted_at IS NOT NULL"}}},
+		}).Create(&EventInterest{EventID: event.ID, UserID: userID}).Error
+	} else {
+		err = h.DB.Where("event_id = ? AND user_id = ?", event.ID, userID).Delete(&EventInterest{}).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update interest"})
+		return
+	}
+
+	counts, err := interestCounts(h.DB, []uint{event.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":       event.ID,
+		"is_interested":  interested,
+		"interest_count": counts[event.ID],
+	})
+}
+
+// GetMyInterestedEvents handles GET /api/events/my-interests - the caller's
+// interested events, split into upcoming (soonest first) and past (most
+// recent first)
+// Requires: JWT authentication
+func (h *Handler) GetMyInterestedEvents(c *gin.Context) {
+	userID := c.GetString(core.ContextUserID)
+
+	var events []Events
+	err := h.DB.Preload("EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).
+		Joins("JOIN event_interests ei ON ei.event_id = events.id AND ei.deleted_at IS NULL").
+		Where("ei.user_id = ? AND events.status = ?", userID, EventStatusConfirmed).
+		Find(&events).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch events"})
+		return
+	}
+
+	now := time.Now().UTC()
+	items := make([]EventListItem, len(events))
+	for i, event := range events {
+		items[i] = newEventListItem(event, nil, now)
+	}
+	if err := h.annotateInterest(items, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count interest"})
+		return
+	}
+
+	upcoming := []EventListItem{}
+	past := []EventListItem{}
+	for i, item := range items {
+		if item.DtstartUTC != nil {
+			upcoming = append(upcoming, item)
+			continue
+		}
+		// Show past events at their last occurrence
+		if dates := events[i].EventDates; len(dates) > 0 {
+			last := dates[len(dates)-1]
+			start := last.DtstartUTC
+			item.DtstartUTC, item.DtendUTC, item.TZ = &start, last.DtendUTC, last.TZ
+		}
+		past = append(past, item)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].DtstartUTC.Before(*upcoming[j].DtstartUTC) })
+	sort.SliceStable(past, func(i, j int) bool { return startAfter(past[i].DtstartUTC, past[j].DtstartUTC) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"upcoming": upcoming,
+		"past":     past,
+	})
+}
+
+// startAfter orders past events by start descending, undated ones last
+func startAfter(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return b == nil && a != nil
+	}
+	return a.After(*b)
+}
+
+// annotateInterest fills in InterestCount for items, and IsInterested when
+// userID is set, with one query each
+func (h *Handler) annotateInterest(items []EventListItem, userID string) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	counts, err := interestCounts(h.DB, ids)
+	if err != nil {
+		return err
+	}
+	interested, err := interestedEventIDs(h.DB, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].InterestCount = counts[items[i].ID]
+		items[i].IsInterested = interested[items[i].ID]
+	}
+	return nil
+}
+
+// interestCounts returns the number of interested users per event
+func interestCounts(db *gorm.DB, eventIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		EventID uint
+		Count   int64
+	}
+	err := db.Model(&EventInterest{}).
+		Select("event_id, COUNT(*) AS count").
+		Where("event_id IN ?", eventIDs).
+		Group("event_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.EventID] = row.Count
+	}
+	return counts, nil
+}
+
+// interestedEventIDs returns which of eventIDs userID is interested in
+func interestedEventIDs(db *gorm.DB, userID string, eventIDs []uint) (map[uint]bool, error) {
+	interested := map[uint]bool{}
+	if userID == "" {
+		return interested, nil
+	}
+
+	var ids []uint
+	err := db.Model(&EventInterest{}).
+		Where("user_id = ? AND event_id IN ?", userID, eventIDs).
+		Pluck("event_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		interested[id] = true
+	}
+	return interested, nil
+}
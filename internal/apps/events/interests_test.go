@@ -0,0 +1,212 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+)
+
+// interestResponse is an interest endpoint's body
+type interestResponse struct {
+	EventID       uint  `json:"event_id"`
+	IsInterested  bool  `json:"is_interested"`
+	InterestCount int64 `json:"interest_count"`
+}
+
+func idParam(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// callInterest calls an interest handler on event id as userID
+func callInterest(t *testing.T, handler gin.HandlerFunc, id, userID string) (int, interestResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/events/"+id+"/interest", nil)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	c.Set(core.ContextUserID, userID)
+	handler(c)
+
+	var response interestResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, response
+}
+
+func TestEventInterestIsIdempotent(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	past := time.Now().Add(-time.Hour)
+	event := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	id := idParam(event.ID)
+
+	steps := []struct {
+		name       string
+		handler    gin.HandlerFunc
+		user       string
+		interested bool
+		count      int64
+	}{
+		{"add", h.AddEventInterest, "user_1", true, 1},
+		{"add again", h.AddEventInterest, "user_1", true, 1},
+		{"another user", h.AddEventInterest, "user_2", true, 2},
+		{"remove", h.RemoveEventInterest, "user_1", false, 1},
+		{"remove again", h.RemoveEventInterest, "user_1", false, 1},
+		{"remove never added", h.RemoveEventInterest, "user_3", false, 1},
+		{"re-add", h.AddEventInterest, "user_1", true, 2},
+		{"re-add again", h.AddEventInterest, "user_1", true, 2},
+	}
+	for _, step := range steps {
+		code, response := callInterest(t, step.handler, id, step.user)
+		if code != http.StatusOK {
+			t.Fatalf("%s = %d", step.name, code)
+		}
+		if response.EventID != event.ID || response.IsInterested != step.interested || response.InterestCount != step.count {
+			t.Errorf("%s = %+v, want interested %t, count %d", step.name, response, step.interested, step.count)
+		}
+
+		// The reported count is always the number of live rows
+		var live int64
+		db.Model(&EventInterest{}).Where("event_id = ?", event.ID).Count(&live)
+		if live != response.InterestCount {
+			t.Errorf("%s: reported %d, but %d users are interested", step.name, response.InterestCount, live)
+		}
+	}
+
+	// Re-adding revives the removed row rather than holding two
+	var rows int64
+	db.Unscoped().Model(&EventInterest{}).Where("event_id = ? AND user_id = ?", event.ID, "user_1").Count(&rows)
+	if rows != 1 {
+		t.Errorf("user_1 has %d interest rows, want 1", rows)
+	}
+}
+
+func TestEventInterestNeedsPublishedEvent(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	pending := createEvent(t, db, "Games Night", EventStatusPending, nil, time.Now().Add(24*time.Hour))
+
+	for _, tt := range []struct {
+		id   string
+		code int
+	}{
+		{"abc", http.StatusBadRequest},
+		{"-1", http.StatusBadRequest},
+		{"999", http.StatusNotFound},
+		{idParam(pending.ID), http.StatusNotFound},
+	} {
+		if code, _ := callInterest(t, h.AddEventInterest, tt.id, "user_1"); code != tt.code {
+			t.Errorf("interest in %q = %d, want %d", tt.id, code, tt.code)
+		}
+	}
+	var count int64
+	db.Unscoped().Model(&EventInterest{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d interests in unpublished events", count)
+	}
+}
+
+func TestGetMyInterestedEvents(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	now := time.Now().UTC().Truncate(time.Second)
+	past := now.Add(-30 * 24 * time.Hour)
+
+	later := createEvent(t, db, "Later", EventStatusConfirmed, &past, now.Add(48*time.Hour))
+	soon := createEvent(t, db, "Soon", EventStatusConfirmed, &past, now.Add(-72*time.Hour), now.Add(24*time.Hour))
+	lastWeek := createEvent(t, db, "Last week", EventStatusConfirmed, &past, now.Add(-7*24*time.Hour))
+	yesterday := createEvent(t, db, "Yesterday", EventStatusConfirmed, &past, now.Add(-10*24*time.Hour), now.Add(-24*time.Hour))
+	removed := createEvent(t, db, "Removed", EventStatusConfirmed, &past, now.Add(24*time.Hour))
+	notInterested := createEvent(t, db, "Not interested", EventStatusConfirmed, &past, now.Add(24*time.Hour))
+
+	for _, event := range []*Events{later, soon, lastWeek, yesterday, removed} {
+		callInterest(t, h.AddEventInterest, idParam(event.ID), "user_1")
+	}
+	callInterest(t, h.AddEventInterest, idParam(soon.ID), "user_2")
+	callInterest(t, h.RemoveEventInterest, idParam(removed.ID), "user_1")
+	callInterest(t, h.AddEventInterest, idParam(notInterested.ID), "user_2")
+	// Unpublishing an event hides it
+	unpublished := createEvent(t, db, "Unpublished", EventStatusConfirmed, &past, now.Add(24*time.Hour))
+	callInterest(t, h.AddEventInterest, idParam(unpublished.ID), "user_1")
+	db.Model(unpublished).Update("status", EventStatusRejected)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/events/my-interests", nil)
+	c.Set(core.ContextUserID, "user_1")
+	h.GetMyInterestedEvents(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("my-interests = %d %s", w.Code, w.Body)
+	}
+
+	var response struct {
+		Upcoming []EventListItem `json:"upcoming"`
+		Past     []EventListItem `json:"past"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	titles := func(items []EventListItem) []string {
+		var titles []string
+		for _, item := range items {
+			titles = append(titles, *item.Title)
+			if !item.IsInterested {
+				t.Errorf("%s isn't marked as interested", *item.Title)
+			}
+		}
+		return titles
+	}
+	if got := titles(response.Upcoming); len(got) != 2 || got[0] != "Soon" || got[1] != "Later" {
+		t.Errorf("upcoming = %v, want Soon then Later", got)
+	}
+	if got := titles(response.Past); len(got) != 2 || got[0] != "Yesterday" || got[1] != "Last week" {
+		t.Errorf("past = %v, want Yesterday then Last week", got)
+	}
+	if len(response.Upcoming) > 0 && response.Upcoming[0].InterestCount != 2 {
+		t.Errorf("Soon has interest count %d, want 2", response.Upcoming[0].InterestCount)
+	}
+	// Past events show their last occurrence
+	if len(response.Past) > 0 && !response.Past[0].DtstartUTC.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("Yesterday shown at %v", response.Past[0].DtstartUTC)
+	}
+}
+
+func TestAnnotateInterest(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	past := time.Now().Add(-time.Hour)
+	a := createEvent(t, db, "A", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	b := createEvent(t, db, "B", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	callInterest(t, h.AddEventInterest, idParam(a.ID), "user_1")
+	callInterest(t, h.AddEventInterest, idParam(a.ID), "user_2")
+	callInterest(t, h.AddEventInterest, idParam(b.ID), "user_2")
+
+	for _, tt := range []struct {
+		user       string
+		interested [2]bool
+	}{
+		{"user_1", [2]bool{true, false}},
+		{"user_2", [2]bool{true, true}},
+		{"", [2]bool{false, false}},
+	} {
+		items := []EventListItem{{Events: Events{ID: a.ID}}, {Events: Events{ID: b.ID}}}
+		if err := h.annotateInterest(items, tt.user); err != nil {
+			t.Fatal(err)
+		}
+		if items[0].InterestCount != 2 || items[1].InterestCount != 1 {
+			t.Errorf("counts = %d, %d; want 2, 1", items[0].InterestCount, items[1].InterestCount)
+		}
+		if got := [2]bool{items[0].IsInterested, items[1].IsInterested}; got != tt.interested {
+			t.Errorf("%q interested = %v, want %v", tt.user, got, tt.interested)
+		}
+	}
+}
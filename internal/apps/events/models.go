@@ -6,35 +6,45 @@ import (
 	"gorm.io/gorm"
 )
 
+// Event statuses stored in Events.Status
+const (
+	EventStatusPending   = "PENDING"
+	EventStatusConfirmed = "CONFIRMED"
+	EventStatusRejected  = "REJECTED"
+)
+
 // Events represents an event in the database
 type Events struct {
-	ID              uint           `gorm:"primaryKey" json:"id"`
-	Title           *string        `gorm:"type:text" json:"title"`
-	Description     *string        `gorm:"type:text" json:"description"`
-	Location        *string        `gorm:"type:text" json:"location"`
-	Categories      []string       `gorm:"type:jsonb;default:'[]'" json:"categories"`
-	Status          *string        `gorm:"size:32" json:"status"`
-	SourceURL       *string        `gorm:"type:text" json:"source_url"`
-	SourceImageURL  *string        `gorm:"type:text" json:"source_image_url"`
-	Reactions       map[string]int `gorm:"type:jsonb;default:'{}'" json:"reactions"`
-	PostedAt        *time.Time     `json:"posted_at"`
-	CommentsCount   int            `gorm:"default:0" json:"comments_count"`
-	LikesCount      int            `gorm:"default:0" json:"likes_count"`
-	Food            *string        `gorm:"size:255" json:"food"`
-	Registration    bool           `gorm:"default:false" json:"registration"`
-	AddedAt         *time.Time     `json:"added_at"`
-	Price           *float64       `json:"price"`
-	School          *string        `gorm:"size:255" json:"school"`
-	ClubType        *string        `gorm:"size:50" json:"club_type"`
-	IGHandle        *string        `gorm:"size:100;column:ig_handle" json:"ig_handle"`
-	DiscordHandle   *string        `gorm:"size:100" json:"discord_handle"`
-	XHandle         *string        `gorm:"size:100;column:x_handle" json:"x_handle"`
-	TiktokHandle    *string        `gorm:"size:100" json:"tiktok_handle"`
-	FBHandle        *string        `gorm:"size:100;column:fb_handle" json:"fb_handle"`
-	OtherHandle     *string        `gorm:"size:100" json:"other_handle"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Title          *string        `gorm:"type:text" json:"title"`
+	Description    *string        `gorm:"type:text" json:"description"`
+	Location       *string        `gorm:"type:text" json:"location"`
+	Categories     []string       `gorm:"type:jsonb;default:'[]';serializer:json" json:"categories"`
+	Status         *string        `gorm:"size:32" json:"status"`
+	SourceURL      *string        `gorm:"type:text" json:"source_url"`
+	SourceImageURL *string        `gorm:"type:text" json:"source_image_url"`
+	ImageVariants  ImageURLs      `gorm:"type:jsonb;serializer:json" json:"image_variants"`
+	ImageSizes     ImageSizes     `gorm:"type:jsonb;serializer:json" json:"image_sizes"`
+	ImagePHash     *string        `gorm:"size:16;index;column:image_phash" json:"image_phash"`
+	Reactions      map[string]int `gorm:"type:jsonb;default:'{}';serializer:json" json:"reactions"`
+	PostedAt       *time.Time     `json:"posted_at"`
+	CommentsCount  int            `gorm:"default:0" json:"comments_count"`
+	LikesCount     int            `gorm:"default:0" json:"likes_count"`
+	Food           *string        `gorm:"size:255" json:"food"`
+	Registration   bool           `gorm:"default:false" json:"registration"`
+	AddedAt        *time.Time     `json:"added_at"`
+	Price          *float64       `json:"price"`
+	School         *string        `gorm:"size:255" json:"school"`
+	ClubType       *string        `gorm:"size:50" json:"club_type"`
+	IGHandle       *string        `gorm:"size:100;column:ig_handle" json:"ig_handle"`
+	DiscordHandle  *string        `gorm:"size:100" json:"discord_handle"`
+	XHandle        *string        `gorm:"size:100;column:x_handle" json:"x_handle"`
+	TiktokHandle   *string        `gorm:"size:100" json:"tiktok_handle"`
+	FBHandle       *string        `gorm:"size:100;column:fb_handle" json:"fb_handle"`
+	OtherHandle    *string        `gorm:"size:100" json:"other_handle"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Associations
 	EventDates []EventDates `gorm:"foreignKey:EventID" json:"event_dates,omitempty"`
@@ -45,17 +55,23 @@ func (Events) TableName() string {
 	return "events"
 }
 
+// ImageURLs maps image variant names (thumbnail, card, full, webp) to URLs
+type ImageURLs map[string]string
+
+// ImageSizes maps image variant names to their size in bytes
+type ImageSizes map[string]int64
+
 // EventDates represents individual occurrence dates for events
 type EventDates struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	EventID     uint           `gorm:"index:idx_event_dtstart;not null" json:"event_id"`
-	DtstartUTC  time.Time      `gorm:"index:idx_dtstart_utc;index:idx_event_dtstart;not null" json:"dtstart_utc"`
-	DtendUTC    *time.Time     `gorm:"index:idx_dtend_utc" json:"dtend_utc"`
-	Duration    *time.Duration `json:"duration"`
-	TZ          *string        `gorm:"size:64" json:"tz"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	EventID    uint           `gorm:"index:idx_event_dtstart;not null" json:"event_id"`
+	DtstartUTC time.Time      `gorm:"index:idx_dtstart_utc;index:idx_event_dtstart;not null" json:"dtstart_utc"`
+	DtendUTC   *time.Time     `gorm:"index:idx_dtend_utc" json:"dtend_utc"`
+	Duration   *time.Duration `json:"duration"`
+	TZ         *string        `gorm:"size:64" json:"tz"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Associations
 	Event Events `gorm:"foreignKey:EventID" json:"-"`
@@ -66,17 +82,26 @@ func (EventDates) TableName() string {
 	return "event_dates"
 }
 
+// Submission statuses stored in EventSubmission.Status
+const (
+	SubmissionStatusPending  = "pending"
+	SubmissionStatusApproved = "approved"
+	SubmissionStatusRejected = "rejected"
+)
+
 // EventSubmission represents user-submitted events pending admin review
 type EventSubmission struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	SubmittedBy    string         `gorm:"size:255;not null" json:"submitted_by"`
-	SubmittedAt    time.Time      `gorm:"index;not null" json:"submitted_at"`
-	ReviewedAt     *time.Time     `json:"reviewed_at"`
-	ReviewedBy     *string        `gorm:"size:255" json:"reviewed_by"`
-	CreatedEventID uint           `gorm:"not null" json:"created_event_id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	SubmittedBy     string         `gorm:"size:255;index;not null" json:"submitted_by"`
+	SubmittedAt     time.Time      `gorm:"index;not null" json:"submitted_at"`
+	Status          string         `gorm:"size:16;index;not null;default:'pending'" json:"status"`
+	ReviewedAt      *time.Time     `json:"reviewed_at"`
+	ReviewedBy      *string        `gorm:"size:255" json:"reviewed_by"`
+	RejectionReason *string        `gorm:"type:text" json:"rejection_reason"`
+	CreatedEventID  uint           `gorm:"not null" json:"created_event_id"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Associations
 	CreatedEvent Events `gorm:"foreignKey:CreatedEventID" json:"created_event,omitempty"`
@@ -87,6 +112,21 @@ func (EventSubmission) TableName() string {
 	return "event_submissions"
 }
 
+// SubmissionAuditEntry records each moderation action taken on a submission
+type SubmissionAuditEntry struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	SubmissionID uint           `gorm:"index;not null" json:"submission_id"`
+	Action       string         `gorm:"size:32;not null" json:"action"` // submitted, edited, approved, rejected
+	Actor        string         `gorm:"size:255;not null" json:"actor"`
+	Details      map[string]any `gorm:"type:jsonb;serializer:json" json:"details"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (SubmissionAuditEntry) TableName() string {
+	return "event_submission_audits"
+}
+
 // EventInterest tracks user interest in events
 type EventInterest struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
@@ -105,6 +145,22 @@ func (EventInterest) TableName() string {
 	return "event_interests"
 }
 
+// EventReaction records one user's emoji reaction to an event; Events.Reactions
+// holds the per-emoji totals. Removed reactions are deleted outright so the
+// unique (event_id, user_id, emoji) slot is freed.
+type EventReaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	EventID   uint      `gorm:"not null;uniqueIndex:idx_event_user_emoji" json:"event_id"`
+	UserID    string    `gorm:"size:255;not null;uniqueIndex:idx_event_user_emoji;index" json:"user_id"`
+	Emoji     string    `gorm:"size:32;not null;uniqueIndex:idx_event_user_emoji" json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (EventReaction) TableName() string {
+	return "event_reactions"
+}
+
 // IgnoredPost represents Instagram posts that should be ignored
 type IgnoredPost struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
@@ -119,3 +175,69 @@ type IgnoredPost struct {
 func (IgnoredPost) TableName() string {
 	return "ignored_posts"
 }
+
+// UploadedImage records an image processed by services.ImageService, so an
+// event submitted with its URL as source_image_url picks up its variants
+type UploadedImage struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	URL        string     `gorm:"type:text;uniqueIndex;not null" json:"url"`
+	Variants   ImageURLs  `gorm:"type:jsonb;serializer:json;not null" json:"variants"`
+	Sizes      ImageSizes `gorm:"type:jsonb;serializer:json" json:"sizes"`
+	PHash      string     `gorm:"size:16;not null;column:phash" json:"phash"`
+	UploadedBy string     `gorm:"size:255;index" json:"uploaded_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (UploadedImage) TableName() string {
+	return "uploaded_images"
+}
+
+// Duplicate flag statuses stored in DuplicateFlag.Status
+const (
+	DuplicateStatusOpen      = "open"
+	DuplicateStatusDismissed = "dismissed"
+	DuplicateStatusMerged    = "merged"
+)
+
+// DuplicateFlag marks an event as a likely duplicate of an existing one,
+// for admins to merge or dismiss
+type DuplicateFlag struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	EventID       uint       `gorm:"not null;uniqueIndex:idx_duplicate_pair" json:"event_id"`
+	DuplicateOfID uint       `gorm:"not null;index;uniqueIndex:idx_duplicate_pair" json:"duplicate_of_id"`
+	Score         float64    `gorm:"not null" json:"score"`
+	Reasons       []string   `gorm:"type:jsonb;serializer:json" json:"reasons"`
+	Status        string     `gorm:"size:16;index;not null;default:'open'" json:"status"`
+	ResolvedBy    *string    `gorm:"size:255" json:"resolved_by"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Associations
+	Event       Events `gorm:"foreignKey:EventID" json:"event"`
+	DuplicateOf Events `gorm:"foreignKey:DuplicateOfID" json:"duplicate_of"`
+}
+
+// TableName specifies the table name for GORM
+func (DuplicateFlag) TableName() string {
+	return "event_duplicate_flags"
+}
+
+// CalendarFeedToken grants access to a user's private calendar subscription feed.
+// Revoking soft-deletes the row; rotating revokes it and issues a new token.
+// A user has at most one live (not deleted) token.
+type CalendarFeedToken struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	UserID     string         `gorm:"size:255;uniqueIndex:idx_calendar_feed_tokens_live_user_id,where:deleted_at IS NULL;not null" json:"user_id"`
+	Token      string         `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	LastUsedAt *time.Time     `json:"last_used_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
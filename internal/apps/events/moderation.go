@@ -0,0 +1,488 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultSubmissionPageSize = 20
+
+var (
+	errSubmissionNotFound   = errors.New("submission not found")
+	errSubmissionNotPending = errors.New("submission has already been reviewed")
+)
+
+// ListSubmissions handles GET /api/events/submissions - admin review queue
+// Requires: Admin authentication
+// Query params:
+//   - status: pending (default), approved, rejected or all
+//   - search: matches the event title
+//   - school, submitted_by: filters
+//   - cursor: pagination cursor (submission ID)
+//   - limit: number of results (default 20)
+func (h *Handler) ListSubmissions(c *gin.Context) {
+	query := h.DB.Model(&EventSubmission{}).
+		Joins("JOIN events ON events.id = event_submissions.created_event_id")
+
+	switch status := c.DefaultQuery("status", SubmissionStatusPending); status {
+	case "all":
+	case SubmissionStatusPending, SubmissionStatusApproved, SubmissionStatusRejected:
+		query = query.Where("event_submissions.status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		query = query.Where("events.title ILIKE ?", "%"+search+"%")
+	}
+	if school := c.Query("school"); school != "" {
+		query = query.Where("events.school = ?", school)
+	}
+	if submittedBy := c.Query("submitted_by"); submittedBy != "" {
+		query = query.Where("event_submissions.submitted_by = ?", submittedBy)
+	}
+
+	// Oldest first so the queue is worked in arrival order
+	h.listSubmissions(c, query, "event_submissions.id ASC", ">")
+}
+
+// GetMySubmissions handles GET /api/events/my-submissions - the caller's
+// submissions with their review status, newest first
+// Requires: JWT authentication
+func (h *Handler) GetMySubmissions(c *gin.Context) {
+	query := h.DB.Model(&EventSubmission{}).
+		Where("event_submissions.submitted_by = ?", c.GetString(core.ContextUserID))
+
+	h.listSubmissions(c, query, "event_submissions.id DESC", "<")
+}
+
+// listSubmissions pages through query by submission ID and writes the
+// standard paginated response
+func (h *Handler) listSubmissions(c *gin.Context, query *gorm.DB, order, cursorOp string) {
+	limit := defaultSubmissionPageSize
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(parsed, maxEventPageSize)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count submissions"})
+		return
+	}
+
+	page := query
+	if cursor := c.Query("cursor"); cursor != "" {
+		id, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		page = page.Where("event_submissions.id "+cursorOp+" ?", id)
+	}
+
+	var submissions []EventSubmission
+	err := page.Select("event_submissions.*").
+		Preload("CreatedEvent.EventDates", func(db *gorm.DB) *gorm.DB {
+			return db.Order("dtstart_utc ASC")
+		}).
+		Order(order).
+		Limit(limit + 1).
+		Find(&submissions).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
+		return
+	}
+
+	hasMore := len(submissions) > limit
+	if hasMore {
+		submissions = submissions[:limit]
+	}
+
+	var nextCursor *string
+	if hasMore {
+		token := strconv.FormatUint(uint64(submissions[len(submissions)-1].ID), 10)
+		nextCursor = &token
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":    submissions,
+		"nextCursor": nextCursor,
+		"hasMore":    hasMore,
+		"totalCount": totalCount,
+	})
+}
+
+// GetSubmission handles GET /api/events/submissions/:id - a submission with
+// its event, dates and audit trail
+// Requires: Admin authentication
+func (h *Handler) GetSubmission(c *gin.Context) {
+	var submission EventSubmission
+	err := h.DB.Preload("CreatedEvent.EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).First(&submission, c.Param("id")).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	var audit []SubmissionAuditEntry
+	if err := h.DB.Where("submission_id = ?", submission.ID).Order("id ASC").Find(&audit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit trail"})
+		return
+	}
+
+	var duplicates []DuplicateFlag
+	err = h.DB.Where("event_id = ? AND status = ?", submission.CreatedEventID, DuplicateStatusOpen).
+		Preload("DuplicateOf.EventDates").
+		Order("score DESC").
+		Find(&duplicates).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch duplicate flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission": submission,
+		"audit":      audit,
+		"duplicates": duplicates,
+	})
+}
+
+// UpdateSubmission handles PUT /api/events/submissions/:id - edit a pending
+// submission's event and dates before approval. Edits to what duplicates are
+// matched on (title, location, handle, dates, poster) re-check for them.
+// Requires: Admin authentication
+// Body: any subset of the SubmitEvent fields; occurrences, when given,
+// replace the event's dates
+func (h *Handler) UpdateSubmission(c *gin.Context) {
+	var body map[string]interface{}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	fields := make([]string, 0, len(body))
+	for field := range body {
+		fields = append(fields, field)
+	}
+
+	var validationErr error
+	submission, err := h.reviewSubmission(c, "edited", map[string]any{"fields": fields},
+		func(tx *gorm.DB, submission *EventSubmission, event *Events) error {
+			merged := eventData(*event)
+			for field, value := range body {
+				merged[field] = value
+			}
+
+			data, err := utils.ValidateEventData(merged)
+			if err != nil {
+				validationErr = err
+				return err
+			}
+
+			applyEventData(event, data)
+			if err := attachUploadedImage(tx, event); err != nil {
+				return err
+			}
+			if err := tx.Omit(clause.Associations).Save(event).Error; err != nil {
+				return err
+			}
+			if _, ok := body["occurrences"]; ok {
+				if err := replaceEventDates(tx, event.ID, data["occurrences"].([]utils.EventOccurrence)); err != nil {
+					return err
+				}
+			}
+			for _, field := range duplicateScoredFields {
+				if _, ok := body[field]; ok {
+					return reflagDuplicates(tx, event)
+				}
+			}
+			return nil
+		})
+	if validationErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
+		return
+	}
+	if err != nil {
+		h.writeReviewError(c, err)
+		return
+	}
+
+	publishSubmission(h.Realtime, "submission.updated", submission)
+	c.JSON(http.StatusOK, gin.H{"submission": submission})
+}
+
+// ApproveSubmission handles POST /api/events/submissions/:id/approve -
+// publish a pending submission's event
+// Requires: Admin authentication
+func (h *Handler) ApproveSubmission(c *gin.Context) {
+	submission, err := h.reviewSubmission(c, "approved", nil,
+		func(tx *gorm.DB, submission *EventSubmission, event *Events) error {
+			now := time.Now()
+			submission.Status = SubmissionStatusApproved
+			updates := map[string]interface{}{"status": EventStatusConfirmed}
+			if event.AddedAt == nil {
+				updates["added_at"] = now
+			}
+			return tx.Model(event).Updates(updates).Error
+		})
+	if err != nil {
+		h.writeReviewError(c, err)
+		return
+	}
+
+	publishSubmission(h.Realtime, "submission.approved", submission)
+	publishEvent(h.Realtime, "event.created", submission.CreatedEvent)
+	c.JSON(http.StatusOK, gin.H{"submission": submission})
+}
+
+// RejectSubmission handles POST /api/events/submissions/:id/reject - reject
+// a pending submission
+// Requires: Admin authentication
+// Body: { "reason": "Duplicate of an existing event" }
+func (h *Handler) RejectSubmission(c *gin.Context) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || strings.TrimSpace(body.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	reason := strings.TrimSpace(body.Reason)
+
+	submission, err := h.reviewSubmission(c, "rejected", map[string]any{"reason": reason},
+		func(tx *gorm.DB, submission *EventSubmission, event *Events) error {
+			submission.Status = SubmissionStatusRejected
+			submission.RejectionReason = &reason
+			return tx.Model(event).Update("status", EventStatusRejected).Error
+		})
+	if err != nil {
+		h.writeReviewError(c, err)
+		return
+	}
+
+	publishSubmission(h.Realtime, "submission.rejected", submission)
+	c.JSON(http.StatusOK, gin.H{"submission": submission})
+}
+
+// reviewSubmission runs a moderation action in a transaction: it locks the
+// pending submission, applies change, stamps the reviewer for approve/reject
+// and appends an audit entry
+func (h *Handler) reviewSubmission(c *gin.Context, action string, details map[string]any,
+	change func(tx *gorm.DB, submission *EventSubmission, event *Events) error) (*EventSubmission, error) {
+	actor := c.GetString(core.ContextUserID)
+
+	var submission EventSubmission
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&submission, c.Param("id")).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errSubmissionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if submission.Status != SubmissionStatusPending {
+			return errSubmissionNotPending
+		}
+
+		var event Events
+		if err := tx.First(&event, submission.CreatedEventID).Error; err != nil {
+			return err
+		}
+
+		if err := change(tx, &submission, &event); err != nil {
+			return err
+		}
+
+		if action != "edited" {
+			now := time.Now()
+			submission.ReviewedAt = &now
+			submission.ReviewedBy = &actor
+		}
+		if err := tx.Omit(clause.Associations).Save(&submission).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, submission.ID, action, actor, details)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.DB.Preload("CreatedEvent.EventDates", func(db *gorm.DB) *gorm.DB {
+		return db.Order("dtstart_utc ASC")
+	}).First(&submission, submission.ID).Error
+	return &submission, err
+}
+
+// writeReviewError maps reviewSubmission errors to responses
+func (h *Handler) writeReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errSubmissionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+	case errors.Is(err, errSubmissionNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update submission"})
+	}
+}
+
+// recordAudit appends a moderation audit entry
+func recordAudit(tx *gorm.DB, submissionID uint, action, actor string, details map[string]any) error {
+	return tx.Create(&SubmissionAuditEntry{
+		SubmissionID: submissionID,
+		Action:       action,
+		Actor:        actor,
+		Details:      details,
+	}).Error
+}
+
+// applyEventData copies data cleaned by utils.ValidateEventData onto event.
+// Optional fields missing from data are cleared.
+func applyEventData(event *Events, data map[string]interface{}) {
+	text := func(field string) *string {
+		if value, ok := data[field].(string); ok {
+			return &value
+		}
+		return nil
+	}
+
+	event.Title = text("title")
+	event.Location = text("location")
+	event.Description = text("description")
+	event.Food = text("food")
+	event.School = text("school")
+	event.ClubType = text("club_type")
+	event.IGHandle = text("ig_handle")
+	event.DiscordHandle = text("discord_handle")
+	event.XHandle = text("x_handle")
+	event.TiktokHandle = text("tiktok_handle")
+	event.FBHandle = text("fb_handle")
+	event.OtherHandle = text("other_handle")
+	event.SourceURL = text("source_url")
+	event.SourceImageURL = text("source_image_url")
+
+	event.Price = nil
+	if price, ok := data["price"].(float64); ok {
+		event.Price = &price
+	}
+
+	event.Registration, _ = data["registration"].(bool)
+
+	event.Categories = []string{}
+	if categories, ok := data["categories"].([]string); ok {
+		event.Categories = categories
+	}
+}
+
+// attachUploadedImage copies the variants and perceptual hash of the event's
+// source image onto it, when that image was processed by us
+func attachUploadedImage(tx *gorm.DB, event *Events) error {
+	event.ImageVariants, event.ImageSizes, event.ImagePHash = nil, nil, nil
+	if event.SourceImageURL == nil {
+		return nil
+	}
+
+	var image UploadedImage
+	if err := tx.Where("url = ?", *event.SourceImageURL).Limit(1).Find(&image).Error; err != nil || image.ID == 0 {
+		return err
+	}
+	event.ImageVariants = image.Variants
+	event.ImageSizes = image.Sizes
+	event.ImagePHash = &image.PHash
+	return nil
+}
+
+// eventData is the inverse of applyEventData: it renders an event (with its
+// dates loaded or not) in the shape SubmitEvent accepts, so partial edits
+// can be merged over it and revalidated
+func eventData(event Events) map[string]interface{} {
+	data := map[string]interface{}{
+		"registration": event.Registration,
+	}
+
+	for field, value := range map[string]*string{
+		"title": event.Title, "location": event.Location, "description": event.Description,
+		"food": event.Food, "school": event.School, "club_type": event.ClubType,
+		"ig_handle": event.IGHandle, "discord_handle": event.DiscordHandle, "x_handle": event.XHandle,
+		"tiktok_handle": event.TiktokHandle, "fb_handle": event.FBHandle, "other_handle": event.OtherHandle,
+		"source_url": event.SourceURL, "source_image_url": event.SourceImageURL,
+	} {
+		if value != nil {
+			data[field] = *value
+		}
+	}
+
+	if event.Price != nil {
+		data["price"] = *event.Price
+	}
+
+	categories := make([]interface{}, len(event.Categories))
+	for i, category := range event.Categories {
+		categories[i] = category
+	}
+	data["categories"] = categories
+
+	// Occurrences are validated on every edit; a placeholder keeps validation
+	// passing when the edit doesn't touch them (the stored dates are kept)
+	occurrences := []interface{}{map[string]interface{}{"dtstart_utc": utils.FormatUTCDateTime(time.Now())}}
+	if len(event.EventDates) > 0 {
+		occurrences = make([]interface{}, len(event.EventDates))
+		for i, date := range event.EventDates {
+			occurrence := map[string]interface{}{"dtstart_utc": utils.FormatUTCDateTime(date.DtstartUTC)}
+			if date.DtendUTC != nil {
+				occurrence["dtend_utc"] = utils.FormatUTCDateTime(*date.DtendUTC)
+			}
+			if date.TZ != nil {
+				occurrence["tz"] = *date.TZ
+			}
+			occurrences[i] = occurrence
+		}
+	}
+	data["occurrences"] = occurrences
+
+	return data
+}
+
+// newEventDates builds EventDates rows from validated occurrences
+func newEventDates(eventID uint, occurrences []utils.EventOccurrence) []EventDates {
+	dates := make([]EventDates, len(occurrences))
+	for i, occurrence := range occurrences {
+		dates[i] = EventDates{
+			EventID:    eventID,
+			DtstartUTC: occurrence.DtstartUTC,
+			DtendUTC:   occurrence.DtendUTC,
+		}
+		if occurrence.TZ != "" {
+			tz := occurrence.TZ
+			dates[i].TZ = &tz
+		}
+	}
+	return dates
+}
+
+// replaceEventDates swaps an event's dates for occurrences
+func replaceEventDates(tx *gorm.DB, eventID uint, occurrences []utils.EventOccurrence) error {
+	if err := tx.Where("event_id = ?", eventID).Delete(&EventDates{}).Error; err != nil {
+		return err
+	}
+	dates := newEventDates(eventID, occurrences)
+	return tx.Create(&dates).Error
+}
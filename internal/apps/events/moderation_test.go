@@ -0,0 +1,246 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// createSubmission stores a pending submission of a pending event titled
+// title, at location, starting at start
+func createSubmission(t *testing.T, db *gorm.DB, title, location string, start time.Time) *EventSubmission {
+	t.Helper()
+	event := createEvent(t, db, title, EventStatusPending, nil, start)
+	if err := db.Model(event).Update("location", location).Error; err != nil {
+		t.Fatal(err)
+	}
+	submission := &EventSubmission{
+		SubmittedBy:    "user_1",
+		SubmittedAt:    time.Now(),
+		Status:         SubmissionStatusPending,
+		CreatedEventID: event.ID,
+	}
+	if err := db.Omit("CreatedEvent").Create(submission).Error; err != nil {
+		t.Fatal(err)
+	}
+	return submission
+}
+
+// moderate calls a moderation handler on submission id as admin_1
+func moderate(handler gin.HandlerFunc, id uint, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/events/submissions/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(id), 10)}}
+	c.Set(core.ContextUserID, "admin_1")
+	handler(c)
+	return w
+}
+
+func auditTrail(t *testing.T, db *gorm.DB, submissionID uint) []SubmissionAuditEntry {
+	t.Helper()
+	var audit []SubmissionAuditEntry
+	if err := db.Where("submission_id = ?", submissionID).Order("id ASC").Find(&audit).Error; err != nil {
+		t.Fatal(err)
+	}
+	return audit
+}
+
+func reloadSubmission(t *testing.T, db *gorm.DB, id uint) EventSubmission {
+	t.Helper()
+	var submission EventSubmission
+	if err := db.Preload("CreatedEvent").First(&submission, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return submission
+}
+
+func TestApproveSubmission(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	submission := createSubmission(t, db, "Demo Night", "E7", time.Now().Add(72*time.Hour))
+
+	if w := moderate(h.ApproveSubmission, submission.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("approve = %d %s", w.Code, w.Body)
+	}
+	got := reloadSubmission(t, db, submission.ID)
+	if got.Status != SubmissionStatusApproved || got.ReviewedAt == nil || got.ReviewedBy == nil || *got.ReviewedBy != "admin_1" {
+		t.Errorf("submission = %+v, want approved by admin_1", got)
+	}
+	if event := got.CreatedEvent; *event.Status != EventStatusConfirmed || event.AddedAt == nil {
+		t.Errorf("event status = %s, added_at = %v; want confirmed and published", *event.Status, event.AddedAt)
+	}
+
+	audit := auditTrail(t, db, submission.ID)
+	if len(audit) != 1 || audit[0].Action != "approved" || audit[0].Actor != "admin_1" {
+		t.Errorf("audit = %+v, want one approval by admin_1", audit)
+	}
+}
+
+func TestRejectSubmission(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	submission := createSubmission(t, db, "Demo Night", "E7", time.Now().Add(72*time.Hour))
+
+	for _, body := range []string{"", `{}`, `{"reason": "  "}`} {
+		if w := moderate(h.RejectSubmission, submission.ID, body); w.Code != http.StatusBadRequest {
+			t.Errorf("reject with %q = %d, want 400", body, w.Code)
+		}
+	}
+	if w := moderate(h.RejectSubmission, submission.ID, `{"reason": " Not a campus event "}`); w.Code != http.StatusOK {
+		t.Fatalf("reject = %d %s", w.Code, w.Body)
+	}
+
+	got := reloadSubmission(t, db, submission.ID)
+	if got.Status != SubmissionStatusRejected || got.RejectionReason == nil || *got.RejectionReason != "Not a campus event" {
+		t.Errorf("submission = %+v, want rejected with the trimmed reason", got)
+	}
+	if *got.CreatedEvent.Status != EventStatusRejected {
+		t.Errorf("event status = %s, want rejected", *got.CreatedEvent.Status)
+	}
+	audit := auditTrail(t, db, submission.ID)
+	if len(audit) != 1 || audit[0].Action != "rejected" || audit[0].Details["reason"] != "Not a campus event" {
+		t.Errorf("audit = %+v, want the rejection and its reason", audit)
+	}
+}
+
+func TestReviewedSubmissionsAreFinal(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	approved := createSubmission(t, db, "Demo Night", "E7", time.Now().Add(72*time.Hour))
+	rejected := createSubmission(t, db, "Bake Sale", "SLC", time.Now().Add(72*time.Hour))
+	moderate(h.ApproveSubmission, approved.ID, "")
+	moderate(h.RejectSubmission, rejected.ID, `{"reason": "Spam"}`)
+
+	for _, tt := range []struct {
+		name    string
+		handler gin.HandlerFunc
+		body    string
+	}{
+		{"approve", h.ApproveSubmission, ""},
+		{"reject", h.RejectSubmission, `{"reason": "Changed my mind"}`},
+		{"edit", h.UpdateSubmission, `{"title": "Renamed"}`},
+	} {
+		for _, submission := range []*EventSubmission{approved, rejected} {
+			if w := moderate(tt.handler, submission.ID, tt.body); w.Code != http.StatusConflict {
+				t.Errorf("%s submission %d = %d, want 409", tt.name, submission.ID, w.Code)
+			}
+			if audit := auditTrail(t, db, submission.ID); len(audit) != 1 {
+				t.Errorf("%s submission %d audited %d actions, want only the first", tt.name, submission.ID, len(audit))
+			}
+		}
+	}
+
+	if got := reloadSubmission(t, db, rejected.ID); got.Status != SubmissionStatusRejected || *got.CreatedEvent.Status != EventStatusRejected {
+		t.Errorf("rejected submission became %s / %s", got.Status, *got.CreatedEvent.Status)
+	}
+	if w := moderate(h.ApproveSubmission, 999, ""); w.Code != http.StatusNotFound {
+		t.Errorf("approve missing submission = %d, want 404", w.Code)
+	}
+}
+
+func TestUpdateSubmission(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	submission := createSubmission(t, db, "Demo Night", "E7", start)
+
+	if w := moderate(h.UpdateSubmission, submission.ID, `{"title": "  Robotics Demo Night ", "price": 5}`); w.Code != http.StatusOK {
+		t.Fatalf("edit = %d %s", w.Code, w.Body)
+	}
+	got := reloadSubmission(t, db, submission.ID)
+	if event := got.CreatedEvent; *event.Title != "Robotics Demo Night" || *event.Location != "E7" || event.Price == nil || *event.Price != 5 {
+		t.Errorf("event = %+v, want the edit merged over the submission", event)
+	}
+	if got.Status != SubmissionStatusPending || got.ReviewedAt != nil {
+		t.Errorf("submission = %+v, want still pending and unreviewed", got)
+	}
+	var dates []EventDates
+	db.Where("event_id = ?", submission.CreatedEventID).Find(&dates)
+	if len(dates) != 1 || !dates[0].DtstartUTC.Equal(start) {
+		t.Errorf("dates = %+v, want the untouched occurrence", dates)
+	}
+
+	// Invalid edits change nothing and aren't audited
+	if w := moderate(h.UpdateSubmission, submission.ID, `{"title": ""}`); w.Code != http.StatusBadRequest {
+		t.Errorf("edit with no title = %d, want 400", w.Code)
+	}
+	if title := *reloadSubmission(t, db, submission.ID).CreatedEvent.Title; title != "Robotics Demo Night" {
+		t.Errorf("title after invalid edit = %q", title)
+	}
+
+	audit := auditTrail(t, db, submission.ID)
+	if len(audit) != 1 || audit[0].Action != "edited" || audit[0].Actor != "admin_1" {
+		t.Fatalf("audit = %+v, want one edit by admin_1", audit)
+	}
+	fields, _ := audit[0].Details["fields"].([]any)
+	if len(fields) != 2 {
+		t.Errorf("audited fields = %v, want title and price", audit[0].Details["fields"])
+	}
+}
+
+func TestUpdateSubmissionReflagsDuplicates(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	start := time.Now().Add(72 * time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+	existing := createEvent(t, db, "Robotics Demo Night", EventStatusConfirmed, &past, start)
+	db.Model(existing).Update("location", "E7")
+
+	submission := createSubmission(t, db, "Bake Sale", "SLC", start.Add(7*24*time.Hour))
+	openFlags := func() []DuplicateFlag {
+		var flags []DuplicateFlag
+		db.Where("event_id = ? AND status = ?", submission.CreatedEventID, DuplicateStatusOpen).Find(&flags)
+		return flags
+	}
+
+	// Non-scored edits don't look for duplicates
+	moderate(h.UpdateSubmission, submission.ID, `{"food": "Cookies"}`)
+	if flags := openFlags(); len(flags) != 0 {
+		t.Fatalf("flags = %+v before any scored edit", flags)
+	}
+
+	// Editing it into a copy of the existing event flags it
+	edit, _ := json.Marshal(map[string]any{
+		"title":       "Robotics Demo Night",
+		"location":    "E7",
+		"occurrences": []map[string]string{{"dtstart_utc": utils.FormatUTCDateTime(start)}},
+	})
+	if w := moderate(h.UpdateSubmission, submission.ID, string(edit)); w.Code != http.StatusOK {
+		t.Fatalf("edit = %d %s", w.Code, w.Body)
+	}
+	flags := openFlags()
+	if len(flags) != 1 || flags[0].DuplicateOfID != existing.ID {
+		t.Fatalf("flags = %+v, want one against event %d", flags, existing.ID)
+	}
+
+	// Moving it away clears the stale flag
+	edit, _ = json.Marshal(map[string]any{
+		"occurrences": []map[string]string{{"dtstart_utc": utils.FormatUTCDateTime(start.Add(7 * 24 * time.Hour))}},
+	})
+	moderate(h.UpdateSubmission, submission.ID, string(edit))
+	if flags := openFlags(); len(flags) != 0 {
+		t.Errorf("flags = %+v after moving the event a week", flags)
+	}
+
+	// A dismissed flag stays dismissed when the match comes back
+	edit, _ = json.Marshal(map[string]any{
+		"occurrences": []map[string]string{{"dtstart_utc": utils.FormatUTCDateTime(start)}},
+	})
+	moderate(h.UpdateSubmission, submission.ID, string(edit))
+	db.Model(&DuplicateFlag{}).Where("event_id = ?", submission.CreatedEventID).Update("status", DuplicateStatusDismissed)
+	moderate(h.UpdateSubmission, submission.ID, `{"title": "Robotics Demo Night!"}`)
+	if flags := openFlags(); len(flags) != 0 {
+		t.Errorf("flags = %+v, want the dismissed match not raised again", flags)
+	}
+}
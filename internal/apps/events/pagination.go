@@ -0,0 +1,95 @@
+package events
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortTime scans a timestamp computed in SQL, such as MIN(dtstart_utc).
+// Drivers that can't type an aggregate, like SQLite's, return it as text.
+type sortTime struct {
+	time.Time
+}
+
+// sortTimeLayouts are the text forms sortTime accepts
+var sortTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+// Scan implements sql.Scanner
+func (t *sortTime) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a sort time", value)
+	}
+	for _, layout := range sortTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse sort time %q", text)
+}
+
+// Value implements driver.Valuer
+func (t sortTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+// eventCursor marks a position in the event listing.
+// The listing is keyset-paginated on (earliest_dtstart, id), and AsOf pins the
+// reference time used on the first page so later pages resolve upcoming/live
+// occurrences and totalCount against the same snapshot.
+type eventCursor struct {
+	EarliestDtstart time.Time
+	ID              uint
+	AsOf            time.Time
+}
+
+// encode serialises the cursor into an opaque URL-safe token
+func (c eventCursor) encode() string {
+	raw := fmt.Sprintf("%d|%d|%d", c.EarliestDtstart.UnixNano(), c.ID, c.AsOf.UnixNano())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeEventCursor parses a token produced by eventCursor.encode
+func decodeEventCursor(token string) (eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return eventCursor{}, errors.New("invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return eventCursor{}, errors.New("invalid cursor")
+	}
+
+	start, err1 := strconv.ParseInt(parts[0], 10, 64)
+	id, err2 := strconv.ParseUint(parts[1], 10, 64)
+	asOf, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return eventCursor{}, errors.New("invalid cursor")
+	}
+
+	return eventCursor{
+		EarliestDtstart: time.Unix(0, start).UTC(),
+		ID:              uint(id),
+		AsOf:            time.Unix(0, asOf).UTC(),
+	}, nil
+}
@@ -0,0 +1,223 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AllowedReactions is the emoji set users can react with
+var AllowedReactions = []string{"👍", "❤️", "🔥", "🎉", "😂", "😮", "👀"}
+
+// reactionsByKey maps an emoji without variation selectors to its canonical
+// form, so "❤" and "❤️" count as the same reaction
+var reactionsByKey = func() map[string]string {
+	keys := make(map[string]string, len(AllowedReactions))
+	for _, emoji := range AllowedReactions {
+		keys[reactionKey(emoji)] = emoji
+	}
+	return keys
+}()
+
+func reactionKey(emoji string) string {
+	return strings.ReplaceAll(strings.TrimSpace(emoji), "\ufe0f", "")
+}
+
+// errNotReactable is returned for missing or unpublished events
+var errNotReactable = errors.New("event not found")
+
+// GetEventReactions handles GET /api/events/:id/reactions - reaction totals,
+// plus the caller's own reactions when authenticated
+func (h *Handler) GetEventReactions(c *gin.Context) {
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+
+	var event Events
+	err = h.DB.Select("id", "reactions").
+		Where("id = ? AND status = ?", eventID, EventStatusConfirmed).
+		Take(&event).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	h.writeReactions(c, &event)
+}
+
+// AddEventReaction handles POST /api/events/:id/reactions - react to an event.
+// Reacting twice with the same emoji is a no-op.
+// Requires: JWT authentication
+// Body: {"emoji": "🔥"}
+func (h *Handler) AddEventReaction(c *gin.Context) {
+	var body struct {
+		Emoji string `json:"emoji"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "emoji is required"})
+		return
+	}
+	h.setReaction(c, body.Emoji, true)
+}
+
+// RemoveEventReaction handles DELETE /api/events/:id/reactions/:emoji - remove
+// the caller's reaction. Removing a missing reaction is a no-op.
+// Requires: JWT authentication
+func (h *Handler) RemoveEventReaction(c *gin.Context) {
+	h.setReaction(c, c.Param("emoji"), false)
+}
+
+// setReaction adds or removes the caller's reaction and adjusts the event's
+// totals in the same transaction. The event row is locked first, so
+// concurrent reactions on an event queue up instead of overwriting each
+// other's totals.
+func (h *Handler) setReaction(c *gin.Context, emoji string, add bool) {
+	userID := c.GetString(core.ContextUserID)
+	eventID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event id"})
+		return
+	}
+	emoji, ok := reactionsByKey[reactionKey(emoji)]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported reaction", "allowed": AllowedReactions})
+		return
+	}
+
+	var event Events
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "reactions").
+			Where("id = ? AND status = ?", eventID, EventStatusConfirmed).
+			Take(&event).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errNotReactable
+		}
+		if err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		if add {
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&EventReaction{EventID: event.ID, UserID: userID, Emoji: emoji})
+		} else {
+			result = tx.Where("event_id = ? AND user_id = ? AND emoji = ?", event.ID, userID, emoji).
+				Delete(&EventReaction{})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Only touch the totals when the caller's reaction actually changed
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		delta := 1
+		if !add {
+			delta = -1
+		}
+		adjustReactionCount(&event, emoji, delta)
+		return tx.Model(&event).Select("reactions").Updates(&Events{Reactions: event.Reactions}).Error
+	})
+	if errors.Is(err, errNotReactable) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	publish(h.Realtime, "event.reactions", gin.H{"event_id": event.ID, "reactions": reactionTotals(event)},
+		realtime.EventTopic(event.ID))
+	h.writeReactions(c, &event)
+}
+
+// adjustReactionCount adds delta to the event's total for emoji, dropping
+// the key when it reaches zero
+func adjustReactionCount(event *Events, emoji string, delta int) {
+	if event.Reactions == nil {
+		event.Reactions = map[string]int{}
+	}
+	event.Reactions[emoji] += delta
+	if event.Reactions[emoji] <= 0 {
+		delete(event.Reactions, emoji)
+	}
+}
+
+// writeReactions responds with the event's totals and the caller's reactions
+func (h *Handler) writeReactions(c *gin.Context, event *Events) {
+	mine := []string{}
+	if userID := c.GetString(core.ContextUserID); userID != "" {
+		err := h.DB.Model(&EventReaction{}).
+			Where("event_id = ? AND user_id = ?", event.ID, userID).
+			Order("id ASC").
+			Pluck("emoji", &mine).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event_id":     event.ID,
+		"reactions":    reactionTotals(*event),
+		"my_reactions": mine,
+		"allowed":      AllowedReactions,
+	})
+}
+
+func reactionTotals(event Events) map[string]int {
+	if event.Reactions == nil {
+		return map[string]int{}
+	}
+	return event.Reactions
+}
+
+// mergeReactions moves the loser's per-user reactions to the winner. A user
+// who reacted to both with the same emoji keeps one reaction, and those
+// overlaps are subtracted from the winner's summed totals.
+func mergeReactions(tx *gorm.DB, winner, loser *Events) error {
+	var overlaps []struct {
+		Emoji string
+		Count int
+	}
+	err := tx.Raw(`SELECT l.emoji, COUNT(*) AS count FROM event_reactions l
+		WHERE l.event_id = ? AND EXISTS (
+			SELECT 1 FROM event_reactions w WHERE w.event_id = ? AND w.user_id = l.user_id AND w.emoji = l.emoji)
+		GROUP BY l.emoji`, loser.ID, winner.ID).Scan(&overlaps).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Where("event_id = ? AND (user_id, emoji) IN (?)", loser.ID,
+		tx.Model(&EventReaction{}).Select("user_id, emoji").Where("event_id = ?", winner.ID)).
+		Delete(&EventReaction{}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(&EventReaction{}).Where("event_id = ?", loser.ID).Update("event_id", winner.ID).Error; err != nil {
+		return err
+	}
+
+	for _, overlap := range overlaps {
+		if winner.Reactions == nil {
+			break
+		}
+		winner.Reactions[overlap.Emoji] -= overlap.Count
+		if winner.Reactions[overlap.Emoji] <= 0 {
+			delete(winner.Reactions, overlap.Emoji)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reactionsResponse is a reactions endpoint's body
+type reactionsResponse struct {
+	EventID     uint           `json:"event_id"`
+	Reactions   map[string]int `json:"reactions"`
+	MyReactions []string       `json:"my_reactions"`
+}
+
+// react adds (or, with remove, removes) userID's emoji reaction to event id
+func react(t *testing.T, h *Handler, id, userID, emoji string, remove bool) (int, reactionsResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	if remove {
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/events/"+id+"/reactions/"+url.PathEscape(emoji), nil)
+		c.Params = append(c.Params, gin.Param{Key: "emoji", Value: emoji})
+	} else {
+		body, _ := json.Marshal(map[string]string{"emoji": emoji})
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/events/"+id+"/reactions", strings.NewReader(string(body)))
+		c.Request.Header.Set("Content-Type", "application/json")
+	}
+	c.Set(core.ContextUserID, userID)
+	if remove {
+		h.RemoveEventReaction(c)
+	} else {
+		h.AddEventReaction(c)
+	}
+
+	var response reactionsResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, response
+}
+
+// reactionRows counts the stored reaction rows for an event by emoji
+func reactionRows(t *testing.T, db *gorm.DB, eventID uint) map[string]int {
+	t.Helper()
+	var reactions []EventReaction
+	if err := db.Where("event_id = ?", eventID).Find(&reactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	rows := map[string]int{}
+	for _, reaction := range reactions {
+		rows[reaction.Emoji]++
+	}
+	return rows
+}
+
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for emoji, count := range a {
+		if b[emoji] != count {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEventReactionsAreIdempotent(t *testing.T) {
+	db := openEventsDB(t)
+	publisher := &recordingPublisher{}
+	h := NewHandler(db, Options{Realtime: publisher})
+	past := time.Now().Add(-time.Hour)
+	event := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	id := idParam(event.ID)
+
+	steps := []struct {
+		name   string
+		user   string
+		emoji  string
+		remove bool
+		totals map[string]int
+		mine   string
+	}{
+		{"react", "user_1", "🔥", false, map[string]int{"🔥": 1}, "🔥"},
+		{"react again", "user_1", "🔥", false, map[string]int{"🔥": 1}, "🔥"},
+		{"second emoji", "user_1", "❤️", false, map[string]int{"🔥": 1, "❤️": 1}, "🔥 ❤️"},
+		{"same emoji without the variation selector", "user_1", "❤", false, map[string]int{"🔥": 1, "❤️": 1}, "🔥 ❤️"},
+		{"another user", "user_2", "🔥", false, map[string]int{"🔥": 2, "❤️": 1}, "🔥"},
+		{"remove", "user_1", "🔥", true, map[string]int{"🔥": 1, "❤️": 1}, "❤️"},
+		{"remove again", "user_1", "🔥", true, map[string]int{"🔥": 1, "❤️": 1}, "❤️"},
+		{"remove never added", "user_3", "🎉", true, map[string]int{"🔥": 1, "❤️": 1}, ""},
+		{"remove the last", "user_1", " ❤ ", true, map[string]int{"🔥": 1}, ""},
+	}
+	for _, step := range steps {
+		code, response := react(t, h, id, step.user, step.emoji, step.remove)
+		if code != http.StatusOK {
+			t.Fatalf("%s = %d", step.name, code)
+		}
+		if !sameCounts(response.Reactions, step.totals) || strings.Join(response.MyReactions, " ") != step.mine {
+			t.Errorf("%s = %v, mine %q; want %v, mine %q", step.name, response.Reactions, response.MyReactions, step.totals, step.mine)
+		}
+
+		// The stored totals always match the rows behind them
+		stored := mustEvent(t, db, event.ID).Reactions
+		if rows := reactionRows(t, db, event.ID); !sameCounts(stored, rows) {
+			t.Errorf("%s: totals %v, but rows %v", step.name, stored, rows)
+		}
+	}
+
+	// Only changes are pushed live
+	if len(publisher.messages) != 9 {
+		t.Errorf("published %d messages for 9 requests", len(publisher.messages))
+	}
+	if want := "event.reactions " + realtime.EventTopic(event.ID); publisher.messages[0] != want {
+		t.Errorf("published %q, want %q", publisher.messages[0], want)
+	}
+}
+
+func TestEventReactionsCountConcurrentUsers(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	past := time.Now().Add(-time.Hour)
+	event := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	id := idParam(event.ID)
+
+	const users = 8
+	var wg sync.WaitGroup
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			// SQLite allows one writer at a time; retry until this one gets in
+			for attempt := 0; attempt < 50; attempt++ {
+				if code, _ := react(t, h, id, user, "🎉", false); code == http.StatusOK {
+					return
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			t.Errorf("%s never reacted", user)
+		}("user_" + string(rune('a'+i)))
+	}
+	wg.Wait()
+
+	stored := mustEvent(t, db, event.ID).Reactions
+	if stored["🎉"] != users || !sameCounts(stored, reactionRows(t, db, event.ID)) {
+		t.Errorf("totals = %v after %d users reacted", stored, users)
+	}
+}
+
+func TestEventReactionsRejectBadRequests(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	past := time.Now().Add(-time.Hour)
+	event := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	pending := createEvent(t, db, "Draft", EventStatusPending, nil, time.Now().Add(24*time.Hour))
+
+	for _, tt := range []struct {
+		name, id, emoji string
+		code            int
+	}{
+		{"unsupported emoji", idParam(event.ID), "💩", http.StatusBadRequest},
+		{"text", idParam(event.ID), "fire", http.StatusBadRequest},
+		{"no emoji", idParam(event.ID), "", http.StatusBadRequest},
+		{"invalid id", "abc", "🔥", http.StatusBadRequest},
+		{"missing event", "999", "🔥", http.StatusNotFound},
+		{"unpublished event", idParam(pending.ID), "🔥", http.StatusNotFound},
+	} {
+		if code, _ := react(t, h, tt.id, "user_1", tt.emoji, false); code != tt.code {
+			t.Errorf("%s = %d, want %d", tt.name, code, tt.code)
+		}
+	}
+	var count int64
+	db.Model(&EventReaction{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d rejected reactions", count)
+	}
+}
+
+func TestGetEventReactions(t *testing.T) {
+	db := openEventsDB(t)
+	h := NewHandler(db, Options{})
+	past := time.Now().Add(-time.Hour)
+	event := createEvent(t, db, "Games Night", EventStatusConfirmed, &past, time.Now().Add(24*time.Hour))
+	react(t, h, idParam(event.ID), "user_1", "👀", false)
+	react(t, h, idParam(event.ID), "user_2", "👀", false)
+
+	for _, user := range []string{"", "user_1"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/events/"+idParam(event.ID)+"/reactions", nil)
+		c.Params = gin.Params{{Key: "id", Value: idParam(event.ID)}}
+		if user != "" {
+			c.Set(core.ContextUserID, user)
+		}
+		h.GetEventReactions(c)
+
+		var response reactionsResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		wantMine := map[string]int{"": 0, "user_1": 1}[user]
+		if w.Code != http.StatusOK || response.Reactions["👀"] != 2 || len(response.MyReactions) != wantMine || response.MyReactions == nil {
+			t.Errorf("as %q: %d %s", user, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// publish pushes a realtime update, if a publisher is configured
+func publish(p realtime.Publisher, msgType string, data interface{}, topics ...string) {
+	if p != nil {
+		p.Publish(msgType, data, topics...)
+	}
+}
+
+// publishEvent announces a change to a published event on the all-events,
+// event, club and category topics. event's dates must be loaded, sorted by
+// start.
+func publishEvent(p realtime.Publisher, msgType string, event Events) {
+	topics := []string{realtime.TopicEvents, realtime.EventTopic(event.ID)}
+	if event.IGHandle != nil {
+		if handle := utils.NormalizeHandle(*event.IGHandle); handle != "" {
+			topics = append(topics, realtime.ClubTopic(handle))
+		}
+	}
+	for _, category := range event.Categories {
+		topics = append(topics, realtime.CategoryTopic(category))
+	}
+
+	publish(p, msgType, newEventListItem(event, nil, time.Now().UTC()), topics...)
+}
+
+// publishSubmission announces a change to the moderation queue
+func publishSubmission(p realtime.Publisher, msgType string, submission *EventSubmission) {
+	publish(p, msgType, gin.H{
+		"submission_id": submission.ID,
+		"event_id":      submission.CreatedEventID,
+		"status":        submission.Status,
+		"title":         submission.CreatedEvent.Title,
+	}, realtime.TopicModeration)
+}
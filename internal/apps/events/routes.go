@@ -1,31 +1,77 @@
 package events
 
 import (
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// Rate limits for the expensive or abusable event routes
+var (
+	extractRateLimit  = middleware.Policy{Name: "events-extract", Limit: 10, Window: time.Hour}
+	submitRateLimit   = middleware.Policy{Name: "events-submit", Limit: 20, Window: time.Hour}
+	reactionRateLimit = middleware.Policy{Name: "events-react", Limit: 60, Window: time.Minute}
+)
+
 // RegisterRoutes registers event-related routes
-func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-	handler := NewHandler(db)
+func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
 	events := rg.Group("/events")
 	{
 		events.GET("/latest-update", handler.GetLatestUpdate)
-		events.GET("/", handler.GetEvents)
-		events.GET("/:id", handler.GetEvent)
+		events.GET("/", core.OptionalJWT(), handler.GetEvents)
+		events.GET("/:id", core.OptionalJWT(), handler.GetEvent)
 		events.GET("/export/ics", handler.ExportEventsICS)
+		events.GET("/export/ics/interested", core.JWTRequired(), handler.ExportInterestedEventsICS)
 		events.GET("/google-calendar-urls", handler.GetGoogleCalendarURLs)
-		
+
+		// Subscribable calendar feeds
+		events.GET("/feeds/events.ics", handler.FilteredCalendarFeed)
+		events.GET("/feeds/club/:id", handler.ClubCalendarFeed)
+		events.GET("/feeds/user/:token", handler.UserCalendarFeed)
+		events.GET("/feeds/me", core.JWTRequired(), handler.GetMyCalendarFeed)
+		events.POST("/feeds/me/rotate", core.JWTRequired(), handler.RotateMyCalendarFeed)
+		events.DELETE("/feeds/me", core.JWTRequired(), handler.RevokeMyCalendarFeed)
+
 		// Protected routes (require JWT)
-		events.POST("/extract", handler.ExtractEventFromScreenshot)
-		events.POST("/submit", handler.SubmitEvent)
-		
-		// TODO: Add rate limiting middleware
-		// TODO: Add authentication middleware for protected routes
+		events.POST("/extract", core.JWTRequired(), middleware.RateLimit(opts.RateLimiter, extractRateLimit), handler.ExtractEventFromScreenshot)
+		events.POST("/submit", core.JWTRequired(), middleware.RateLimit(opts.RateLimiter, submitRateLimit), handler.SubmitEvent)
+		events.GET("/my-submissions", core.JWTRequired(), handler.GetMySubmissions)
+
+		// Interest (RSVP)
+		events.POST("/:id/interest", core.JWTRequired(), handler.AddEventInterest)
+		events.DELETE("/:id/interest", core.JWTRequired(), handler.RemoveEventInterest)
+		events.GET("/my-interests", core.JWTRequired(), handler.GetMyInterestedEvents)
+
+		// Emoji reactions
+		events.GET("/:id/reactions", core.OptionalJWT(), handler.GetEventReactions)
+		events.POST("/:id/reactions", core.JWTRequired(), middleware.RateLimit(opts.RateLimiter, reactionRateLimit), handler.AddEventReaction)
+		events.DELETE("/:id/reactions/:emoji", core.JWTRequired(), middleware.RateLimit(opts.RateLimiter, reactionRateLimit), handler.RemoveEventReaction)
+
+		// Moderation (admin only)
+		admin := events.Group("/submissions", core.JWTRequired(), core.AdminRequired())
+		admin.GET("", handler.ListSubmissions)
+		admin.GET("/:id", handler.GetSubmission)
+		admin.PUT("/:id", handler.UpdateSubmission)
+		admin.POST("/:id/approve", handler.ApproveSubmission)
+		admin.POST("/:id/reject", handler.RejectSubmission)
+
+		// Duplicate review (admin only)
+		events.GET("/duplicates", core.JWTRequired(), core.AdminRequired(), handler.ListDuplicateFlags)
+		events.POST("/duplicates/:id/dismiss", core.JWTRequired(), core.AdminRequired(), handler.DismissDuplicateFlag)
+		events.GET("/:id/duplicates", core.JWTRequired(), core.AdminRequired(), handler.GetEventDuplicates)
+		events.POST("/merge", core.JWTRequired(), core.AdminRequired(), handler.MergeEvents)
 	}
+}
+
+// RegisterRootRoutes registers event routes served outside /api (RSS and Atom feeds)
+func RegisterRootRoutes(router *gin.Engine, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
-	// RSS feed at root level
-	// TODO: Register at router level, not under /api/events
-	// router.GET("/rss.xml", handler.RSSFeed)
+	router.GET("/rss.xml", handler.RSSFeed)
+	router.GET("/atom.xml", handler.AtomFeed)
 }
@@ -0,0 +1,315 @@
+package events
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// feedItemLimit caps the number of events in the RSS and Atom feeds
+	feedItemLimit = 50
+
+	feedTitle       = "Wat2Do - Upcoming Events"
+	feedDescription = "Upcoming campus club events"
+)
+
+// rssDocument is the root of an RSS 2.0 feed
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	Language      string      `xml:"language"`
+	LastBuildDate string      `xml:"lastBuildDate"`
+	TTL           int         `xml:"ttl"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Description string        `xml:"description"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// atomFeed is the root of an Atom (RFC 4287) feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// feedItems returns the next feedItemLimit confirmed upcoming or live events
+// matching the request's filters, ordered by earliest occurrence
+func (h *Handler) feedItems(c *gin.Context) ([]EventListItem, error) {
+	filter := eventFilterFromQuery(c)
+	now := time.Now().UTC()
+	query := h.DB.Model(&Events{}).
+		Joins("JOIN (?) AS occ ON occ.event_id = events.id", h.occurrenceWindow(now, nil)).
+		Where("events.status = ?", EventStatusConfirmed)
+	query = filter.ApplyEventFilters(query)
+
+	var keys []eventSortKey
+	err := query.Select("events.id, occ.earliest_dtstart").
+		Order("occ.earliest_dtstart ASC, events.id ASC").
+		Limit(feedItemLimit).
+		Scan(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return h.loadListItems(keys, nil, now)
+}
+
+// buildRSS renders feed items as an RSS 2.0 document
+func (h *Handler) buildRSS(c *gin.Context, items []EventListItem) rssDocument {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         feedTitle,
+			Link:          h.SiteURL,
+			Description:   feedDescription,
+			Language:      "en-ca",
+			LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+			TTL:           int(feedRefreshInterval / time.Minute),
+			AtomLink: rssAtomLink{
+				Href: h.requestURL(c),
+				Rel:  "self",
+				Type: "application/rss+xml",
+			},
+		},
+	}
+
+	for _, item := range items {
+		// The event page doubles as the GUID, so it never changes for an event
+		link := utils.BuildEventURL(item.ID, h.SiteURL)
+		rss := rssItem{
+			Title:       feedItemTitle(item),
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     feedItemPublished(item).Format(time.RFC1123Z),
+			Description: feedItemSummary(item),
+			Categories:  feedItemCategories(item),
+		}
+		if imageURL, length := feedItemImage(item); imageURL != "" {
+			rss.Enclosure = &rssEnclosure{URL: imageURL, Type: imageMIMEType(imageURL), Length: length}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, rss)
+	}
+
+	return doc
+}
+
+// buildAtom renders feed items as an Atom document
+func (h *Handler) buildAtom(c *gin.Context, items []EventListItem) atomFeed {
+	self := h.requestURL(c)
+	feed := atomFeed{
+		ID:    self,
+		Title: feedTitle,
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: h.SiteURL, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomPerson{Name: "Wat2Do"},
+	}
+
+	var updated time.Time
+	for _, item := range items {
+		link := utils.BuildEventURL(item.ID, h.SiteURL)
+		entry := atomEntry{
+			ID:         link,
+			Title:      feedItemTitle(item),
+			Updated:    item.UpdatedAt.UTC().Format(time.RFC3339),
+			Published:  feedItemPublished(item).UTC().Format(time.RFC3339),
+			Links:      []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Summary:    atomText{Type: "html", Body: feedItemSummary(item)},
+			Categories: make([]atomCategory, 0, len(item.Categories)),
+		}
+		for _, category := range feedItemCategories(item) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if imageURL, length := feedItemImage(item); imageURL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: imageURL, Rel: "enclosure", Type: imageMIMEType(imageURL), Length: length})
+		}
+		feed.Entries = append(feed.Entries, entry)
+
+		if item.UpdatedAt.After(updated) {
+			updated = item.UpdatedAt
+		}
+	}
+
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	return feed
+}
+
+// writeXML marshals doc with an XML declaration
+func (h *Handler) writeXML(c *gin.Context, contentType string, doc any) {
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(feedRefreshInterval/time.Second)/4))
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), out...))
+}
+
+func feedItemTitle(item EventListItem) string {
+	if item.Title != nil && *item.Title != "" {
+		return *item.Title
+	}
+	return "Untitled event"
+}
+
+// feedItemPublished is when the event was added to Wat2Do
+func feedItemPublished(item EventListItem) time.Time {
+	if item.AddedAt != nil {
+		return *item.AddedAt
+	}
+	return item.CreatedAt
+}
+
+// feedItemSummary renders the occurrence, location, host and description as HTML
+func feedItemSummary(item EventListItem) string {
+	var lines []string
+	if item.DtstartUTC != nil {
+		lines = append(lines, "<strong>When:</strong> "+html.EscapeString(formatOccurrence(*item.DtstartUTC, item.TZ)))
+	}
+	if item.Location != nil && *item.Location != "" {
+		lines = append(lines, "<strong>Where:</strong> "+html.EscapeString(*item.Location))
+	}
+	if item.DisplayHandle != "" {
+		lines = append(lines, "<strong>Host:</strong> "+html.EscapeString(item.DisplayHandle))
+	}
+	if item.Description != nil && *item.Description != "" {
+		lines = append(lines, "", strings.ReplaceAll(html.EscapeString(*item.Description), "\n", "<br/>"))
+	}
+	return strings.Join(lines, "<br/>")
+}
+
+// feedItemCategories returns the event's categories plus its club type
+func feedItemCategories(item EventListItem) []string {
+	categories := append([]string{}, item.Categories...)
+	if item.ClubType != nil && *item.ClubType != "" {
+		categories = append(categories, *item.ClubType)
+	}
+	return categories
+}
+
+// feedItemImage returns the image to attach as an enclosure and its size in
+// bytes, preferring the card-sized variant over the raw upload. The size is 0
+// when it isn't known, as for images we didn't process, which is what RSS
+// readers expect of an enclosure of unknown length.
+func feedItemImage(item EventListItem) (string, int64) {
+	if card := item.ImageVariants["card"]; card != "" {
+		return card, item.ImageSizes["card"]
+	}
+	if item.SourceImageURL != nil {
+		return *item.SourceImageURL, 0
+	}
+	return "", 0
+}
+
+// imageMIMEType guesses an image's content type from its URL extension
+func imageMIMEType(imageURL string) string {
+	if parsed, err := url.Parse(imageURL); err == nil {
+		if t := mime.TypeByExtension(strings.ToLower(path.Ext(parsed.Path))); strings.HasPrefix(t, "image/") {
+			return t
+		}
+	}
+	return "image/jpeg"
+}
+
+// formatOccurrence formats a start time in the occurrence's timezone
+func formatOccurrence(start time.Time, tz *string) string {
+	loc := time.UTC
+	if tz != nil && *tz != "" {
+		if l, err := time.LoadLocation(*tz); err == nil {
+			loc = l
+		}
+	}
+	return start.In(loc).Format("Mon, Jan 2, 2006 3:04 PM MST")
+}
+
+// requestURL is the absolute public URL of the current request. It's built
+// from the configured API URL rather than the request's Host header, which
+// clients control.
+func (h *Handler) requestURL(c *gin.Context) string {
+	return strings.TrimRight(h.APIURL, "/") + c.Request.URL.RequestURI()
+}
@@ -0,0 +1,234 @@
+package events
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// testFeed is RSS and Atom as a reader sees them
+type testFeed struct {
+	// RSS
+	Channel struct {
+		// Both <link> and <atom:link>, told apart by namespace
+		Links []struct {
+			XMLName xml.Name
+			Href    string `xml:"href,attr"`
+			Rel     string `xml:"rel,attr"`
+			Value   string `xml:",chardata"`
+		} `xml:"link"`
+		Items []struct {
+			Title       string   `xml:"title"`
+			Link        string   `xml:"link"`
+			GUID        string   `xml:"guid"`
+			Description string   `xml:"description"`
+			Categories  []string `xml:"category"`
+			Enclosure   *struct {
+				URL    string `xml:"url,attr"`
+				Type   string `xml:"type,attr"`
+				Length string `xml:"length,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+
+	// Atom
+	ID      string     `xml:"id"`
+	Links   []testLink `xml:"link"`
+	Entries []struct {
+		ID         string     `xml:"id"`
+		Title      string     `xml:"title"`
+		Links      []testLink `xml:"link"`
+		Summary    string     `xml:"summary"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+type testLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+func linkWithRel(links []testLink, rel string) *testLink {
+	for i := range links {
+		if links[i].Rel == rel {
+			return &links[i]
+		}
+	}
+	return nil
+}
+
+// createFeedEvents stores a confirmed event whose text needs escaping, with
+// a processed poster, and one with an external image
+func createFeedEvents(t *testing.T, db *gorm.DB) (poster, external *Events) {
+	t.Helper()
+	past := time.Now().Add(-time.Hour)
+	start := time.Now().Add(24 * time.Hour).UTC()
+
+	poster = createEvent(t, db, `Pizza & "Pop" <Night>`, EventStatusConfirmed, &past, start)
+	clubType := "Academic"
+	description := "Free <b>pizza</b>\nfor everyone & friends"
+	location := "MC 4020"
+	err := db.Model(poster).Updates(&Events{
+		Description: &description,
+		Location:    &location,
+		ClubType:    &clubType,
+		Categories:  []string{"Food & Drink", "Social"},
+		ImageVariants: ImageURLs{
+			"card": "https://cdn.wat2do.ca/screenshots/abc/card.jpg",
+			"full": "https://cdn.wat2do.ca/screenshots/abc/full.jpg",
+		},
+		ImageSizes: ImageSizes{"card": 48213, "full": 203117},
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	external = createEvent(t, db, "Games", EventStatusConfirmed, &past, start.Add(time.Hour))
+	source := "https://example.com/poster.png"
+	if err := db.Model(external).Update("source_image_url", source).Error; err != nil {
+		t.Fatal(err)
+	}
+	return poster, external
+}
+
+// getFeed requests a feed through the root routes as a client claiming
+// another host
+func getFeed(t *testing.T, db *gorm.DB, opts Options, target string) (string, testFeed) {
+	t.Helper()
+	router := gin.New()
+	RegisterRootRoutes(router, db, opts)
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "attacker.example"
+	req.Header.Set("X-Forwarded-Proto", "http")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d %s", target, w.Code, w.Body)
+	}
+
+	var feed testFeed
+	if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed doesn't parse: %v\n%s", err, w.Body)
+	}
+	return w.Body.String(), feed
+}
+
+func TestRSSFeed(t *testing.T) {
+	db := openEventsDB(t)
+	poster, external := createFeedEvents(t, db)
+
+	body, feed := getFeed(t, db, Options{SiteURL: "https://wat2do.ca", APIURL: "https://api.wat2do.ca"}, "/rss.xml?ref=reader&x=%3Cy%3E")
+	if !strings.HasPrefix(body, xml.Header) {
+		t.Error("feed has no XML declaration")
+	}
+	links := feed.Channel.Links
+	if len(links) != 2 {
+		t.Fatalf("channel links = %+v, want <link> and <atom:link>", links)
+	}
+	if links[0].XMLName.Space != "" || links[0].Value != "https://wat2do.ca" {
+		t.Errorf("channel link = %+v", links[0])
+	}
+	if self := links[1]; self.XMLName.Space != "http://www.w3.org/2005/Atom" || self.Rel != "self" ||
+		self.Href != "https://api.wat2do.ca/rss.xml?ref=reader&x=%3Cy%3E" {
+		t.Errorf("self link = %+v, want the API URL, not the request's host", self)
+	}
+
+	items := feed.Channel.Items
+	if len(items) != 2 {
+		t.Fatalf("%d items, want 2", len(items))
+	}
+	item := items[0]
+	if item.Title != `Pizza & "Pop" <Night>` {
+		t.Errorf("title = %q", item.Title)
+	}
+	if strings.Contains(body, "<Night>") || strings.Contains(body, "<b>pizza") {
+		t.Error("feed has unescaped markup")
+	}
+	// The description is HTML, so the event's own markup is shown, not rendered
+	if !strings.Contains(item.Description, "Free &lt;b&gt;pizza&lt;/b&gt;<br/>for everyone &amp; friends") {
+		t.Errorf("description = %q", item.Description)
+	}
+	if want := "https://wat2do.ca/events/" + strconv.FormatUint(uint64(poster.ID), 10); item.Link != want || item.GUID != want {
+		t.Errorf("link = %q, guid = %q, want %q", item.Link, item.GUID, want)
+	}
+	if strings.Join(item.Categories, "|") != "Food & Drink|Social|Academic" {
+		t.Errorf("categories = %q, want the event's plus its club type", item.Categories)
+	}
+	if e := item.Enclosure; e == nil || e.URL != "https://cdn.wat2do.ca/screenshots/abc/card.jpg" || e.Type != "image/jpeg" || e.Length != "48213" {
+		t.Errorf("enclosure = %+v, want the card variant and its size", e)
+	}
+
+	// An image we didn't process has no known size
+	if e := items[1].Enclosure; e == nil || e.URL != *mustEvent(t, db, external.ID).SourceImageURL || e.Type != "image/png" || e.Length != "0" {
+		t.Errorf("external enclosure = %+v", e)
+	}
+	if len(items[1].Categories) != 0 {
+		t.Errorf("categories = %q, want none", items[1].Categories)
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	db := openEventsDB(t)
+	poster, _ := createFeedEvents(t, db)
+
+	body, feed := getFeed(t, db, Options{SiteURL: "https://wat2do.ca", APIURL: "https://api.wat2do.ca/"}, "/atom.xml?ref=reader")
+	self := linkWithRel(feed.Links, "self")
+	if self == nil || self.Href != "https://api.wat2do.ca/atom.xml?ref=reader" || self.Type != "application/atom+xml" {
+		t.Errorf("self link = %+v, want the API URL", self)
+	}
+	if feed.ID != "https://api.wat2do.ca/atom.xml?ref=reader" {
+		t.Errorf("feed id = %q", feed.ID)
+	}
+	if alternate := linkWithRel(feed.Links, "alternate"); alternate == nil || alternate.Href != "https://wat2do.ca" {
+		t.Errorf("alternate link = %+v", alternate)
+	}
+
+	if len(feed.Entries) != 2 {
+		t.Fatalf("%d entries, want 2", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.Title != `Pizza & "Pop" <Night>` || strings.Contains(body, "<Night>") {
+		t.Errorf("title = %q", entry.Title)
+	}
+	if entry.ID != "https://wat2do.ca/events/"+strconv.FormatUint(uint64(poster.ID), 10) {
+		t.Errorf("entry id = %q", entry.ID)
+	}
+	if !strings.Contains(entry.Summary, "Free &lt;b&gt;pizza&lt;/b&gt;") {
+		t.Errorf("summary = %q", entry.Summary)
+	}
+	var terms []string
+	for _, category := range entry.Categories {
+		terms = append(terms, category.Term)
+	}
+	if strings.Join(terms, "|") != "Food & Drink|Social|Academic" {
+		t.Errorf("categories = %q", terms)
+	}
+	if enclosure := linkWithRel(entry.Links, "enclosure"); enclosure == nil || enclosure.Length != "48213" || enclosure.Type != "image/jpeg" {
+		t.Errorf("enclosure = %+v, want the card variant and its size", enclosure)
+	}
+	// Atom's length is optional, so it's left out when unknown
+	if enclosure := linkWithRel(feed.Entries[1].Links, "enclosure"); enclosure == nil || enclosure.Length != "" {
+		t.Errorf("external enclosure = %+v, want no length", enclosure)
+	}
+}
+
+func mustEvent(t *testing.T, db *gorm.DB, id uint) *Events {
+	t.Helper()
+	var event Events
+	if err := db.First(&event, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &event
+}
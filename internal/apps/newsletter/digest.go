@@ -0,0 +1,201 @@
+package newsletter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"gorm.io/gorm"
+)
+
+// maxHighlights caps each highlight section of the digest
+const maxHighlights = 3
+
+// Digest is a day or week of upcoming events, as rendered by the digest
+// template
+type Digest struct {
+	Start      time.Time // inclusive
+	End        time.Time // exclusive
+	Days       []DigestDay
+	FreeFood   []DigestEvent // events offering food
+	Popular    []DigestEvent // events with the most interest
+	EventCount int           // distinct events
+}
+
+// DigestDay lists the occurrences starting on one local day
+type DigestDay struct {
+	Date   time.Time
+	Label  string // e.g. "Monday, October 20"
+	Events []DigestEvent
+}
+
+// DigestEvent is one event occurrence in the digest
+type DigestEvent struct {
+	ID            uint
+	Title         string
+	URL           string
+	Start         time.Time
+	Day           string // e.g. "Mon, Oct 20"
+	Time          string // e.g. "7:00 PM"
+	Location      string
+	Food          string
+	Price         string // "Free", "$12.50", or empty when unknown
+	Free          bool
+	InterestCount int64
+}
+
+// Daily reports whether the digest covers a single day
+func (d *Digest) Daily() bool {
+	return !d.Start.AddDate(0, 0, 1).Before(d.End)
+}
+
+// Title summarises the digest for email subjects
+func (d *Digest) Title() string {
+	if d.Daily() {
+		return fmt.Sprintf("%d events today on Wat2Do (%s)", d.EventCount, d.Start.Format("Mon, Jan 2"))
+	}
+	last := d.End.AddDate(0, 0, -1)
+	return fmt.Sprintf("%d events this week on Wat2Do (%s – %s)",
+		d.EventCount, d.Start.Format("Jan 2"), last.Format("Jan 2"))
+}
+
+// digestRow is an occurrence joined with its event
+type digestRow struct {
+	EventID    uint
+	DtstartUTC time.Time
+	Title      *string
+	Location   *string
+	Food       *string
+	Price      *float64
+}
+
+// BuildDigest collects the CONFIRMED event occurrences starting in
+// [start, end), grouped by day in start's location. A non-nil filter narrows
+// the events the same way as the event listing.
+func BuildDigest(db *gorm.DB, start, end time.Time, siteURL string, filter *utils.EventFilter) (*Digest, error) {
+	query := db.Table("event_dates").
+		Select("event_dates.event_id, event_dates.dtstart_utc, events.title, events.location, events.food, events.price").
+		Joins("JOIN events ON events.id = event_dates.event_id AND events.deleted_at IS NULL").
+		Where("events.status = ? AND event_dates.deleted_at IS NULL", events.EventStatusConfirmed).
+		Where("event_dates.dtstart_utc >= ? AND event_dates.dtstart_utc < ?", start.UTC(), end.UTC())
+	if filter != nil {
+		query = filter.ApplyEventFilters(query)
+	}
+
+	var rows []digestRow
+	err := query.Order("event_dates.dtstart_utc, event_dates.event_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := interestCounts(db, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &Digest{Start: start, End: end}
+	firsts := map[uint]DigestEvent{} // each event's first occurrence, for highlights
+	var order []uint
+	for _, row := range rows {
+		event := newDigestEvent(row, start.Location(), siteURL, counts[row.EventID])
+
+		date := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, start.Location())
+		if n := len(digest.Days); n == 0 || !digest.Days[n-1].Date.Equal(date) {
+			digest.Days = append(digest.Days, DigestDay{Date: date, Label: date.Format("Monday, January 2")})
+		}
+		day := &digest.Days[len(digest.Days)-1]
+		day.Events = append(day.Events, event)
+
+		if _, ok := firsts[row.EventID]; !ok {
+			firsts[row.EventID] = event
+			order = append(order, row.EventID)
+		}
+	}
+	digest.EventCount = len(order)
+
+	for _, id := range order {
+		if event := firsts[id]; event.Food != "" && len(digest.FreeFood) < maxHighlights {
+			digest.FreeFood = append(digest.FreeFood, event)
+		}
+	}
+
+	popular := make([]DigestEvent, 0, len(order))
+	for _, id := range order {
+		if event := firsts[id]; event.InterestCount > 0 {
+			popular = append(popular, event)
+		}
+	}
+	// Stable, so ties keep chronological order
+	sort.SliceStable(popular, func(i, j int) bool {
+		return popular[i].InterestCount > popular[j].InterestCount
+	})
+	if len(popular) > maxHighlights {
+		popular = popular[:maxHighlights]
+	}
+	digest.Popular = popular
+
+	return digest, nil
+}
+
+func newDigestEvent(row digestRow, loc *time.Location, siteURL string, interest int64) DigestEvent {
+	start := row.DtstartUTC.In(loc)
+	event := DigestEvent{
+		ID:            row.EventID,
+		Title:         "Untitled event",
+		URL:           fmt.Sprintf("%s/events/%d", strings.TrimRight(siteURL, "/"), row.EventID),
+		Start:         start,
+		Day:           start.Format("Mon, Jan 2"),
+		Time:          start.Format("3:04 PM"),
+		InterestCount: interest,
+	}
+	if row.Title != nil && strings.TrimSpace(*row.Title) != "" {
+		event.Title = strings.TrimSpace(*row.Title)
+	}
+	if row.Location != nil {
+		event.Location = strings.TrimSpace(*row.Location)
+	}
+	if row.Food != nil {
+		event.Food = strings.TrimSpace(*row.Food)
+	}
+	if row.Price != nil {
+		if *row.Price <= 0 {
+			event.Price = "Free"
+			event.Free = true
+		} else {
+			event.Price = fmt.Sprintf("$%.2f", *row.Price)
+		}
+	}
+	return event
+}
+
+// interestCounts counts interested users per event in rows
+func interestCounts(db *gorm.DB, rows []digestRow) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	if len(rows) == 0 {
+		return counts, nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.EventID)
+	}
+
+	var results []struct {
+		EventID uint
+		Count   int64
+	}
+	err := db.Model(&events.EventInterest{}).
+		Select("event_id, COUNT(*) AS count").
+		Where("event_id IN ?", ids).
+		Group("event_id").
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		counts[r.EventID] = r.Count
+	}
+	return counts, nil
+}
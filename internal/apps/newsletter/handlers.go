@@ -1,48 +1,394 @@
 package newsletter
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/mail"
+	"strings"
+	"time"
 
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// confirmationResendDelay stops repeated sign-ups from flooding an inbox
+const confirmationResendDelay = 10 * time.Minute
+
+const (
+	// maxPreferenceValues caps each preference list
+	maxPreferenceValues = 50
+
+	// maxPreferenceLength caps each category, club type or school
+	maxPreferenceLength = 100
 )
 
 // Handler holds dependencies for newsletter handlers
 type Handler struct {
 	DB *gorm.DB
+	Options
+}
+
+// Options configures the newsletter handlers
+type Options struct {
+	Mailer *Mailer
+
+	// RateLimiter enforces the per-route limits; nil disables them
+	RateLimiter middleware.Limiter
+
+	// DailyDigests reports whether daily digests are sent; without them
+	// subscribers can only choose weekly
+	DailyDigests bool
 }
 
 // NewHandler creates a new newsletter handler
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *gorm.DB, opts Options) *Handler {
+	return &Handler{DB: db, Options: opts}
 }
 
 // Subscribe handles POST /api/newsletter/subscribe - subscribe to newsletter
-// Body: { "email": "user@example.com" }
+// Body: { "email": "user@example.com", "locale": "fr" }
+// The locale is optional and defaults to the Accept-Language header. The
+// subscriber stays inactive until they follow the emailed confirmation link.
+// The response is the same whether or not the address was subscribed.
 func (h *Handler) Subscribe(c *gin.Context) {
-	// TODO: Implement newsletter subscription
-	// 1. Parse email from request body
-	// 2. Validate email format
-	// 3. Check if email already exists
-	// 4. Create new subscriber record
-	// 5. Send confirmation email
-	// 6. Return success response
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Subscribed successfully",
+	var body struct {
+		Email  string `json:"email"`
+		Locale string `json:"locale"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+	email, ok := normalizeEmail(body.Email)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	locale := body.Locale
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+
+	sub, err := h.pendingSubscriber(email, services.ParseLocale(locale))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe"})
+		return
+	}
+
+	if sub != nil {
+		if err := h.Mailer.SendConfirmation(sub); err != nil {
+			log.Printf("Failed to send newsletter confirmation to subscriber %d: %v", sub.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send confirmation email"})
+			return
+		}
+		h.DB.Model(sub).UpdateColumn("confirmation_sent_at", time.Now())
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Check your inbox to confirm your subscription",
+	})
+}
+
+// pendingSubscriber returns the subscriber for email that should be sent a
+// confirmation, creating it in locale or restoring an unsubscribed one. It
+// returns nil for confirmed subscribers and ones recently sent a confirmation.
+func (h *Handler) pendingSubscriber(email, locale string) (*NewsletterSubscriber, error) {
+	// Insert-or-ignore so concurrent sign-ups can't trip the unique index
+	err := h.DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "email"}}, DoNothing: true}).
+		Create(&NewsletterSubscriber{Email: email, Locale: locale}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var sub NewsletterSubscriber
+	if err := h.DB.Unscoped().Where("email = ?", email).Take(&sub).Error; err != nil {
+		return nil, err
+	}
+
+	if sub.DeletedAt.Valid {
+		// Unsubscribed earlier: restore the row and require confirmation again
+		err := h.DB.Unscoped().Model(&sub).Updates(map[string]interface{}{
+			"deleted_at":           nil,
+			"active":               false,
+			"locale":               locale,
+			"confirmed_at":         nil,
+			"confirmation_sent_at": nil,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		sub.DeletedAt = gorm.DeletedAt{}
+		sub.Active = false
+		sub.Locale = locale
+		sub.ConfirmedAt = nil
+		return &sub, nil
+	}
+
+	if sub.Active {
+		return nil, nil
+	}
+	if sub.ConfirmationSentAt != nil && time.Since(*sub.ConfirmationSentAt) < confirmationResendDelay {
+		return nil, nil
+	}
+	return &sub, nil
+}
+
+// ConfirmSubscription handles POST /api/newsletter/confirm - confirm a subscription
+// Body: { "token": "..." } from the emailed confirmation link
+func (h *Handler) ConfirmSubscription(c *gin.Context) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	sub, err := h.Mailer.Signer.Subscriber(h.DB, purposeConfirm, body.Token)
+	if err != nil || sub.DeletedAt.Valid {
+		if err != nil && !errors.Is(err, errInvalidToken) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm subscription"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+
+	if !sub.Active {
+		now := time.Now()
+		err := h.DB.Model(sub).Updates(map[string]interface{}{
+			"active":       true,
+			"confirmed_at": now,
+		}).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm subscription"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subscription confirmed",
 	})
 }
 
 // Unsubscribe handles POST /api/newsletter/unsubscribe - unsubscribe from newsletter
-// Body: { "email": "user@example.com" }
+// Token: ?token=... (RFC 8058 one-click, List-Unsubscribe header) or
+// { "token": "..." } from the unsubscribe page
 func (h *Handler) Unsubscribe(c *gin.Context) {
-	// TODO: Implement newsletter unsubscription
-	// 1. Parse email from request body
-	// 2. Find subscriber by email
-	// 3. Mark as inactive or delete record
-	// 4. Return success response
+	token := c.Query("token")
+	if token == "" {
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := c.ShouldBindJSON(&body); err == nil {
+			token = body.Token
+		}
+	}
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+
+	sub, err := h.Mailer.Signer.Subscriber(h.DB, purposeUnsubscribe, token)
+	if errors.Is(err, errInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unsubscribe link"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+
+	// Repeated unsubscribes succeed; the row is already soft-deleted
+	if !sub.DeletedAt.Valid {
+		err := h.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(sub).Update("active", false).Error; err != nil {
+				return err
+			}
+			return tx.Delete(sub).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Unsubscribed successfully",
 	})
 }
+
+// GetPreferences handles GET /api/newsletter/preferences - get a subscriber's preferences
+// Token: ?token=... from the preferences link in newsletter emails
+func (h *Handler) GetPreferences(c *gin.Context) {
+	sub, ok := h.preferencesSubscriber(c)
+	if !ok {
+		return
+	}
+
+	prefs := SubscriberPreferences{Frequency: FrequencyWeekly}
+	err := h.DB.Where("subscriber_id = ?", sub.ID).Take(&prefs).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch preferences"})
+		return
+	}
+	if prefs.Frequency == FrequencyDaily && !h.DailyDigests {
+		// Daily subscribers get the weekly digest while no daily one is sent
+		prefs.Frequency = FrequencyWeekly
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":       sub.Email,
+		"locale":      sub.Locale,
+		"preferences": prefs,
+	})
+}
+
+// UpdatePreferences handles PUT /api/newsletter/preferences - replace a subscriber's preferences
+// Token: ?token=... from the preferences link in newsletter emails
+// Body: { "categories": [...], "club_types": [...], "schools": [...],
+// "free_food_only": false, "frequency": "daily" | "weekly", "locale": "en" | "fr" }
+// The locale is optional; it's left unchanged when omitted. Daily is only
+// accepted when daily digests are scheduled.
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	sub, ok := h.preferencesSubscriber(c)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Categories   []string `json:"categories"`
+		ClubTypes    []string `json:"club_types"`
+		Schools      []string `json:"schools"`
+		FreeFoodOnly bool     `json:"free_food_only"`
+		Frequency    string   `json:"frequency"`
+		Locale       string   `json:"locale"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	prefs := SubscriberPreferences{
+		SubscriberID: sub.ID,
+		FreeFoodOnly: body.FreeFoodOnly,
+		Frequency:    strings.ToLower(strings.TrimSpace(body.Frequency)),
+	}
+	if prefs.Frequency == "" {
+		prefs.Frequency = FrequencyWeekly
+	}
+	if prefs.Frequency != FrequencyDaily && prefs.Frequency != FrequencyWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "frequency must be daily or weekly"})
+		return
+	}
+	if prefs.Frequency == FrequencyDaily && !h.DailyDigests {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Daily digests are not available; frequency must be weekly"})
+		return
+	}
+	if body.Locale != "" && !services.SupportedLocale(body.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "locale must be one of " + strings.Join(services.EmailLocales, ", ")})
+		return
+	}
+	for _, field := range []struct {
+		name   string
+		values []string
+		dest   *[]string
+	}{
+		{"categories", body.Categories, &prefs.Categories},
+		{"club_types", body.ClubTypes, &prefs.ClubTypes},
+		{"schools", body.Schools, &prefs.Schools},
+	} {
+		values, ok := preferenceValues(field.values)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must have at most %d values of up to %d characters",
+				field.name, maxPreferenceValues, maxPreferenceLength)})
+			return
+		}
+		*field.dest = values
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "subscriber_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"categories", "club_types", "schools", "free_food_only", "frequency", "updated_at"}),
+		}).Create(&prefs).Error
+		if err != nil || body.Locale == "" || body.Locale == sub.Locale {
+			return err
+		}
+		sub.Locale = body.Locale
+		return tx.Model(sub).Update("locale", body.Locale).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":       sub.Email,
+		"locale":      sub.Locale,
+		"preferences": prefs,
+	})
+}
+
+// preferencesSubscriber loads the subscriber from the ?token= preferences
+// token, writing the error response if it's invalid or they've unsubscribed
+func (h *Handler) preferencesSubscriber(c *gin.Context) (*NewsletterSubscriber, bool) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return nil, false
+	}
+
+	sub, err := h.Mailer.Signer.Subscriber(h.DB, purposePreferences, token)
+	if err != nil && !errors.Is(err, errInvalidToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriber"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid preferences link"})
+		return nil, false
+	}
+	if sub.DeletedAt.Valid {
+		c.JSON(http.StatusGone, gin.H{"error": "You have unsubscribed from the newsletter"})
+		return nil, false
+	}
+	return sub, true
+}
+
+// preferenceValues trims and de-duplicates a preference list, rejecting
+// oversized ones
+func preferenceValues(raw []string) ([]string, bool) {
+	values := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, value := range raw {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		if len(value) > maxPreferenceLength {
+			return nil, false
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	if len(values) > maxPreferenceValues {
+		return nil, false
+	}
+	return values, true
+}
+
+// normalizeEmail lower-cases and validates a bare email address
+func normalizeEmail(raw string) (string, bool) {
+	email := strings.ToLower(strings.TrimSpace(raw))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 255 {
+		return "", false
+	}
+	return email, true
+}
@@ -0,0 +1,242 @@
+package newsletter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// preferencesRouter serves the preferences routes for one active subscriber
+// and returns the token from their preferences link
+func preferencesRouter(t *testing.T, dailyDigests bool) (*gin.Engine, *Handler, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.OpenDB(t, &NewsletterSubscriber{}, &SubscriberPreferences{})
+
+	sub := &NewsletterSubscriber{Email: "reader@example.com", Active: true, Locale: "en"}
+	if err := db.Create(sub).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(db, Options{
+		Mailer:       &Mailer{Signer: NewSigner([]byte("test-key")), SiteURL: "https://example.com"},
+		DailyDigests: dailyDigests,
+	})
+	router := gin.New()
+	router.GET("/preferences", h.GetPreferences)
+	router.PUT("/preferences", h.UpdatePreferences)
+
+	link, err := url.Parse(h.Mailer.PreferencesURL(sub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, h, link.Query().Get("token")
+}
+
+func preferencesRequest(router *gin.Engine, method, token, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, "/preferences?token="+url.QueryEscape(token), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func frequency(response map[string]interface{}) interface{} {
+	prefs, _ := response["preferences"].(map[string]interface{})
+	return prefs["frequency"]
+}
+
+func TestUpdatePreferencesFrequency(t *testing.T) {
+	router, _, token := preferencesRouter(t, true)
+
+	code, response := preferencesRequest(router, http.MethodPut, token, `{"frequency": "Daily", "categories": ["Tech"]}`)
+	if code != http.StatusOK || frequency(response) != FrequencyDaily {
+		t.Fatalf("daily = %d %v, want 200 daily", code, response)
+	}
+	if code, response := preferencesRequest(router, http.MethodGet, token, ""); code != http.StatusOK || frequency(response) != FrequencyDaily {
+		t.Errorf("get = %d %v, want daily", code, response)
+	}
+	if code, _ := preferencesRequest(router, http.MethodPut, token, `{"frequency": "hourly"}`); code != http.StatusBadRequest {
+		t.Errorf("hourly = %d, want 400", code)
+	}
+}
+
+func TestUpdatePreferencesWithoutDailyDigests(t *testing.T) {
+	router, h, token := preferencesRouter(t, false)
+
+	if code, _ := preferencesRequest(router, http.MethodPut, token, `{"frequency": "daily"}`); code != http.StatusBadRequest {
+		t.Errorf("daily = %d, want 400", code)
+	}
+	if code, response := preferencesRequest(router, http.MethodPut, token, `{"frequency": "weekly"}`); code != http.StatusOK || frequency(response) != FrequencyWeekly {
+		t.Errorf("weekly = %d %v, want 200 weekly", code, response)
+	}
+
+	// Chosen while daily digests were sent; they get the weekly one now
+	h.DB.Model(&SubscriberPreferences{}).Where("1 = 1").Update("frequency", FrequencyDaily)
+	if code, response := preferencesRequest(router, http.MethodGet, token, ""); code != http.StatusOK || frequency(response) != FrequencyWeekly {
+		t.Errorf("get = %d %v, want weekly", code, response)
+	}
+}
+
+// recordingSender records the templated emails it's asked to send, failing
+// those to addresses in fail
+type recordingSender struct {
+	to   []string
+	sent []map[string]interface{}
+	fail map[string]bool
+}
+
+func (s *recordingSender) Send(*services.EmailMessage) error { return nil }
+
+func (s *recordingSender) SendTemplatedEmail(to, templateName, locale string, data map[string]interface{}, headers map[string]string) error {
+	if s.fail[to] {
+		return errors.New("mailbox unavailable")
+	}
+	s.to = append(s.to, to)
+	s.sent = append(s.sent, data)
+	return nil
+}
+
+// subscriptionRouter serves the sign-up, confirm and unsubscribe routes
+func subscriptionRouter(t *testing.T) (*gin.Engine, *Handler, *recordingSender) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.OpenDB(t, &NewsletterSubscriber{}, &SubscriberPreferences{})
+	sender := &recordingSender{}
+	h := NewHandler(db, Options{Mailer: &Mailer{
+		Email:   sender,
+		Signer:  NewSigner([]byte("test-key")),
+		SiteURL: "https://example.com",
+		APIURL:  "https://api.example.com",
+	}})
+	router := gin.New()
+	router.POST("/subscribe", h.Subscribe)
+	router.POST("/confirm", h.ConfirmSubscription)
+	router.POST("/unsubscribe", h.Unsubscribe)
+	return router, h, sender
+}
+
+func post(router *gin.Engine, path, body string) int {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// linkToken returns the token in a link
+func linkToken(t *testing.T, link string) string {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query().Get("token")
+}
+
+func tokenBody(token string) string {
+	body, _ := json.Marshal(map[string]string{"token": token})
+	return string(body)
+}
+
+// subscriber reloads the subscriber for email, including unsubscribed ones
+func subscriber(t *testing.T, h *Handler, email string) *NewsletterSubscriber {
+	t.Helper()
+	var sub NewsletterSubscriber
+	if err := h.DB.Unscoped().Where("email = ?", email).Take(&sub).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &sub
+}
+
+func TestSubscribeConfirmAndUnsubscribe(t *testing.T) {
+	router, h, sender := subscriptionRouter(t)
+
+	if code := post(router, "/subscribe", `{"email": " Reader@Example.com "}`); code != http.StatusAccepted {
+		t.Fatalf("subscribe = %d", code)
+	}
+	sub := subscriber(t, h, "reader@example.com")
+	if sub.Active || len(sender.sent) != 1 {
+		t.Fatalf("after sign-up: active %t, %d emails; want inactive, 1 confirmation", sub.Active, len(sender.sent))
+	}
+	confirm := linkToken(t, sender.sent[0]["ConfirmURL"].(string))
+
+	// Signing up again soon after doesn't send another email
+	post(router, "/subscribe", `{"email": "reader@example.com"}`)
+	if len(sender.sent) != 1 {
+		t.Errorf("sent %d confirmations for two quick sign-ups", len(sender.sent))
+	}
+
+	// The confirmation token only confirms
+	unsubscribe := linkToken(t, h.Mailer.OneClickUnsubscribeURL(sub))
+	if code := post(router, "/unsubscribe?token="+url.QueryEscape(confirm), ""); code != http.StatusBadRequest {
+		t.Errorf("unsubscribe with a confirm token = %d, want 400", code)
+	}
+	if code := post(router, "/confirm", tokenBody(unsubscribe)); code != http.StatusBadRequest {
+		t.Errorf("confirm with an unsubscribe token = %d, want 400", code)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := post(router, "/confirm", tokenBody(confirm)); code != http.StatusOK {
+			t.Errorf("confirm %d = %d", i+1, code)
+		}
+	}
+	if sub := subscriber(t, h, "reader@example.com"); !sub.Active || sub.ConfirmedAt == nil {
+		t.Errorf("confirmed subscriber = %+v", sub)
+	}
+
+	// One-click unsubscribe, then again from the page; both succeed
+	if code := post(router, "/unsubscribe?token="+url.QueryEscape(unsubscribe), ""); code != http.StatusOK {
+		t.Errorf("one-click unsubscribe = %d", code)
+	}
+	if code := post(router, "/unsubscribe", tokenBody(unsubscribe)); code != http.StatusOK {
+		t.Errorf("repeat unsubscribe = %d", code)
+	}
+	if sub := subscriber(t, h, "reader@example.com"); sub.Active || !sub.DeletedAt.Valid {
+		t.Errorf("unsubscribed subscriber = %+v", sub)
+	}
+
+	// An old confirmation link can't resubscribe them
+	if code := post(router, "/confirm", tokenBody(confirm)); code != http.StatusBadRequest {
+		t.Errorf("confirm after unsubscribing = %d, want 400", code)
+	}
+
+	// Signing up again restores the row, pending a new confirmation
+	post(router, "/subscribe", `{"email": "reader@example.com", "locale": "fr"}`)
+	if sub := subscriber(t, h, "reader@example.com"); sub.DeletedAt.Valid || sub.Active || sub.Locale != "fr" || len(sender.sent) != 2 {
+		t.Errorf("after signing up again: %+v, %d emails", sub, len(sender.sent))
+	}
+}
+
+func TestSubscriptionRejectsBadRequests(t *testing.T) {
+	router, _, sender := subscriptionRouter(t)
+
+	for _, tt := range []struct {
+		path, body string
+	}{
+		{"/subscribe", `{"email": "not an email"}`},
+		{"/subscribe", `{}`},
+		{"/confirm", `{}`},
+		{"/confirm", tokenBody("1.0.forged")},
+		{"/unsubscribe", `{}`},
+		{"/unsubscribe?token=1.0.forged", ""},
+	} {
+		if code := post(router, tt.path, tt.body); code != http.StatusBadRequest {
+			t.Errorf("%s %s = %d, want 400", tt.path, tt.body, code)
+		}
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("sent %d emails for bad requests", len(sender.sent))
+	}
+}
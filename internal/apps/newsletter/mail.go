@@ -0,0 +1,105 @@
+package newsletter
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+)
+
+// EmailSender sends a single email; services.EmailService implements it
+type EmailSender interface {
+	Send(msg *services.EmailMessage) error
+	SendTemplatedEmail(to, templateName, locale string, data map[string]interface{}, headers map[string]string) error
+}
+
+// Mailer sends newsletter emails with signed confirmation and unsubscribe
+// links
+type Mailer struct {
+	Email   EmailSender
+	Signer  *Signer
+	SiteURL string // frontend pages that confirm or unsubscribe via the API
+	APIURL  string // public API root, for one-click unsubscribe
+}
+
+// ConfirmURL is the frontend page that confirms sub's subscription
+func (m *Mailer) ConfirmURL(sub *NewsletterSubscriber) string {
+	return m.siteLink("/newsletter/confirm", m.Signer.Token(purposeConfirm, sub, confirmTokenTTL))
+}
+
+// UnsubscribeURL is the frontend page that unsubscribes sub
+func (m *Mailer) UnsubscribeURL(sub *NewsletterSubscriber) string {
+	return m.siteLink("/newsletter/unsubscribe", m.Signer.Token(purposeUnsubscribe, sub, 0))
+}
+
+// PreferencesURL is the frontend preference centre for sub
+func (m *Mailer) PreferencesURL(sub *NewsletterSubscriber) string {
+	return m.siteLink("/newsletter/preferences", m.Signer.Token(purposePreferences, sub, 0))
+}
+
+// OneClickUnsubscribeURL is the API endpoint mail clients POST to, per RFC 8058
+func (m *Mailer) OneClickUnsubscribeURL(sub *NewsletterSubscriber) string {
+	token := m.Signer.Token(purposeUnsubscribe, sub, 0)
+	return strings.TrimRight(m.APIURL, "/") + "/api/newsletter/unsubscribe?token=" + url.QueryEscape(token)
+}
+
+func (m *Mailer) siteLink(path, token string) string {
+	return strings.TrimRight(m.SiteURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// SendConfirmation emails sub a link to confirm their subscription
+func (m *Mailer) SendConfirmation(sub *NewsletterSubscriber) error {
+	return m.Email.SendTemplatedEmail(sub.Email, "confirm", sub.Locale, map[string]interface{}{
+		"SiteURL":    m.SiteURL,
+		"ConfirmURL": m.ConfirmURL(sub),
+		"Days":       int(confirmTokenTTL / (24 * time.Hour)),
+	}, nil)
+}
+
+// SendNewsletter emails an active subscriber. The message carries preferences
+// and unsubscribe links in its footer and RFC 8058 one-click unsubscribe
+// headers.
+func (m *Mailer) SendNewsletter(sub *NewsletterSubscriber, subject, htmlBody, textBody string) error {
+	return m.Email.Send(m.newsletterMessage(sub, subject, htmlBody, textBody))
+}
+
+func (m *Mailer) newsletterMessage(sub *NewsletterSubscriber, subject, htmlBody, textBody string) *services.EmailMessage {
+	unsubscribe := m.UnsubscribeURL(sub)
+	preferences := m.PreferencesURL(sub)
+	return &services.EmailMessage{
+		To:      sub.Email,
+		Subject: subject,
+		Text:    textBody + "\n\n--\nManage preferences: " + preferences + "\nUnsubscribe: " + unsubscribe + "\n",
+		HTML: htmlBody + fmt.Sprintf(`<p style="font-size:12px;color:#666">`+
+			`<a href="%s">Manage preferences</a> or <a href="%s">unsubscribe</a> from the Wat2Do newsletter.</p>`,
+			html.EscapeString(preferences), html.EscapeString(unsubscribe)),
+		Headers: m.unsubscribeHeaders(sub),
+	}
+}
+
+// SendTemplatedNewsletter emails an active subscriber the named template in
+// their locale. The template gets UnsubscribeURL, PreferencesURL and SiteURL
+// alongside data, and must link to UnsubscribeURL itself; the message carries
+// the RFC 8058 headers.
+func (m *Mailer) SendTemplatedNewsletter(sub *NewsletterSubscriber, templateName string, data map[string]interface{}) error {
+	merged := map[string]interface{}{
+		"SiteURL":        m.SiteURL,
+		"UnsubscribeURL": m.UnsubscribeURL(sub),
+		"PreferencesURL": m.PreferencesURL(sub),
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	return m.Email.SendTemplatedEmail(sub.Email, templateName, sub.Locale, merged, m.unsubscribeHeaders(sub))
+}
+
+// unsubscribeHeaders are the RFC 8058 one-click unsubscribe headers
+func (m *Mailer) unsubscribeHeaders(sub *NewsletterSubscriber) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + m.OneClickUnsubscribeURL(sub) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
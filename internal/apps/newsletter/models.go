@@ -3,20 +3,119 @@ package newsletter
 import (
 	"time"
 
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
 	"gorm.io/gorm"
 )
 
-// NewsletterSubscriber represents a newsletter subscriber
+// NewsletterSubscriber represents a newsletter subscriber. Subscribers start
+// inactive and are activated when they confirm their address; unsubscribing
+// soft-deletes them.
 type NewsletterSubscriber struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"size:255;uniqueIndex;not null" json:"email"`
-	Active    bool           `gorm:"default:true" json:"active"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Email              string         `gorm:"size:255;uniqueIndex;not null" json:"email"`
+	Active             bool           `gorm:"default:false" json:"active"`
+	Locale             string         `gorm:"size:8;not null;default:en" json:"locale"` // email language, e.g. "fr"
+	ConfirmedAt        *time.Time     `json:"confirmed_at"`
+	ConfirmationSentAt *time.Time     `json:"-"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Associations
+	Preferences *SubscriberPreferences `gorm:"foreignKey:SubscriberID" json:"-"`
 }
 
 // TableName specifies the table name for GORM
 func (NewsletterSubscriber) TableName() string {
 	return "newsletter_subscribers"
 }
+
+// Digest frequencies a subscriber can choose
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// SubscriberPreferences narrows the events a subscriber's digest covers and
+// sets how often it's sent. Subscribers without a row get the full weekly
+// digest. Empty lists don't filter.
+type SubscriberPreferences struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	SubscriberID uint      `gorm:"uniqueIndex;not null" json:"-"`
+	Categories   []string  `gorm:"type:jsonb;default:'[]';serializer:json" json:"categories"`
+	ClubTypes    []string  `gorm:"type:jsonb;default:'[]';serializer:json" json:"club_types"`
+	Schools      []string  `gorm:"type:jsonb;default:'[]';serializer:json" json:"schools"`
+	FreeFoodOnly bool      `gorm:"default:false" json:"free_food_only"`
+	Frequency    string    `gorm:"size:16;not null;default:weekly" json:"frequency"`
+	CreatedAt    time.Time `json:"-"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (SubscriberPreferences) TableName() string {
+	return "newsletter_preferences"
+}
+
+// EventFilter is the event listing filter matching the preferences
+func (p *SubscriberPreferences) EventFilter() utils.EventFilter {
+	filter := utils.EventFilter{
+		Categories: p.Categories,
+		ClubTypes:  p.ClubTypes,
+		Schools:    p.Schools,
+	}
+	if p.FreeFoodOnly {
+		hasFood := true
+		filter.HasFood = &hasFood
+	}
+	return filter
+}
+
+// Digest run statuses stored in DigestRun.Status
+const (
+	DigestRunRunning   = "running"
+	DigestRunCompleted = "completed"
+)
+
+// DigestRun is one scheduled send of the daily or weekly digest
+type DigestRun struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Frequency    string     `gorm:"size:16;not null;default:weekly;uniqueIndex:idx_digest_runs_frequency_scheduled" json:"frequency"`
+	ScheduledFor time.Time  `gorm:"not null;uniqueIndex:idx_digest_runs_frequency_scheduled" json:"scheduled_for"`
+	Status       string     `gorm:"size:32;not null" json:"status"`
+	EventCount   int        `json:"event_count"`
+	SentCount    int        `json:"sent_count"`
+	FailedCount  int        `json:"failed_count"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (DigestRun) TableName() string {
+	return "digest_runs"
+}
+
+// Digest delivery statuses stored in DigestDelivery.Status
+const (
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+)
+
+// DigestDelivery records a run's email to one subscriber. It's claimed as
+// sending before the email goes out, so a crashed run never sends twice; a
+// delivery left sending may or may not have been delivered.
+type DigestDelivery struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RunID        uint      `gorm:"not null;uniqueIndex:idx_digest_run_subscriber" json:"run_id"`
+	SubscriberID uint      `gorm:"not null;uniqueIndex:idx_digest_run_subscriber" json:"subscriber_id"`
+	Status       string    `gorm:"size:32;not null" json:"status"`
+	Error        *string   `gorm:"type:text" json:"error"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (DigestDelivery) TableName() string {
+	return "digest_deliveries"
+}
@@ -1,18 +1,28 @@
 package newsletter
 
 import (
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// subscribeRateLimit caps sign-ups, each of which sends an email. Confirm,
+// unsubscribe and preferences need a signed token instead, and one-click unsubscribes arrive
+// from a few mail provider IPs, so they only get the global limit.
+var subscribeRateLimit = middleware.Policy{Name: "newsletter-subscribe", Limit: 5, Window: time.Hour}
+
 // RegisterRoutes registers newsletter-related routes
-func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-	handler := NewHandler(db)
+func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
 	newsletter := rg.Group("/newsletter")
 	{
-		newsletter.POST("/subscribe", handler.Subscribe)
+		newsletter.POST("/subscribe", middleware.RateLimit(opts.RateLimiter, subscribeRateLimit), handler.Subscribe)
+		newsletter.POST("/confirm", handler.ConfirmSubscription)
 		newsletter.POST("/unsubscribe", handler.Unsubscribe)
-		// TODO: Add rate limiting middleware
+		newsletter.GET("/preferences", handler.GetPreferences)
+		newsletter.PUT("/preferences", handler.UpdatePreferences)
 	}
 }
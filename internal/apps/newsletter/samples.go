@@ -0,0 +1,63 @@
+package newsletter
+
+import (
+	"sort"
+	"time"
+)
+
+// EmailSamples returns example data for previewing each newsletter email
+// template, with links to siteURL
+func EmailSamples(siteURL string) map[string]func() map[string]interface{} {
+	return map[string]func() map[string]interface{}{
+		"confirm": func() map[string]interface{} {
+			return map[string]interface{}{
+				"SiteURL":    siteURL,
+				"ConfirmURL": siteURL + "/newsletter/confirm?token=sample",
+				"Days":       int(confirmTokenTTL / (24 * time.Hour)),
+			}
+		},
+		"digest": func() map[string]interface{} {
+			return map[string]interface{}{
+				"Digest":         sampleDigest(siteURL),
+				"SiteURL":        siteURL,
+				"UnsubscribeURL": siteURL + "/newsletter/unsubscribe?token=sample",
+				"PreferencesURL": siteURL + "/newsletter/preferences?token=sample",
+			}
+		},
+	}
+}
+
+// sampleDigest is a week of made-up events starting tomorrow
+func sampleDigest(siteURL string) *Digest {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	str := func(s string) *string { return &s }
+	price := func(p float64) *float64 { return &p }
+
+	rows := []struct {
+		row      digestRow
+		interest int64
+	}{
+		{digestRow{EventID: 1, DtstartUTC: start.Add(18 * time.Hour), Title: str("Board Games Night"),
+			Location: str("SLC Great Hall"), Food: str("Pizza"), Price: price(0)}, 42},
+		{digestRow{EventID: 2, DtstartUTC: start.Add(36*time.Hour + 30*time.Minute), Title: str("Intro to Rust Workshop"),
+			Location: str("MC 4020"), Price: price(0)}, 17},
+		{digestRow{EventID: 3, DtstartUTC: start.Add(4*24*time.Hour + 20*time.Hour), Title: str("Fall Formal"),
+			Location: str("Federation Hall"), Food: str("Appetizers"), Price: price(25)}, 88},
+	}
+
+	digest := &Digest{Start: start, End: start.AddDate(0, 0, 7), EventCount: len(rows)}
+	for _, r := range rows {
+		event := newDigestEvent(r.row, start.Location(), siteURL, r.interest)
+		date := time.Date(event.Start.Year(), event.Start.Month(), event.Start.Day(), 0, 0, 0, 0, start.Location())
+		digest.Days = append(digest.Days, DigestDay{Date: date, Label: date.Format("Monday, January 2"), Events: []DigestEvent{event}})
+		if event.Food != "" {
+			digest.FreeFood = append(digest.FreeFood, event)
+		}
+		digest.Popular = append(digest.Popular, event)
+	}
+	sort.SliceStable(digest.Popular, func(i, j int) bool {
+		return digest.Popular[i].InterestCount > digest.Popular[j].InterestCount
+	})
+	return digest
+}
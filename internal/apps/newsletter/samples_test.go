@@ -0,0 +1,69 @@
+package newsletter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+)
+
+func TestEmailSamplesRender(t *testing.T) {
+	samples := EmailSamples("https://wat2do.ca")
+
+	// Every template has sample data, and every sample renders in every
+	// locale it has
+	for name, locales := range services.EmailTemplateNames() {
+		sample, ok := samples[name]
+		if !ok {
+			t.Errorf("template %s has no sample data", name)
+			continue
+		}
+		for _, locale := range locales {
+			email, err := services.RenderTemplate(name, locale, sample())
+			if err != nil {
+				t.Errorf("%s/%s: %v", name, locale, err)
+				continue
+			}
+			if email.Locale != locale || email.Subject == "" || strings.Contains(email.Subject, "&") {
+				t.Errorf("%s/%s: locale %s, subject %q", name, locale, email.Locale, email.Subject)
+			}
+			if strings.Contains(email.HTML, "<no value>") || strings.Contains(email.Text, "<no value>") {
+				t.Errorf("%s/%s renders a missing value", name, locale)
+			}
+		}
+	}
+}
+
+func TestDigestEmail(t *testing.T) {
+	data := EmailSamples("https://wat2do.ca")["digest"]()
+	unsubscribe := data["UnsubscribeURL"].(string)
+
+	for _, tt := range []struct {
+		locale  string
+		subject string
+		content []string
+	}{
+		{"en", "3 events this week on Wat2Do", []string{"Most popular", "Free food", "Fall Formal", "$25.00"}},
+		{"fr", "3 événements cette semaine sur Wat2Do", []string{"Les plus populaires", "Fall Formal", "Gratuit"}},
+	} {
+		email, err := services.RenderTemplate("digest", tt.locale, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(email.Subject, tt.subject) {
+			t.Errorf("%s subject = %q, want %s...", tt.locale, email.Subject, tt.subject)
+		}
+		for _, want := range append(tt.content, `href="`+unsubscribe+`"`, `href="https://wat2do.ca/events/3"`) {
+			if !strings.Contains(email.HTML, want) {
+				t.Errorf("%s HTML is missing %s", tt.locale, want)
+			}
+		}
+		// Both the hand-written and generated text versions link to
+		// unsubscribe and each event
+		for _, want := range []string{unsubscribe, "https://wat2do.ca/events/3"} {
+			if !strings.Contains(email.Text, want) {
+				t.Errorf("%s text is missing %s:\n%s", tt.locale, want, email.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package newsletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// digestBatchSize is how many subscribers are loaded at a time
+	digestBatchSize = 100
+
+	// missedRunGrace is how late a run may still start, e.g. after the
+	// server was down at the scheduled time; older runs are skipped
+	missedRunGrace = 12 * time.Hour
+)
+
+// WeeklySchedule is a day and time of the week in a location
+type WeeklySchedule struct {
+	Weekday  time.Weekday
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// ParseWeeklySchedule parses a schedule such as "sunday 17:00" in loc
+func ParseWeeklySchedule(spec string, loc *time.Location) (WeeklySchedule, error) {
+	day, clock, ok := strings.Cut(strings.TrimSpace(strings.ToLower(spec)), " ")
+	if !ok {
+		return WeeklySchedule{}, fmt.Errorf("schedule %q: want \"<weekday> HH:MM\"", spec)
+	}
+
+	schedule := WeeklySchedule{Weekday: -1, Location: loc}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToLower(d.String()) == day {
+			schedule.Weekday = d
+		}
+	}
+	if schedule.Weekday < 0 {
+		return WeeklySchedule{}, fmt.Errorf("schedule %q: unknown weekday %q", spec, day)
+	}
+
+	h, m, err := parseClock(clock)
+	if err != nil {
+		return WeeklySchedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+	schedule.Hour, schedule.Minute = h, m
+	return schedule, nil
+}
+
+// parseClock parses a 24-hour "HH:MM" time
+func parseClock(clock string) (int, int, error) {
+	hour, minute, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	h, herr := strconv.Atoi(hour)
+	m, merr := strconv.Atoi(minute)
+	if !ok || herr != nil || merr != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return h, m, nil
+}
+
+// Prev returns the latest scheduled time at or before t
+func (s WeeklySchedule) Prev(t time.Time) time.Time {
+	local := t.In(s.Location)
+	back := (int(local.Weekday()) - int(s.Weekday) + 7) % 7
+	// Build from the date so DST changes keep the wall-clock time
+	prev := time.Date(local.Year(), local.Month(), local.Day()-back, s.Hour, s.Minute, 0, 0, s.Location)
+	if prev.After(t) {
+		prev = prev.AddDate(0, 0, -7)
+	}
+	return prev
+}
+
+// Next returns the earliest scheduled time after t
+func (s WeeklySchedule) Next(t time.Time) time.Time {
+	return s.Prev(t).AddDate(0, 0, 7)
+}
+
+// DailySchedule is a time of day in a location
+type DailySchedule struct {
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// ParseDailySchedule parses a schedule such as "08:00" in loc
+func ParseDailySchedule(spec string, loc *time.Location) (DailySchedule, error) {
+	h, m, err := parseClock(spec)
+	if err != nil {
+		return DailySchedule{}, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+	return DailySchedule{Hour: h, Minute: m, Location: loc}, nil
+}
+
+// Prev returns the latest scheduled time at or before t
+func (s DailySchedule) Prev(t time.Time) time.Time {
+	local := t.In(s.Location)
+	prev := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, s.Minute, 0, 0, s.Location)
+	if prev.After(t) {
+		prev = prev.AddDate(0, 0, -1)
+	}
+	return prev
+}
+
+// Next returns the earliest scheduled time after t
+func (s DailySchedule) Next(t time.Time) time.Time {
+	return s.Prev(t).AddDate(0, 0, 1)
+}
+
+// schedule is a recurring digest send time
+type schedule interface {
+	Prev(t time.Time) time.Time
+	Next(t time.Time) time.Time
+}
+
+// DigestScheduler sends the digest of upcoming events to active subscribers,
+// daily or weekly as each prefers and narrowed by their preferences. Each
+// scheduled send is recorded as a DigestRun, and each email as a
+// DigestDelivery, so a run interrupted by a crash or restart resumes where it
+// stopped without emailing anyone twice.
+type DigestScheduler struct {
+	DB       *gorm.DB
+	Mailer   *Mailer
+	Schedule WeeklySchedule
+
+	// Daily is when daily digests go out; nil sends daily subscribers the
+	// weekly digest instead
+	Daily *DailySchedule
+}
+
+// schedules returns the schedule of each frequency that is sent
+func (s *DigestScheduler) schedules() map[string]schedule {
+	schedules := map[string]schedule{FrequencyWeekly: s.Schedule}
+	if s.Daily != nil {
+		schedules[FrequencyDaily] = *s.Daily
+	}
+	return schedules
+}
+
+// Run sends digests on schedule until ctx is cancelled. On start it resumes
+// or starts the most recent run of each frequency if it's within
+// missedRunGrace.
+func (s *DigestScheduler) Run(ctx context.Context) {
+	for {
+		var next time.Time
+		for frequency, sched := range s.schedules() {
+			scheduledFor := sched.Prev(time.Now())
+			if time.Since(scheduledFor) < missedRunGrace {
+				if err := s.SendRun(ctx, frequency, scheduledFor); err != nil {
+					log.Printf("%s digest run for %s failed: %v", frequency, scheduledFor.Format(time.RFC3339), err)
+				}
+			}
+			if n := sched.Next(time.Now()); next.IsZero() || n.Before(next) {
+				next = n
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// SendRun sends, or resumes sending, the frequency digest scheduled for
+// scheduledFor, covering events in the following day or week. Completed runs
+// are left alone.
+func (s *DigestScheduler) SendRun(ctx context.Context, frequency string, scheduledFor time.Time) error {
+	run, err := s.claimRun(frequency, scheduledFor)
+	if err != nil {
+		return err
+	}
+	if run.Status == DigestRunCompleted {
+		return nil
+	}
+
+	start := scheduledFor.In(s.Schedule.Location)
+	end := start.AddDate(0, 0, 7)
+	if frequency == FrequencyDaily {
+		end = start.AddDate(0, 0, 1)
+	}
+	digests := &digestCache{db: s.DB, start: start, end: end, siteURL: s.Mailer.SiteURL}
+
+	all, err := digests.For(nil)
+	if err != nil {
+		return fmt.Errorf("build digest: %w", err)
+	}
+	if all.EventCount == 0 {
+		log.Printf("No events for the %s digest scheduled for %s, skipping", frequency, start.Format(time.RFC3339))
+		return s.completeRun(run, 0)
+	}
+
+	lastID := uint(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			// Left running, so the next start resumes it
+			return err
+		}
+
+		subscribers, err := s.pendingSubscribers(run, lastID)
+		if err != nil {
+			return err
+		}
+		if len(subscribers) == 0 {
+			break
+		}
+		for i := range subscribers {
+			sub := &subscribers[i]
+			digest, err := digests.For(sub.Preferences)
+			if err != nil {
+				return fmt.Errorf("build digest for subscriber %d: %w", sub.ID, err)
+			}
+			if digest.EventCount == 0 {
+				// Nothing matches their preferences this time
+				continue
+			}
+			if err := s.deliver(run, sub, digest); err != nil {
+				return err
+			}
+		}
+		lastID = subscribers[len(subscribers)-1].ID
+	}
+
+	return s.completeRun(run, all.EventCount)
+}
+
+// digestCache builds a run's digests, once per distinct set of preferences
+type digestCache struct {
+	db         *gorm.DB
+	start, end time.Time
+	siteURL    string
+	digests    map[string]*Digest
+}
+
+// For returns the digest matching prefs, or every event if prefs is nil
+func (c *digestCache) For(prefs *SubscriberPreferences) (*Digest, error) {
+	var filter *utils.EventFilter
+	if prefs != nil {
+		f := prefs.EventFilter()
+		filter = &f
+	}
+	key, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	if digest, ok := c.digests[string(key)]; ok {
+		return digest, nil
+	}
+
+	digest, err := BuildDigest(c.db, c.start, c.end, c.siteURL, filter)
+	if err != nil {
+		return nil, err
+	}
+	if c.digests == nil {
+		c.digests = map[string]*Digest{}
+	}
+	c.digests[string(key)] = digest
+	return digest, nil
+}
+
+// claimRun creates the run for frequency and scheduledFor, or loads the
+// existing one
+func (s *DigestScheduler) claimRun(frequency string, scheduledFor time.Time) (*DigestRun, error) {
+	err := s.DB.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "frequency"}, {Name: "scheduled_for"}}, DoNothing: true}).
+		Create(&DigestRun{Frequency: frequency, ScheduledFor: scheduledFor, Status: DigestRunRunning}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var run DigestRun
+	if err := s.DB.Where("frequency = ? AND scheduled_for = ?", frequency, scheduledFor).Take(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// pendingSubscribers loads active subscribers to run's frequency after lastID
+// that the run hasn't emailed or started emailing. Failed deliveries are
+// retried.
+func (s *DigestScheduler) pendingSubscribers(run *DigestRun, lastID uint) ([]NewsletterSubscriber, error) {
+	query := s.DB.Preload("Preferences").
+		Where("active AND id > ?", lastID).
+		Where("NOT EXISTS (?)", s.DB.Model(&DigestDelivery{}).Select("1").
+			Where("digest_deliveries.subscriber_id = newsletter_subscribers.id").
+			Where("digest_deliveries.run_id = ? AND digest_deliveries.status IN ?", run.ID, []string{DeliverySending, DeliverySent}))
+	if s.Daily != nil {
+		// Without daily runs, everyone gets the weekly digest
+		frequency := s.DB.Model(&SubscriberPreferences{}).Select("frequency").
+			Where("newsletter_preferences.subscriber_id = newsletter_subscribers.id")
+		query = query.Where("COALESCE((?), ?) = ?", frequency, FrequencyWeekly, run.Frequency)
+	}
+
+	var subscribers []NewsletterSubscriber
+	err := query.Order("id").Limit(digestBatchSize).Find(&subscribers).Error
+	return subscribers, err
+}
+
+// deliver claims sub's delivery and emails them. The claim is committed
+// before sending, so if another instance holds it, or we crash mid-send, the
+// email isn't sent again.
+func (s *DigestScheduler) deliver(run *DigestRun, sub *NewsletterSubscriber, digest *Digest) error {
+	claim := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "run_id"}, {Name: "subscriber_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"status": DeliverySending, "error": nil}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: "digest_deliveries", Name: "status"}, Value: DeliveryFailed},
+		}},
+	}).Create(&DigestDelivery{RunID: run.ID, SubscriberID: sub.ID, Status: DeliverySending})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	sendErr := s.Mailer.SendTemplatedNewsletter(sub, "digest", map[string]interface{}{
+		"Digest": digest,
+	})
+
+	updates := map[string]interface{}{"status": DeliverySent}
+	if sendErr != nil {
+		log.Printf("Failed to send digest to subscriber %d: %v", sub.ID, sendErr)
+		updates = map[string]interface{}{"status": DeliveryFailed, "error": sendErr.Error()}
+	}
+	return s.DB.Model(&DigestDelivery{}).
+		Where("run_id = ? AND subscriber_id = ?", run.ID, sub.ID).
+		Updates(updates).Error
+}
+
+// completeRun marks run completed with its delivery totals
+func (s *DigestScheduler) completeRun(run *DigestRun, eventCount int) error {
+	var totals []struct {
+		Status string
+		Count  int
+	}
+	err := s.DB.Model(&DigestDelivery{}).
+		Select("status, COUNT(*) AS count").
+		Where("run_id = ?", run.ID).
+		Group("status").
+		Scan(&totals).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       DigestRunCompleted,
+		"event_count":  eventCount,
+		"completed_at": now,
+		"sent_count":   0,
+		"failed_count": 0,
+	}
+	for _, t := range totals {
+		switch t.Status {
+		case DeliverySent:
+			updates["sent_count"] = t.Count
+		case DeliveryFailed:
+			updates["failed_count"] = t.Count
+		}
+	}
+	if err := s.DB.Model(run).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	log.Printf("%s digest run %d completed: %v sent, %v failed", run.Frequency, run.ID, updates["sent_count"], updates["failed_count"])
+	return nil
+}
@@ -0,0 +1,275 @@
+package newsletter
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+)
+
+func TestWeeklySchedule(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skip(err)
+	}
+	s, err := ParseWeeklySchedule(" Sunday 17:00 ", toronto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		now, prev, next string
+	}{
+		{"2026-03-04 12:00", "2026-03-01 17:00", "2026-03-08 17:00"},
+		{"2026-03-01 17:00", "2026-03-01 17:00", "2026-03-08 17:00"},
+		{"2026-03-01 16:59", "2026-02-22 17:00", "2026-03-01 17:00"},
+		// Across the spring DST change the send stays at 17:00 local
+		{"2026-03-07 12:00", "2026-03-01 17:00", "2026-03-08 17:00"},
+		{"2026-03-09 12:00", "2026-03-08 17:00", "2026-03-15 17:00"},
+	}
+	for _, tt := range tests {
+		now, _ := time.ParseInLocation("2006-01-02 15:04", tt.now, toronto)
+		if got := s.Prev(now).Format("2006-01-02 15:04"); got != tt.prev {
+			t.Errorf("Prev(%s) = %s, want %s", tt.now, got, tt.prev)
+		}
+		if got := s.Next(now).Format("2006-01-02 15:04"); got != tt.next {
+			t.Errorf("Next(%s) = %s, want %s", tt.now, got, tt.next)
+		}
+	}
+
+	for _, spec := range []string{"sunday", "funday 17:00", "sunday 24:00", "sunday 17:60", "sunday 5pm", ""} {
+		if _, err := ParseWeeklySchedule(spec, toronto); err == nil {
+			t.Errorf("ParseWeeklySchedule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestDailySchedule(t *testing.T) {
+	s, err := ParseDailySchedule("08:30", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)
+	if got, want := s.Prev(now), time.Date(2026, 3, 3, 8, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Prev = %v, want %v", got, want)
+	}
+	if got, want := s.Next(now), time.Date(2026, 3, 4, 8, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+	if _, err := ParseDailySchedule("8", time.UTC); err == nil {
+		t.Error("ParseDailySchedule(\"8\") succeeded")
+	}
+}
+
+// scheduledFor is the weekly run the scheduler tests send
+var scheduledFor = time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC)
+
+// newTestScheduler sets up a weekly run with a food event and another event
+// that week, one outside it, and subscribers:
+//
+//	all@     weekly, no preferences
+//	food@    weekly, free food only
+//	daily@   daily
+//	off@     inactive
+func newTestScheduler(t *testing.T, daily bool) (*DigestScheduler, *recordingSender) {
+	t.Helper()
+	db := testutil.OpenDB(t, &NewsletterSubscriber{}, &SubscriberPreferences{}, &DigestRun{}, &DigestDelivery{},
+		&events.Events{}, &events.EventDates{}, &events.EventInterest{})
+
+	confirmed := events.EventStatusConfirmed
+	for i, day := range []int{1, 3, 10} {
+		title := []string{"Pizza night", "Chess club", "Next week"}[i]
+		event := &events.Events{Title: &title, Status: &confirmed}
+		if i == 0 {
+			food := "Pizza"
+			event.Food = &food
+		}
+		event.EventDates = []events.EventDates{{DtstartUTC: scheduledFor.Add(time.Duration(day) * 24 * time.Hour)}}
+		if err := db.Create(event).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, sub := range []struct {
+		email  string
+		active bool
+		prefs  *SubscriberPreferences
+	}{
+		{"all@example.com", true, nil},
+		{"food@example.com", true, &SubscriberPreferences{FreeFoodOnly: true, Frequency: FrequencyWeekly}},
+		{"daily@example.com", true, &SubscriberPreferences{Frequency: FrequencyDaily}},
+		{"off@example.com", false, nil},
+	} {
+		s := &NewsletterSubscriber{Email: sub.email, Active: sub.active, Locale: "en", Preferences: sub.prefs}
+		if err := db.Create(s).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sender := &recordingSender{}
+	scheduler := &DigestScheduler{
+		DB:       db,
+		Mailer:   &Mailer{Email: sender, Signer: NewSigner([]byte("test-key")), SiteURL: "https://example.com"},
+		Schedule: WeeklySchedule{Weekday: time.Sunday, Hour: 17, Location: time.UTC},
+	}
+	if daily {
+		scheduler.Daily = &DailySchedule{Hour: 8, Location: time.UTC}
+	}
+	return scheduler, sender
+}
+
+// run loads the scheduler's run for frequency at scheduledFor
+func run(t *testing.T, s *DigestScheduler, frequency string) DigestRun {
+	t.Helper()
+	var run DigestRun
+	if err := s.DB.Where("frequency = ? AND scheduled_for = ?", frequency, scheduledFor).Take(&run).Error; err != nil {
+		t.Fatal(err)
+	}
+	return run
+}
+
+func recipients(sender *recordingSender) string {
+	to := append([]string(nil), sender.to...)
+	sort.Strings(to)
+	return strings.Join(to, " ")
+}
+
+func TestSendRunDeliversOnce(t *testing.T) {
+	s, sender := newTestScheduler(t, true)
+
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	// Daily subscribers get the daily digest instead; preferences narrow the
+	// digest to matching events
+	if got := recipients(sender); got != "all@example.com food@example.com" {
+		t.Fatalf("sent to %s", got)
+	}
+	for i, to := range sender.to {
+		digest := sender.sent[i]["Digest"].(*Digest)
+		want := map[string]int{"all@example.com": 2, "food@example.com": 1}[to]
+		if digest.EventCount != want {
+			t.Errorf("%s got %d events, want %d", to, digest.EventCount, want)
+		}
+		if sender.sent[i]["UnsubscribeURL"] == "" {
+			t.Errorf("%s's digest has no unsubscribe link", to)
+		}
+	}
+
+	r := run(t, s, FrequencyWeekly)
+	if r.Status != DigestRunCompleted || r.EventCount != 2 || r.SentCount != 2 || r.FailedCount != 0 || r.CompletedAt == nil {
+		t.Errorf("run = %+v", r)
+	}
+
+	// Running it again, e.g. from another instance, sends nothing
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.to) != 2 {
+		t.Errorf("sent %d emails after a repeat run", len(sender.to))
+	}
+	var runs int64
+	s.DB.Model(&DigestRun{}).Count(&runs)
+	if runs != 1 {
+		t.Errorf("%d runs recorded", runs)
+	}
+}
+
+func TestSendRunWithoutDailyDigests(t *testing.T) {
+	s, sender := newTestScheduler(t, false)
+
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	if got := recipients(sender); got != "all@example.com daily@example.com food@example.com" {
+		t.Errorf("sent to %s, want every active subscriber", got)
+	}
+}
+
+func TestSendRunResumes(t *testing.T) {
+	s, sender := newTestScheduler(t, false)
+
+	// A crashed run: one email sent, one claimed mid-send and one failed
+	claimed, err := s.claimRun(FrequencyWeekly, scheduledFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for email, status := range map[string]string{
+		"all@example.com":   DeliverySent,
+		"food@example.com":  DeliverySending,
+		"daily@example.com": DeliveryFailed,
+	} {
+		var sub NewsletterSubscriber
+		s.DB.Where("email = ?", email).Take(&sub)
+		if err := s.DB.Create(&DigestDelivery{RunID: claimed.ID, SubscriberID: sub.ID, Status: status}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	// Only the failed delivery is retried; a claimed one may have gone out
+	if got := recipients(sender); got != "daily@example.com" {
+		t.Errorf("resumed run sent to %s", got)
+	}
+	if r := run(t, s, FrequencyWeekly); r.Status != DigestRunCompleted || r.SentCount != 2 || r.FailedCount != 0 {
+		t.Errorf("run = %+v", r)
+	}
+}
+
+func TestSendRunRecordsFailures(t *testing.T) {
+	s, sender := newTestScheduler(t, false)
+	sender.fail = map[string]bool{"food@example.com": true}
+
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	r := run(t, s, FrequencyWeekly)
+	if r.SentCount != 2 || r.FailedCount != 1 {
+		t.Errorf("run = %+v, want 2 sent and 1 failed", r)
+	}
+	var failed DigestDelivery
+	s.DB.Where("run_id = ? AND status = ?", r.ID, DeliveryFailed).Take(&failed)
+	if failed.Error == nil || *failed.Error != "mailbox unavailable" {
+		t.Errorf("failed delivery = %+v", failed)
+	}
+}
+
+func TestSendRunStopsWhenCancelled(t *testing.T) {
+	s, sender := newTestScheduler(t, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.SendRun(ctx, FrequencyWeekly, scheduledFor); err != context.Canceled {
+		t.Fatalf("SendRun = %v, want context.Canceled", err)
+	}
+	// Left running, so the next start resumes it
+	if r := run(t, s, FrequencyWeekly); r.Status != DigestRunRunning || len(sender.to) != 0 {
+		t.Errorf("run = %+v after sending %d", r, len(sender.to))
+	}
+	if err := s.SendRun(context.Background(), FrequencyWeekly, scheduledFor); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.to) != 3 {
+		t.Errorf("resumed run sent %d emails, want 3", len(sender.to))
+	}
+}
+
+func TestSendRunWithoutEvents(t *testing.T) {
+	s, sender := newTestScheduler(t, false)
+	empty := scheduledFor.AddDate(0, 1, 0)
+
+	if err := s.SendRun(context.Background(), FrequencyWeekly, empty); err != nil {
+		t.Fatal(err)
+	}
+	var r DigestRun
+	s.DB.Where("scheduled_for = ?", empty).Take(&r)
+	if r.Status != DigestRunCompleted || r.EventCount != 0 || len(sender.to) != 0 {
+		t.Errorf("empty run = %+v after sending %d", r, len(sender.to))
+	}
+}
@@ -0,0 +1,88 @@
+package newsletter
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+)
+
+func TestSignerTokens(t *testing.T) {
+	db := testutil.OpenDB(t, &NewsletterSubscriber{})
+	sub := &NewsletterSubscriber{Email: "reader@example.com", Active: true}
+	other := &NewsletterSubscriber{Email: "other@example.com", Active: true}
+	for _, s := range []*NewsletterSubscriber{sub, other} {
+		if err := db.Create(s).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner([]byte("test-key"))
+	signer.now = func() time.Time { return now }
+
+	confirm := signer.Token(purposeConfirm, sub, confirmTokenTTL)
+	unsubscribe := signer.Token(purposeUnsubscribe, sub, 0)
+	parts := strings.Split(confirm, ".")
+	otherSignature := strings.Split(signer.Token(purposeConfirm, other, confirmTokenTTL), ".")[2]
+
+	tests := []struct {
+		name, purpose, token string
+		ok                   bool
+	}{
+		{"confirm", purposeConfirm, confirm, true},
+		{"unsubscribe", purposeUnsubscribe, unsubscribe, true},
+		{"confirm token to unsubscribe", purposeUnsubscribe, confirm, false},
+		{"unsubscribe token to confirm", purposeConfirm, unsubscribe, false},
+		{"unsubscribe token for preferences", purposePreferences, unsubscribe, false},
+		{"another subscriber's id", purposeConfirm, strconv.FormatUint(uint64(other.ID), 10) + "." + parts[1] + "." + parts[2], false},
+		{"another subscriber's signature", purposeConfirm, parts[0] + "." + parts[1] + "." + otherSignature, false},
+		{"extended expiry", purposeConfirm, parts[0] + "." + strconv.FormatInt(now.Add(30*24*time.Hour).Unix(), 10) + "." + parts[2], false},
+		{"expiry removed", purposeConfirm, parts[0] + ".0." + parts[2], false},
+		{"other key", purposeConfirm, NewSigner([]byte("other-key")).Token(purposeConfirm, sub, confirmTokenTTL), false},
+		{"missing subscriber", purposeConfirm, "999." + parts[1] + "." + parts[2], false},
+		{"malformed", purposeConfirm, "not-a-token", false},
+		{"bad id", purposeConfirm, "x." + parts[1] + "." + parts[2], false},
+		{"bad expiry", purposeConfirm, parts[0] + ".x." + parts[2], false},
+		{"extra part", purposeConfirm, confirm + ".x", false},
+		{"empty", purposeConfirm, "", false},
+	}
+	for _, tt := range tests {
+		got, err := signer.Subscriber(db, tt.purpose, tt.token)
+		if tt.ok {
+			if err != nil || got.ID != sub.ID {
+				t.Errorf("%s: Subscriber = %v, %v; want subscriber %d", tt.name, got, err, sub.ID)
+			}
+		} else if err != errInvalidToken {
+			t.Errorf("%s: Subscriber = %v, %v; want errInvalidToken", tt.name, got, err)
+		}
+	}
+
+	// Confirmation links expire; unsubscribe links don't
+	now = now.Add(confirmTokenTTL - time.Second)
+	if _, err := signer.Subscriber(db, purposeConfirm, confirm); err != nil {
+		t.Errorf("confirm token rejected before expiry: %v", err)
+	}
+	now = now.Add(2 * time.Second)
+	if _, err := signer.Subscriber(db, purposeConfirm, confirm); err != errInvalidToken {
+		t.Errorf("expired confirm token = %v, want errInvalidToken", err)
+	}
+	now = now.Add(365 * 24 * time.Hour)
+	if _, err := signer.Subscriber(db, purposeUnsubscribe, unsubscribe); err != nil {
+		t.Errorf("unsubscribe token rejected a year on: %v", err)
+	}
+
+	// Unsubscribed subscribers are still found, so repeat clicks succeed
+	db.Delete(sub)
+	if got, err := signer.Subscriber(db, purposeUnsubscribe, unsubscribe); err != nil || !got.DeletedAt.Valid {
+		t.Errorf("unsubscribed subscriber = %v, %v", got, err)
+	}
+
+	// Tokens are tied to the address they were sent to
+	db.Unscoped().Model(sub).Update("email", "new@example.com")
+	if _, err := signer.Subscriber(db, purposeUnsubscribe, unsubscribe); err != errInvalidToken {
+		t.Errorf("token for an old address = %v, want errInvalidToken", err)
+	}
+}
@@ -1,64 +1,268 @@
 package payments
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/user_auth"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// checkoutReuseMargin is how long an existing Checkout session must have left
+// to be offered again rather than replaced
+const checkoutReuseMargin = 5 * time.Minute
+
 // Handler holds dependencies for payment handlers
 type Handler struct {
 	DB *gorm.DB
+	Options
+}
+
+// Options configures the payment handlers
+type Options struct {
+	Stripe           services.StripeClient
+	WebhookSecret    string
+	WebhookTolerance time.Duration
+	Currency         string // ISO code tickets are charged in
+	SiteURL          string // Checkout returns buyers to event pages here
 }
 
 // NewHandler creates a new payments handler
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *gorm.DB, opts Options) *Handler {
+	if opts.Currency == "" {
+		opts.Currency = "CAD"
+	}
+	return &Handler{DB: db, Options: opts}
 }
 
 // CreateCheckoutSession handles POST /api/payments/create-checkout-session
-// Creates a Stripe checkout session for payment
+// Creates a Stripe checkout session for a ticket to a paid event
 // Requires: JWT authentication
+// Body: {"event_id": 123}
 func (h *Handler) CreateCheckoutSession(c *gin.Context) {
-	// TODO: Implement Stripe checkout session creation
-	// 1. Validate authentication
-	// 2. Parse payment details from request body
-	// 3. Create Stripe checkout session
-	// 4. Create Payment record in database
-	// 5. Return session URL
+	userID := c.GetString(core.ContextUserID)
+
+	var body struct {
+		EventID uint `json:"event_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_id is required"})
+		return
+	}
+
+	var event events.Events
+	err := h.DB.Where("status = ?", events.EventStatusConfirmed).Take(&event, body.EventID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load event"})
+		return
+	}
+	if event.Price == nil || *event.Price <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event has no ticket price"})
+		return
+	}
+
+	var upcoming int64
+	err = h.DB.Model(&events.EventDates{}).
+		Where("event_id = ? AND COALESCE(dtend_utc, dtstart_utc) > ?", event.ID, time.Now()).
+		Count(&upcoming).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load event dates"})
+		return
+	}
+	if upcoming == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Event has already ended"})
+		return
+	}
+
+	var paid int64
+	err = h.DB.Model(&Payment{}).
+		Where("user_id = ? AND event_id = ? AND status = ?", userID, event.ID, PaymentStatusCompleted).
+		Count(&paid).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing payments"})
+		return
+	}
+	if paid > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a ticket for this event"})
+		return
+	}
+
+	payment, err := h.pendingPayment(userID, &event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+		return
+	}
+	if payment.StripeSessionID != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"sessionUrl": payment.Metadata["checkout_url"],
+			"sessionId":  *payment.StripeSessionID,
+			"paymentId":  payment.ID,
+		})
+		return
+	}
+
+	session, err := h.Stripe.CreateCheckoutSession(c.Request.Context(), h.checkoutParams(&payment, &event, userID))
+	if err != nil {
+		log.Printf("Failed to create Stripe checkout session for payment %d: %v", payment.ID, err)
+		if err := h.DB.Model(&payment).Update("status", PaymentStatusFailed).Error; err != nil {
+			log.Printf("Failed to mark payment %d failed: %v", payment.ID, err)
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create checkout session"})
+		return
+	}
+
+	payment.StripeSessionID = &session.ID
+	payment.Metadata = map[string]any{
+		"checkout_url":        session.URL,
+		"checkout_expires_at": session.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+	err = h.DB.Model(&payment).Select("stripe_session_id", "metadata").Updates(&payment).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save checkout session"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"sessionUrl": "",
-		"sessionId":  "",
+		"sessionUrl": session.URL,
+		"sessionId":  session.ID,
+		"paymentId":  payment.ID,
 	})
 }
 
-// HandleWebhook handles POST /api/payments/webhook
-// Handles Stripe webhook events for payment status updates
-func (h *Handler) HandleWebhook(c *gin.Context) {
-	// TODO: Implement Stripe webhook handler
-	// 1. Verify webhook signature
-	// 2. Parse webhook event
-	// 3. Update Payment record based on event type
-	// 4. Return 200 OK to acknowledge receipt
+// pendingPayment returns the user's open checkout for event, recording a new
+// pending payment if they have none. A session that has expired, or is about
+// to, is marked expired and replaced. The record is created first so its ID
+// can tie the Checkout session back to it.
+//
+// A user has at most one pending payment per event (a unique partial index),
+// so concurrent or repeated requests share one payment. A payment that has
+// no session yet is returned as is; its idempotency key makes Stripe answer
+// every request with the same session.
+func (h *Handler) pendingPayment(userID string, event *events.Events) (Payment, error) {
+	var payment Payment
+	err := h.DB.Where("user_id = ? AND event_id = ? AND status = ?", userID, event.ID, PaymentStatusPending).Take(&payment).Error
+	if err == nil {
+		if payment.StripeSessionID == nil || checkoutOpen(&payment, time.Now()) {
+			return payment, nil
+		}
+		err = h.DB.Model(&Payment{}).
+			Where("id = ? AND status = ?", payment.ID, PaymentStatusPending).
+			Update("status", PaymentStatusExpired).Error
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	if err != nil {
+		return Payment{}, err
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"received": true,
-	})
+	payment = Payment{
+		UserID:        userID,
+		EventID:       &event.ID,
+		Amount:        *event.Price,
+		Currency:      strings.ToUpper(h.Currency),
+		Status:        PaymentStatusPending,
+		PaymentMethod: "stripe",
+	}
+	result := h.DB.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "user_id"}, {Name: "event_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: openCheckoutWhere}}},
+		DoNothing:   true,
+	}).Create(&payment)
+	if result.Error != nil || result.RowsAffected == 1 {
+		return payment, result.Error
+	}
+
+	// Another request created one first
+	payment = Payment{}
+	err = h.DB.Where("user_id = ? AND event_id = ? AND status = ?", userID, event.ID, PaymentStatusPending).Take(&payment).Error
+	return payment, err
+}
+
+// checkoutOpen reports whether payment's Checkout session can still be paid,
+// with checkoutReuseMargin to spare
+func checkoutOpen(payment *Payment, now time.Time) bool {
+	expires, _ := payment.Metadata["checkout_expires_at"].(string)
+	expiresAt, err := time.Parse(time.RFC3339, expires)
+	return err == nil && now.Add(checkoutReuseMargin).Before(expiresAt)
+}
+
+// checkoutParams describes payment's Checkout session. Buyers return to the
+// event page; the redirect only signals the outcome, webhooks record it.
+func (h *Handler) checkoutParams(payment *Payment, event *events.Events, userID string) services.CheckoutSessionParams {
+	name := fmt.Sprintf("Ticket for event %d", event.ID)
+	if event.Title != nil && *event.Title != "" {
+		name = *event.Title
+	}
+	eventURL := fmt.Sprintf("%s/events/%d", strings.TrimRight(h.SiteURL, "/"), event.ID)
+	paymentID := strconv.FormatUint(uint64(payment.ID), 10)
+
+	params := services.CheckoutSessionParams{
+		ProductName:       name,
+		UnitAmount:        int64(math.Round(payment.Amount * 100)),
+		Currency:          payment.Currency,
+		SuccessURL:        eventURL + "?checkout=success&session_id={CHECKOUT_SESSION_ID}",
+		CancelURL:         eventURL + "?checkout=cancelled",
+		ClientReferenceID: paymentID,
+		Metadata: map[string]string{
+			"payment_id": paymentID,
+			"event_id":   strconv.FormatUint(uint64(event.ID), 10),
+			"user_id":    userID,
+		},
+		IdempotencyKey: "checkout-payment-" + paymentID,
+	}
+
+	var user user_auth.User
+	if err := h.DB.Select("email").Where("clerk_id = ?", userID).Take(&user).Error; err == nil {
+		params.CustomerEmail = user.Email
+	}
+	return params
 }
 
 // GetPaymentStatus handles GET /api/payments/:id/status
 // Get payment status by ID
-// Requires: JWT authentication
+// Requires: JWT authentication; only the payment's owner can see it
 func (h *Handler) GetPaymentStatus(c *gin.Context) {
-	// TODO: Implement payment status retrieval
-	// 1. Parse payment ID from URL
-	// 2. Validate user owns this payment
-	// 3. Query payment record
-	// 4. Return payment status
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	var payment Payment
+	// Other users' payments are reported as missing rather than forbidden,
+	// so IDs can't be probed
+	err = h.DB.Where("user_id = ?", c.GetString(core.ContextUserID)).Take(&payment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load payment"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status": "pending",
+		"id":         payment.ID,
+		"status":     payment.Status,
+		"amount":     payment.Amount,
+		"currency":   payment.Currency,
+		"event_id":   payment.EventID,
+		"created_at": payment.CreatedAt,
+		"updated_at": payment.UpdatedAt,
 	})
 }
@@ -0,0 +1,263 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/user_auth"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStripe is a StripeClient that records the sessions asked for and
+// answers with a numbered session, or err
+type fakeStripe struct {
+	err error
+
+	mu     sync.Mutex
+	params []services.CheckoutSessionParams
+}
+
+func (s *fakeStripe) CreateCheckoutSession(ctx context.Context, params services.CheckoutSessionParams) (*services.CheckoutSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = append(s.params, params)
+	if s.err != nil {
+		return nil, s.err
+	}
+	id := "cs_test_" + params.ClientReferenceID
+	return &services.CheckoutSession{
+		ID:        id,
+		URL:       "https://checkout.stripe.test/" + id,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, nil
+}
+
+// newTestHandler opens a database holding one confirmed $15 event with an
+// upcoming date, and returns a handler using stripe
+func newTestHandler(t *testing.T, stripe services.StripeClient) (*Handler, *events.Events) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.OpenDB(t, &events.Events{}, &events.EventDates{}, &user_auth.User{}, &Payment{}, &StripeWebhookEvent{})
+
+	title, status, price := "Charity Gala", events.EventStatusConfirmed, 15.0
+	event := &events.Events{Title: &title, Status: &status, Price: &price}
+	if err := db.Create(event).Error; err != nil {
+		t.Fatal(err)
+	}
+	date := events.EventDates{EventID: event.ID, DtstartUTC: time.Now().Add(48 * time.Hour)}
+	if err := db.Create(&date).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&user_auth.User{ClerkID: "user_1", Email: "buyer@example.com"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHandler(db, Options{
+		Stripe:           stripe,
+		WebhookSecret:    testWebhookSecret,
+		WebhookTolerance: 5 * time.Minute,
+		SiteURL:          "https://example.com/",
+	}), event
+}
+
+func createCheckout(h *Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/payments/create-checkout-session", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set(core.ContextUserID, "user_1")
+	h.CreateCheckoutSession(c)
+	return w
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	stripe := &fakeStripe{}
+	h, event := newTestHandler(t, stripe)
+
+	w := createCheckout(h, `{"event_id": 1}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var response struct {
+		SessionURL string `json:"sessionUrl"`
+		SessionID  string `json:"sessionId"`
+		PaymentID  uint   `json:"paymentId"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.SessionID != "cs_test_1" || response.PaymentID != 1 {
+		t.Errorf("response = %+v", response)
+	}
+
+	params := stripe.params[0]
+	if params.UnitAmount != 1500 || params.Currency != "CAD" || params.ProductName != "Charity Gala" {
+		t.Errorf("params = %+v, want a CAD 15.00 Charity Gala ticket", params)
+	}
+	if params.CustomerEmail != "buyer@example.com" || params.IdempotencyKey != "checkout-payment-1" {
+		t.Errorf("params = %+v, want the buyer's email and the payment's idempotency key", params)
+	}
+	if params.CancelURL != "https://example.com/events/1?checkout=cancelled" {
+		t.Errorf("cancel URL = %q", params.CancelURL)
+	}
+
+	var payment Payment
+	if err := h.DB.First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != PaymentStatusPending || *payment.EventID != event.ID || *payment.StripeSessionID != "cs_test_1" {
+		t.Errorf("payment = %+v, want pending with the session", payment)
+	}
+	if payment.Metadata["checkout_url"] != response.SessionURL {
+		t.Errorf("metadata = %v", payment.Metadata)
+	}
+}
+
+// checkoutResponse decodes a successful CreateCheckoutSession response
+func checkoutResponse(t *testing.T, w *httptest.ResponseRecorder) (sessionID string, paymentID uint) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var response struct {
+		SessionID string `json:"sessionId"`
+		PaymentID uint   `json:"paymentId"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response.SessionID, response.PaymentID
+}
+
+func TestCreateCheckoutSessionReusesOpenSession(t *testing.T) {
+	stripe := &fakeStripe{}
+	h, _ := newTestHandler(t, stripe)
+
+	firstSession, firstPayment := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`))
+	session, payment := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`))
+	if session != firstSession || payment != firstPayment {
+		t.Errorf("second checkout = %s/%d, want the open %s/%d", session, payment, firstSession, firstPayment)
+	}
+	if len(stripe.params) != 1 {
+		t.Errorf("created %d Stripe sessions, want 1", len(stripe.params))
+	}
+
+	// The index allows one pending payment per user and event
+	eventID := uint(1)
+	duplicate := Payment{UserID: "user_1", EventID: &eventID, Amount: 15, Status: PaymentStatusPending}
+	if err := h.DB.Create(&duplicate).Error; err == nil {
+		t.Error("created a second pending payment for the event")
+	}
+}
+
+func TestCreateCheckoutSessionReplacesExpiredSession(t *testing.T) {
+	stripe := &fakeStripe{}
+	h, _ := newTestHandler(t, stripe)
+
+	_, firstPayment := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`))
+	// Too close to expiring to send a buyer to
+	err := h.DB.Model(&Payment{ID: firstPayment}).Select("metadata").Updates(&Payment{Metadata: map[string]any{
+		"checkout_url":        "https://checkout.stripe.test/cs_test_1",
+		"checkout_expires_at": time.Now().Add(time.Minute).UTC().Format(time.RFC3339),
+	}}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, payment := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`))
+	if payment == firstPayment || session == "cs_test_1" {
+		t.Fatalf("checkout = %s/%d, want a new session", session, payment)
+	}
+	if got := reload(t, h, &Payment{ID: firstPayment}); got.Status != PaymentStatusExpired {
+		t.Errorf("replaced payment status = %q, want expired", got.Status)
+	}
+	if len(stripe.params) != 2 || stripe.params[1].IdempotencyKey == stripe.params[0].IdempotencyKey {
+		t.Errorf("Stripe calls = %+v, want a second session under a new idempotency key", stripe.params)
+	}
+}
+
+func TestCreateCheckoutSessionJoinsInFlightCheckout(t *testing.T) {
+	stripe := &fakeStripe{}
+	h, _ := newTestHandler(t, stripe)
+
+	// Another request recorded the payment and is waiting on Stripe
+	eventID := uint(1)
+	inFlight := Payment{UserID: "user_1", EventID: &eventID, Amount: 15, Currency: "CAD", Status: PaymentStatusPending}
+	if err := h.DB.Create(&inFlight).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	session, payment := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`))
+	if payment != inFlight.ID || session != "cs_test_1" {
+		t.Errorf("checkout = %s/%d, want payment %d's session", session, payment, inFlight.ID)
+	}
+	// Both requests ask Stripe under the payment's key, so they get one session
+	if key := stripe.params[0].IdempotencyKey; key != "checkout-payment-1" {
+		t.Errorf("idempotency key = %q", key)
+	}
+	var pending int64
+	h.DB.Model(&Payment{}).Where("status = ?", PaymentStatusPending).Count(&pending)
+	if pending != 1 {
+		t.Errorf("pending payments = %d, want 1", pending)
+	}
+}
+
+func TestCreateCheckoutSessionStripeFailure(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeStripe{err: errors.New("stripe response: status 500")})
+
+	if w := createCheckout(h, `{"event_id": 1}`); w.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", w.Code)
+	}
+	var payment Payment
+	if err := h.DB.First(&payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != PaymentStatusFailed || payment.StripeSessionID != nil {
+		t.Errorf("payment = %+v, want failed without a session", payment)
+	}
+
+	// The failed payment doesn't hold up a retry
+	h.Stripe = &fakeStripe{}
+	if _, retry := checkoutResponse(t, createCheckout(h, `{"event_id": 1}`)); retry == payment.ID {
+		t.Errorf("retry reused failed payment %d", retry)
+	}
+}
+
+func TestCreateCheckoutSessionRejectsUnpayableEvents(t *testing.T) {
+	stripe := &fakeStripe{}
+	h, event := newTestHandler(t, stripe)
+
+	if w := createCheckout(h, `{"event_id": 99}`); w.Code != http.StatusNotFound {
+		t.Errorf("missing event = %d, want 404", w.Code)
+	}
+	if w := createCheckout(h, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("no event_id = %d, want 400", w.Code)
+	}
+
+	paid := Payment{UserID: "user_1", EventID: &event.ID, Amount: 15, Status: PaymentStatusCompleted}
+	if err := h.DB.Create(&paid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := createCheckout(h, `{"event_id": 1}`); w.Code != http.StatusConflict {
+		t.Errorf("already paid = %d, want 409", w.Code)
+	}
+
+	h.DB.Model(&events.EventDates{}).Where("event_id = ?", event.ID).Update("dtstart_utc", time.Now().Add(-48*time.Hour))
+	if w := createCheckout(h, `{"event_id": 1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("past event = %d, want 400", w.Code)
+	}
+	if len(stripe.params) != 0 {
+		t.Errorf("Stripe was asked for %d sessions, want none", len(stripe.params))
+	}
+}
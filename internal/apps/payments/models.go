@@ -6,17 +6,31 @@ import (
 	"gorm.io/gorm"
 )
 
+// Payment statuses stored in Payment.Status
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusCompleted = "completed"
+	PaymentStatusExpired   = "expired"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+)
+
+// openCheckoutWhere matches the payments idx_payments_open_checkout covers:
+// a user has at most one pending payment per event
+const openCheckoutWhere = "status = 'pending' AND deleted_at IS NULL"
+
 // Payment represents a payment transaction
 type Payment struct {
 	ID              uint           `gorm:"primaryKey" json:"id"`
-	UserID          string         `gorm:"size:255;index;not null" json:"user_id"`
+	UserID          string         `gorm:"size:255;index;uniqueIndex:idx_payments_open_checkout,where:status = 'pending' AND deleted_at IS NULL;not null" json:"user_id"`
+	EventID         *uint          `gorm:"index;uniqueIndex:idx_payments_open_checkout" json:"event_id"`
 	Amount          float64        `gorm:"not null" json:"amount"`
 	Currency        string         `gorm:"size:3;default:'CAD'" json:"currency"`
-	Status          string         `gorm:"size:32;not null" json:"status"` // pending, completed, failed, refunded
+	Status          string         `gorm:"size:32;not null" json:"status"` // pending, completed, expired, failed, refunded
 	PaymentMethod   string         `gorm:"size:64" json:"payment_method"`
-	TransactionID   *string        `gorm:"size:255;uniqueIndex" json:"transaction_id"`
-	StripeSessionID *string        `gorm:"size:255" json:"stripe_session_id"`
-	Metadata        map[string]any `gorm:"type:jsonb" json:"metadata"`
+	TransactionID   *string        `gorm:"size:255;uniqueIndex" json:"transaction_id"` // Stripe payment intent ID
+	StripeSessionID *string        `gorm:"size:255;uniqueIndex" json:"stripe_session_id"`
+	Metadata        map[string]any `gorm:"type:jsonb;serializer:json" json:"metadata"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
@@ -26,3 +40,17 @@ type Payment struct {
 func (Payment) TableName() string {
 	return "payments"
 }
+
+// StripeWebhookEvent records a processed Stripe webhook by event ID, so
+// redeliveries are acknowledged without being applied twice
+type StripeWebhookEvent struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	StripeEventID string    `gorm:"size:255;uniqueIndex;not null" json:"stripe_event_id"`
+	Type          string    `gorm:"size:64;not null" json:"type"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (StripeWebhookEvent) TableName() string {
+	return "stripe_webhook_events"
+}
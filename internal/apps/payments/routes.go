@@ -1,19 +1,19 @@
 package payments
 
 import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // RegisterRoutes registers payment-related routes
-func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-	handler := NewHandler(db)
+func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
 	payments := rg.Group("/payments")
 	{
-		payments.POST("/create-checkout-session", handler.CreateCheckoutSession)
+		payments.POST("/create-checkout-session", core.JWTRequired(), handler.CreateCheckoutSession)
 		payments.POST("/webhook", handler.HandleWebhook)
-		payments.GET("/:id/status", handler.GetPaymentStatus)
-		// TODO: Add JWT authentication middleware for protected routes
+		payments.GET("/:id/status", core.JWTRequired(), handler.GetPaymentStatus)
 	}
 }
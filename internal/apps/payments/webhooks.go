@@ -0,0 +1,188 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWebhookSize bounds the webhook body read into memory
+const maxWebhookSize = 1 << 20
+
+// checkoutSession is the data.object of checkout.session.* events
+type checkoutSession struct {
+	ID            string  `json:"id"`
+	PaymentStatus string  `json:"payment_status"` // paid, unpaid, no_payment_required
+	PaymentIntent *string `json:"payment_intent"`
+}
+
+// charge is the data.object of charge.* events
+type charge struct {
+	PaymentIntent  *string `json:"payment_intent"`
+	Amount         int64   `json:"amount"`
+	AmountRefunded int64   `json:"amount_refunded"`
+	Refunded       bool    `json:"refunded"` // fully refunded
+}
+
+// HandleWebhook handles POST /api/payments/webhook
+// Handles Stripe webhook events for payment status updates. Events are
+// verified with the Stripe-Signature header and applied once per event ID.
+func (h *Handler) HandleWebhook(c *gin.Context) {
+	if h.WebhookSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Stripe webhooks are not configured"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(payload) > maxWebhookSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Webhook body too large"})
+		return
+	}
+
+	event, err := services.ConstructStripeEvent(payload, c.GetHeader("Stripe-Signature"), h.WebhookSecret, h.WebhookTolerance)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+	if event.ID == "" || event.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	applied, err := h.applyStripeEvent(event)
+	if err != nil {
+		log.Printf("Failed to apply Stripe %s event %s: %v", event.Type, event.ID, err)
+		// A non-2xx response makes Stripe retry the delivery
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"received":  true,
+		"duplicate": !applied,
+	})
+}
+
+// applyStripeEvent records the event and applies it in one transaction, so
+// an event is either applied and recorded or neither. It reports false for
+// events that were already applied.
+func (h *Handler) applyStripeEvent(event *services.StripeEvent) (bool, error) {
+	applied := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&StripeWebhookEvent{StripeEventID: event.ID, Type: event.Type})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+
+		switch event.Type {
+		case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+			return completeCheckout(tx, event)
+		case "checkout.session.expired", "checkout.session.async_payment_failed":
+			return failCheckout(tx, event)
+		case "charge.refunded":
+			return refundCharge(tx, event)
+		default:
+			// Other events are acknowledged so Stripe stops retrying them
+			return nil
+		}
+	})
+	return applied, err
+}
+
+// completeCheckout marks a pending payment completed once Stripe reports the
+// session paid; delayed methods complete later via async_payment_succeeded
+func completeCheckout(tx *gorm.DB, event *services.StripeEvent) error {
+	var session checkoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		return err
+	}
+	if session.PaymentStatus != "paid" {
+		return nil
+	}
+
+	result := tx.Model(&Payment{}).
+		Where("stripe_session_id = ? AND status IN ?", session.ID, []string{PaymentStatusPending, PaymentStatusExpired}).
+		Updates(map[string]interface{}{
+			"status":         PaymentStatusCompleted,
+			"transaction_id": session.PaymentIntent,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		logUnmatched(tx, event, "stripe_session_id = ?", session.ID)
+	}
+	return nil
+}
+
+// failCheckout marks a pending payment expired or failed
+func failCheckout(tx *gorm.DB, event *services.StripeEvent) error {
+	var session checkoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		return err
+	}
+
+	status := PaymentStatusExpired
+	if event.Type == "checkout.session.async_payment_failed" {
+		status = PaymentStatusFailed
+	}
+	return tx.Model(&Payment{}).
+		Where("stripe_session_id = ? AND status = ?", session.ID, PaymentStatusPending).
+		Update("status", status).Error
+}
+
+// refundCharge marks a completed payment refunded once its charge is fully
+// refunded; partial refunds are only recorded in the payment's metadata
+func refundCharge(tx *gorm.DB, event *services.StripeEvent) error {
+	var ch charge
+	if err := json.Unmarshal(event.Data.Object, &ch); err != nil {
+		return err
+	}
+	if ch.PaymentIntent == nil {
+		return nil
+	}
+
+	var payment Payment
+	err := tx.Where("transaction_id = ?", *ch.PaymentIntent).Take(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logUnmatched(tx, event, "transaction_id = ?", *ch.PaymentIntent)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if payment.Metadata == nil {
+		payment.Metadata = map[string]any{}
+	}
+	payment.Metadata["amount_refunded"] = float64(ch.AmountRefunded) / 100
+	if ch.Refunded && payment.Status == PaymentStatusCompleted {
+		payment.Status = PaymentStatusRefunded
+	}
+	return tx.Model(&payment).Select("status", "metadata").Updates(&payment).Error
+}
+
+// logUnmatched notes events for payments we don't know, such as ones made
+// outside the app on the same Stripe account, or already past the transition
+func logUnmatched(tx *gorm.DB, event *services.StripeEvent, query string, arg string) {
+	var count int64
+	if tx.Model(&Payment{}).Where(query, arg).Count(&count); count == 0 {
+		log.Printf("Stripe %s event %s matches no payment", event.Type, event.ID)
+	}
+}
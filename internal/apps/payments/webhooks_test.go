@@ -0,0 +1,191 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func stripeEvent(t *testing.T, id, eventType string, object interface{}) *services.StripeEvent {
+	t.Helper()
+	data, err := json.Marshal(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := &services.StripeEvent{ID: id, Type: eventType}
+	event.Data.Object = data
+	return event
+}
+
+func applyEvent(t *testing.T, h *Handler, event *services.StripeEvent) bool {
+	t.Helper()
+	applied, err := h.applyStripeEvent(event)
+	if err != nil {
+		t.Fatalf("applyStripeEvent(%s): %v", event.ID, err)
+	}
+	return applied
+}
+
+// pendingPayment records a payment waiting on Checkout session sessionID
+func pendingPayment(t *testing.T, h *Handler, sessionID string) *Payment {
+	t.Helper()
+	payment := &Payment{UserID: "user_1", Amount: 15, Status: PaymentStatusPending, StripeSessionID: &sessionID}
+	if err := h.DB.Create(payment).Error; err != nil {
+		t.Fatal(err)
+	}
+	return payment
+}
+
+func reload(t *testing.T, h *Handler, payment *Payment) Payment {
+	t.Helper()
+	var current Payment
+	if err := h.DB.First(&current, payment.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return current
+}
+
+func TestApplyStripeEventIsIdempotent(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeStripe{})
+	payment := pendingPayment(t, h, "cs_1")
+
+	completed := stripeEvent(t, "evt_1", "checkout.session.completed", map[string]interface{}{
+		"id": "cs_1", "payment_status": "paid", "payment_intent": "pi_1",
+	})
+	if !applyEvent(t, h, completed) {
+		t.Fatal("first delivery was not applied")
+	}
+
+	// A moderator or later event moves the payment on; a redelivery of the
+	// first event must not move it back
+	h.DB.Model(payment).Update("status", PaymentStatusRefunded)
+	if applyEvent(t, h, completed) {
+		t.Error("redelivery was applied again")
+	}
+	if got := reload(t, h, payment); got.Status != PaymentStatusRefunded {
+		t.Errorf("status after redelivery = %q, want refunded", got.Status)
+	}
+
+	var recorded int64
+	h.DB.Model(&StripeWebhookEvent{}).Where("stripe_event_id = ?", "evt_1").Count(&recorded)
+	if recorded != 1 {
+		t.Errorf("recorded events = %d, want 1", recorded)
+	}
+}
+
+func TestApplyStripeEventTransitions(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeStripe{})
+	payment := pendingPayment(t, h, "cs_1")
+
+	// An unpaid completion (a delayed payment method) leaves it pending
+	applyEvent(t, h, stripeEvent(t, "evt_1", "checkout.session.completed", map[string]interface{}{
+		"id": "cs_1", "payment_status": "unpaid", "payment_intent": "pi_1",
+	}))
+	if got := reload(t, h, payment); got.Status != PaymentStatusPending {
+		t.Fatalf("status after unpaid completion = %q, want pending", got.Status)
+	}
+
+	applyEvent(t, h, stripeEvent(t, "evt_2", "checkout.session.async_payment_succeeded", map[string]interface{}{
+		"id": "cs_1", "payment_status": "paid", "payment_intent": "pi_1",
+	}))
+	got := reload(t, h, payment)
+	if got.Status != PaymentStatusCompleted || got.TransactionID == nil || *got.TransactionID != "pi_1" {
+		t.Fatalf("payment after async success = %+v, want completed with pi_1", got)
+	}
+
+	// A late expiry doesn't undo a completed payment
+	applyEvent(t, h, stripeEvent(t, "evt_3", "checkout.session.expired", map[string]interface{}{"id": "cs_1"}))
+	if got := reload(t, h, payment); got.Status != PaymentStatusCompleted {
+		t.Fatalf("status after late expiry = %q, want completed", got.Status)
+	}
+
+	// A partial refund is only noted
+	applyEvent(t, h, stripeEvent(t, "evt_4", "charge.refunded", map[string]interface{}{
+		"payment_intent": "pi_1", "amount": 1500, "amount_refunded": 500, "refunded": false,
+	}))
+	got = reload(t, h, payment)
+	if got.Status != PaymentStatusCompleted || got.Metadata["amount_refunded"] != 5.0 {
+		t.Fatalf("payment after partial refund = %+v, want completed with 5.00 refunded", got)
+	}
+
+	applyEvent(t, h, stripeEvent(t, "evt_5", "charge.refunded", map[string]interface{}{
+		"payment_intent": "pi_1", "amount": 1500, "amount_refunded": 1500, "refunded": true,
+	}))
+	got = reload(t, h, payment)
+	if got.Status != PaymentStatusRefunded || got.Metadata["amount_refunded"] != 15.0 {
+		t.Errorf("payment after full refund = %+v, want refunded with 15.00 refunded", got)
+	}
+}
+
+func TestApplyStripeEventFailures(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeStripe{})
+	expired := pendingPayment(t, h, "cs_1")
+	failed := pendingPayment(t, h, "cs_2")
+
+	applyEvent(t, h, stripeEvent(t, "evt_1", "checkout.session.expired", map[string]interface{}{"id": "cs_1"}))
+	applyEvent(t, h, stripeEvent(t, "evt_2", "checkout.session.async_payment_failed", map[string]interface{}{"id": "cs_2"}))
+	if got := reload(t, h, expired); got.Status != PaymentStatusExpired {
+		t.Errorf("expired session's payment = %q, want expired", got.Status)
+	}
+	if got := reload(t, h, failed); got.Status != PaymentStatusFailed {
+		t.Errorf("failed session's payment = %q, want failed", got.Status)
+	}
+
+	// Unknown payments and event types are acknowledged
+	if !applyEvent(t, h, stripeEvent(t, "evt_3", "charge.refunded", map[string]interface{}{"payment_intent": "pi_unknown", "refunded": true})) {
+		t.Error("refund of an unknown payment was not recorded")
+	}
+	if !applyEvent(t, h, stripeEvent(t, "evt_4", "customer.created", map[string]interface{}{"id": "cus_1"})) {
+		t.Error("unhandled event type was not recorded")
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	h, _ := newTestHandler(t, &fakeStripe{})
+	payment := pendingPayment(t, h, "cs_1")
+	payload := `{"id": "evt_1", "type": "checkout.session.completed", "data": {"object": {"id": "cs_1", "payment_status": "paid", "payment_intent": "pi_1"}}}`
+
+	deliver := func(signature string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/payments/webhook", strings.NewReader(payload))
+		req.Header.Set("Stripe-Signature", signature)
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		h.HandleWebhook(c)
+		return w
+	}
+
+	timestamp := fmt.Sprint(time.Now().Unix())
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "." + payload))
+	signature := "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if w := deliver("t=" + timestamp + ",v1=00"); w.Code != http.StatusBadRequest {
+		t.Fatalf("forged delivery = %d, want 400", w.Code)
+	}
+	if got := reload(t, h, payment); got.Status != PaymentStatusPending {
+		t.Fatalf("forged delivery changed the payment to %q", got.Status)
+	}
+
+	for i, want := range []string{`"duplicate":false`, `"duplicate":true`} {
+		w := deliver(signature)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("delivery %d = %d %s, want 200 with %s", i+1, w.Code, w.Body, want)
+		}
+	}
+	if got := reload(t, h, payment); got.Status != PaymentStatusCompleted {
+		t.Errorf("status = %q, want completed", got.Status)
+	}
+}
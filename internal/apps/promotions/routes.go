@@ -1,6 +1,7 @@
 package promotions
 
 import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -12,9 +13,8 @@ func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	promotions := rg.Group("/promotions")
 	{
 		promotions.GET("/", handler.GetPromotions)
-		promotions.POST("/", handler.CreatePromotion)
-		promotions.PUT("/:id", handler.UpdatePromotion)
-		promotions.DELETE("/:id", handler.DeletePromotion)
-		// TODO: Add admin authentication middleware for protected routes
+		promotions.POST("/", core.JWTRequired(), core.AdminRequired(), handler.CreatePromotion)
+		promotions.PUT("/:id", core.JWTRequired(), core.AdminRequired(), handler.UpdatePromotion)
+		promotions.DELETE("/:id", core.JWTRequired(), core.AdminRequired(), handler.DeletePromotion)
 	}
 }
@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Broker carries published messages to the hubs of every server instance
+type Broker interface {
+	// Publish stamps msg with the next ID and sends it to all subscribers
+	Publish(ctx context.Context, msg Message) error
+
+	// Subscribe calls deliver, in order, with every message published
+	// through the broker by any instance until stop is called
+	Subscribe(deliver func(Message)) (stop func(), err error)
+}
+
+// MemoryBroker is a Broker within one process. Hubs sharing a MemoryBroker
+// behave like instances sharing a Redis broker, which is handy in tests.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	nextKey     int
+	subscribers map[int]func(Message)
+}
+
+// NewMemoryBroker creates an in-process broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: map[int]func(Message){}}
+}
+
+// Publish delivers msg to every subscriber before returning
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	// Deliver under the lock so every subscriber sees messages in ID order
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	msg.ID = b.lastID
+	for _, deliver := range b.subscribers {
+		deliver(msg)
+	}
+	return nil
+}
+
+// Subscribe registers deliver
+func (b *MemoryBroker) Subscribe(deliver func(Message)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := b.nextKey
+	b.nextKey++
+	b.subscribers[key] = deliver
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, key)
+	}, nil
+}
+
+// Redis keys used by RedisBroker
+const (
+	redisChannel     = "wat2do:realtime"
+	redisSequenceKey = "wat2do:realtime:seq"
+)
+
+// RedisBroker is a Broker over Redis pub/sub. Message IDs come from a shared
+// counter, so a client can resume with Last-Event-ID on any instance
+// (concurrent publishers' messages may arrive slightly out of ID order).
+// Pub/sub doesn't store messages: an instance that loses its connection
+// misses what's published until go-redis reconnects.
+type RedisBroker struct {
+	client *redis.Client
+}
+
+// NewRedisBroker connects to the Redis server at addr, given as host:port or
+// a redis:// URL
+func NewRedisBroker(ctx context.Context, addr string) (*RedisBroker, error) {
+	opts := &redis.Options{Addr: addr}
+	if strings.Contains(addr, "://") {
+		parsed, err := redis.ParseURL(addr)
+		if err != nil {
+			return nil, fmt.Errorf("parse redis url: %w", err)
+		}
+		opts = parsed
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	return &RedisBroker{client: client}, nil
+}
+
+// Publish stamps msg from the shared counter and publishes it
+func (b *RedisBroker) Publish(ctx context.Context, msg Message) error {
+	id, err := b.client.Incr(ctx, redisSequenceKey).Result()
+	if err != nil {
+		return err
+	}
+	msg.ID = uint64(id)
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, redisChannel, payload).Err()
+}
+
+// Subscribe subscribes to the channel and delivers messages from a
+// background goroutine
+func (b *RedisBroker) Subscribe(deliver func(Message)) (func(), error) {
+	ctx := context.Background()
+	pubsub := b.client.Subscribe(ctx, redisChannel)
+	// Wait for the subscription so nothing published after we return is lost
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("subscribe to %s: %w", redisChannel, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for payload := range pubsub.Channel() {
+			var msg Message
+			if err := json.Unmarshal([]byte(payload.Payload), &msg); err != nil {
+				log.Printf("realtime: dropping malformed message: %v", err)
+				continue
+			}
+			deliver(msg)
+		}
+	}()
+
+	return func() {
+		pubsub.Close()
+		<-done
+	}, nil
+}
+
+// Close closes the Redis connection
+func (b *RedisBroker) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// receive waits for the next message on sub
+func receive(t *testing.T, sub *Subscription) Message {
+	t.Helper()
+	select {
+	case msg, ok := <-sub.C:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return Message{}
+}
+
+// expectNone checks nothing arrives on sub for a short while
+func expectNone(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case msg := <-sub.C:
+		t.Fatalf("unexpected message %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newTestHub(t *testing.T, broker Broker) *Hub {
+	t.Helper()
+	hub, err := NewHub(broker)
+	if err != nil {
+		t.Fatalf("NewHub: %v", err)
+	}
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+func TestMemoryBrokerFansOutAcrossHubs(t *testing.T) {
+	broker := NewMemoryBroker()
+	a, b := newTestHub(t, broker), newTestHub(t, broker)
+
+	onA := a.Subscribe(clientBuffer, TopicEvents)
+	onB := b.Subscribe(clientBuffer, TopicEvents)
+	moderators := b.Subscribe(clientBuffer, TopicModeration)
+
+	a.Publish("event.created", map[string]int{"event_id": 1}, TopicEvents, EventTopic(1))
+
+	for _, sub := range []*Subscription{onA, onB} {
+		msg := receive(t, sub)
+		if msg.ID != 1 || msg.Type != "event.created" {
+			t.Errorf("message = %+v, want event.created with ID 1", msg)
+		}
+	}
+	expectNone(t, moderators)
+
+	// Messages from either hub share one ID sequence, so a client can resume
+	// on a different instance
+	b.Publish("event.updated", nil, TopicEvents)
+	a.Publish("event.pending", nil, TopicModeration)
+	if msg := receive(t, onA); msg.ID != 2 {
+		t.Errorf("second message ID = %d, want 2", msg.ID)
+	}
+	if msg := receive(t, moderators); msg.ID != 3 {
+		t.Errorf("moderation message ID = %d, want 3", msg.ID)
+	}
+
+	sub, missed, complete := b.SubscribeFrom(clientBuffer, 1, TopicEvents)
+	defer b.Unsubscribe(sub)
+	if !complete || len(missed) != 1 || missed[0].ID != 2 {
+		t.Errorf("SubscribeFrom(1) = %+v, complete %v; want message 2 only", missed, complete)
+	}
+}
+
+func newTestRedisBroker(t *testing.T, addr string) *RedisBroker {
+	t.Helper()
+	broker, err := NewRedisBroker(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("NewRedisBroker: %v", err)
+	}
+	t.Cleanup(func() { broker.Close() })
+	return broker
+}
+
+func TestRedisBrokerFansOutAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	a := newTestHub(t, newTestRedisBroker(t, server.Addr()))
+	b := newTestHub(t, newTestRedisBroker(t, "redis://"+server.Addr()+"/0"))
+
+	onB := b.Subscribe(clientBuffer, EventTopic(7))
+	a.Publish("event.reactions", map[string]interface{}{"event_id": 7}, TopicEvents, EventTopic(7))
+
+	msg := receive(t, onB)
+	if msg.ID != 1 || msg.Type != "event.reactions" || len(msg.Topics) != 2 {
+		t.Errorf("message = %+v, want event.reactions with ID 1", msg)
+	}
+	if data, _ := msg.Data.(map[string]interface{}); data["event_id"] != float64(7) {
+		t.Errorf("data = %v", msg.Data)
+	}
+
+	b.Publish("event.updated", nil, TopicEvents, EventTopic(7))
+	if msg := receive(t, onB); msg.ID != 2 {
+		t.Errorf("second message ID = %d, want 2 from the shared counter", msg.ID)
+	}
+}
+
+func TestRedisBrokerResubscribesAfterReconnect(t *testing.T) {
+	server := miniredis.RunT(t)
+	publisher := newTestHub(t, newTestRedisBroker(t, server.Addr()))
+	subscriber := newTestHub(t, newTestRedisBroker(t, server.Addr()))
+	sub := subscriber.Subscribe(clientBuffer, TopicEvents)
+
+	publisher.Publish("before", nil, TopicEvents)
+	if msg := receive(t, sub); msg.Type != "before" {
+		t.Fatalf("message = %+v, want before", msg)
+	}
+
+	// Drop every connection, as a Redis restart or failover would
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatalf("restart redis: %v", err)
+	}
+
+	// Pub/sub doesn't queue for disconnected subscribers, so keep publishing
+	// until the subscriber's connection is back
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		publisher.Publish("after", nil, TopicEvents)
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				t.Fatal("subscription closed")
+			}
+			if msg.Type != "after" {
+				t.Fatalf("message = %+v, want after", msg)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no message received after redis restarted")
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package realtime
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait bounds each write to the peer
+	writeWait = 10 * time.Second
+
+	// pongWait is how long a client may stay silent; pings go out more often
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+
+	// maxCommandSize limits the size of client commands
+	maxCommandSize = 4096
+
+	// clientBuffer is how many published messages may queue for a client
+	// before it's considered too slow and disconnected
+	clientBuffer = 64
+
+	// replyBuffer is how many command replies may queue for a client
+	replyBuffer = 8
+)
+
+// command is a message sent by a client
+type command struct {
+	Action string   `json:"action"` // subscribe or unsubscribe
+	Topics []string `json:"topics"`
+}
+
+// client is one WebSocket connection. readPump runs on the request goroutine
+// and writePump on its own; only writePump writes to conn.
+type client struct {
+	handler *Handler
+	conn    *websocket.Conn
+	sub     *Subscription
+	isAdmin bool
+	replies chan Message
+	done    chan struct{} // closed when readPump exits
+}
+
+// readPump handles client commands and pongs until the connection fails
+func (c *client) readPump() {
+	defer func() {
+		close(c.done)
+		c.handler.Hub.Unsubscribe(c.sub)
+	}()
+
+	c.conn.SetReadLimit(maxCommandSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd command
+		var ok bool
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			ok = c.reply("error", map[string]string{"error": "invalid command"})
+		} else {
+			ok = c.reply(c.handle(cmd))
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// handle applies a command and returns the reply to send
+func (c *client) handle(cmd command) (string, interface{}) {
+	topics, err := c.handler.resolveTopics(cmd.Topics, c.isAdmin)
+	if err != nil {
+		return "error", map[string]string{"error": err.Error()}
+	}
+
+	switch cmd.Action {
+	case "subscribe":
+		c.handler.Hub.Follow(c.sub, topics...)
+	case "unsubscribe":
+		c.handler.Hub.Unfollow(c.sub, topics...)
+	default:
+		return "error", map[string]string{"error": "unknown action"}
+	}
+	return "subscriptions", map[string][]string{"topics": c.handler.Hub.Topics(c.sub)}
+}
+
+// reply queues a reply for writePump, returning false if the client isn't
+// reading its replies either
+func (c *client) reply(msgType string, data interface{}) bool {
+	select {
+	case c.replies <- Message{Type: msgType, Data: data, SentAt: time.Now().UTC()}:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePump sends published messages, replies and pings. It closes the
+// connection when the client disconnects or is dropped for being slow.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.sub.C:
+			if !ok {
+				// Unsubscribed: either readPump exited or the hub dropped us
+				select {
+				case <-c.done:
+					return
+				default:
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"))
+				return
+			}
+			if !c.write(msg) {
+				return
+			}
+
+		case msg := <-c.replies:
+			if !c.write(msg) {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *client) write(msg Message) bool {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.conn.WriteJSON(msg) == nil
+}
@@ -1,46 +1,191 @@
 package realtime
 
 import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
 )
 
 // Handler holds dependencies for realtime handlers
 type Handler struct {
 	DB *gorm.DB
+	Options
+	upgrader websocket.Upgrader
+	origins  *middleware.OriginMatcher
+}
+
+// Options configures the realtime handlers
+type Options struct {
+	Hub            *Hub
+	AllowedOrigins []string // browser origin patterns allowed to connect, as for CORS
 }
 
 // NewHandler creates a new realtime handler
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *gorm.DB, opts Options) *Handler {
+	h := &Handler{DB: db, Options: opts, origins: middleware.NewOriginMatcher(opts.AllowedOrigins)}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkOrigin,
+	}
+	return h
 }
 
 // HandleWebSocket handles WebSocket connections for real-time updates
 // GET /api/realtime/ws
+// Query params:
+//   - topics: comma-separated topics to follow initially (default events)
+//   - token: JWT, for clients that can't send an Authorization header
+//
+// Clients change topics by sending {"action": "subscribe"|"unsubscribe",
+// "topics": [...]}. Topics are events, event:<id>, club:<id or handle>,
+// category:<name> and, for admins, moderation; broadcast is always followed.
 func (h *Handler) HandleWebSocket(c *gin.Context) {
-	// TODO: Implement WebSocket handler
-	// 1. Upgrade HTTP connection to WebSocket
-	// 2. Authenticate user if needed
-	// 3. Subscribe to relevant channels (events, notifications, etc.)
-	// 4. Handle incoming messages
-	// 5. Broadcast updates to connected clients
-	// 6. Handle disconnection and cleanup
-
-	c.JSON(200, gin.H{
-		"message": "WebSocket endpoint - upgrade connection",
-	})
+	topics := []string{TopicEvents}
+	if raw := c.Query("topics"); raw != "" {
+		topics = strings.Split(raw, ",")
+	}
+	topics, err := h.resolveTopics(topics, core.IsAdmin(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+
+	client := &client{
+		handler: h,
+		conn:    conn,
+		sub:     h.Hub.Subscribe(clientBuffer, append(topics, TopicBroadcast)...),
+		isAdmin: core.IsAdmin(c),
+		replies: make(chan Message, replyBuffer),
+		done:    make(chan struct{}),
+	}
+	go client.writePump()
+	client.readPump()
 }
 
 // BroadcastUpdate handles POST /api/realtime/broadcast - broadcast update to all clients
 // Requires: Admin authentication
+// Body: {"type": "announcement", "data": {...}, "topics": ["events"]};
+// topics defaults to broadcast, which every client follows
 func (h *Handler) BroadcastUpdate(c *gin.Context) {
-	// TODO: Implement broadcast
-	// 1. Validate admin authentication
-	// 2. Parse message from request body
-	// 3. Send message to all connected WebSocket clients
-	// 4. Return success response
+	var body struct {
+		Type   string      `json:"type"`
+		Data   interface{} `json:"data"`
+		Topics []string    `json:"topics"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Data == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "data is required"})
+		return
+	}
+	if body.Type == "" {
+		body.Type = "broadcast"
+	}
+	if len(body.Topics) == 0 {
+		body.Topics = []string{TopicBroadcast}
+	}
 
-	c.JSON(200, gin.H{
+	topics, err := h.resolveTopics(body.Topics, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.Hub.Publish(body.Type, body.Data, topics...)
+	c.JSON(http.StatusOK, gin.H{
 		"message": "Broadcast sent",
+		"topics":  topics,
 	})
 }
+
+var errUnknownTopic = errors.New("unknown topic")
+
+// resolveTopics validates client-supplied topics and maps club IDs to the
+// handle-keyed topics events are published on
+func (h *Handler) resolveTopics(topics []string, isAdmin bool) ([]string, error) {
+	resolved := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		topic = strings.TrimSpace(topic)
+		name, arg, _ := strings.Cut(topic, ":")
+
+		switch {
+		case topic == TopicEvents || topic == TopicBroadcast:
+			resolved = append(resolved, topic)
+
+		case topic == TopicModeration:
+			if !isAdmin {
+				return nil, errors.New("moderation topic requires admin access")
+			}
+			resolved = append(resolved, topic)
+
+		case name == "event" && arg != "":
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return nil, errUnknownTopic
+			}
+			resolved = append(resolved, EventTopic(uint(id)))
+
+		case name == "category" && arg != "":
+			resolved = append(resolved, CategoryTopic(arg))
+
+		case name == "club" && arg != "":
+			handle, err := h.clubHandle(arg)
+			if err != nil {
+				return nil, err
+			}
+			resolved = append(resolved, ClubTopic(handle))
+
+		default:
+			return nil, errUnknownTopic
+		}
+	}
+	return resolved, nil
+}
+
+// clubHandle resolves a club ID or Instagram handle to a normalised handle
+func (h *Handler) clubHandle(ref string) (string, error) {
+	id, err := strconv.ParseUint(ref, 10, 64)
+	if err != nil {
+		if handle := utils.NormalizeHandle(ref); handle != "" {
+			return handle, nil
+		}
+		return "", errUnknownTopic
+	}
+
+	var club clubs.Clubs
+	if err := h.DB.Select("id", "ig").Take(&club, id).Error; err != nil {
+		return "", errors.New("club not found")
+	}
+	if club.IG == nil || utils.NormalizeHandle(*club.IG) == "" {
+		return "", errors.New("club has no linked Instagram handle")
+	}
+	return utils.NormalizeHandle(*club.IG), nil
+}
+
+// checkOrigin allows non-browser clients, which send no Origin, and browser
+// pages served from AllowedOrigins
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.origins.Allows(origin) {
+		return true
+	}
+
+	// Same-origin pages are always allowed
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
@@ -0,0 +1,241 @@
+package realtime
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Topics clients can subscribe to
+const (
+	TopicEvents     = "events"     // every public event update
+	TopicModeration = "moderation" // submission queue changes; admins only
+	TopicBroadcast  = "broadcast"  // admin announcements; every client receives them
+)
+
+// EventTopic is the topic for updates to one event
+func EventTopic(eventID uint) string {
+	return "event:" + strconv.FormatUint(uint64(eventID), 10)
+}
+
+// CategoryTopic is the topic for updates to events in a category
+func CategoryTopic(category string) string {
+	return "category:" + strings.ToLower(strings.TrimSpace(category))
+}
+
+// ClubTopic is the topic for updates to a club's events, keyed by the club's
+// normalised Instagram handle (see utils.NormalizeHandle)
+func ClubTopic(handle string) string {
+	return "club:" + handle
+}
+
+const (
+	// replaySize is how many recent messages the hub keeps for SubscribeFrom
+	replaySize = 256
+
+	// publishTimeout bounds a publish to the broker
+	publishTimeout = 2 * time.Second
+)
+
+// Message is an update pushed to subscribers
+type Message struct {
+	ID     uint64      `json:"id,omitempty"` // assigned by the broker; 0 for replies
+	Type   string      `json:"type"`         // e.g. event.created, event.reactions
+	Topics []string    `json:"topics"`
+	Data   interface{} `json:"data"`
+	SentAt time.Time   `json:"sent_at"`
+}
+
+// Publisher publishes messages; *Hub implements it
+type Publisher interface {
+	Publish(msgType string, data interface{}, topics ...string)
+}
+
+// Hub fans out messages to this instance's subscribers and keeps the most
+// recent ones so reconnecting clients can catch up. Messages are published
+// through a Broker, so subscribers on every instance receive them.
+type Hub struct {
+	broker      Broker
+	stop        func()
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	lastID      uint64
+	recent      []Message // ring buffer of the last replaySize messages
+	next        int       // index in recent the next message goes to
+}
+
+// NewHub creates a hub receiving messages from broker
+func NewHub(broker Broker) (*Hub, error) {
+	h := &Hub{
+		broker:      broker,
+		subscribers: map[*Subscription]struct{}{},
+		recent:      make([]Message, 0, replaySize),
+	}
+
+	stop, err := broker.Subscribe(h.deliver)
+	if err != nil {
+		return nil, err
+	}
+	h.stop = stop
+	return h, nil
+}
+
+// Close stops receiving messages from the broker
+func (h *Hub) Close() {
+	h.stop()
+}
+
+// Subscription receives the messages published on its topics. C is closed
+// when the subscription ends, either through Unsubscribe or because the
+// subscriber fell behind and was dropped.
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	topics map[string]bool // guarded by Hub.mu
+}
+
+// Subscribe registers a subscriber for topics. At most size messages are
+// buffered for it; a subscriber that lets its buffer fill is dropped rather
+// than slowing down publishers.
+func (h *Hub) Subscribe(size int, topics ...string) *Subscription {
+	sub := newSubscription(size, topics)
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// SubscribeFrom is Subscribe that also returns the buffered messages on
+// topics published after lastID. complete is false when messages after
+// lastID have already left the buffer (or lastID is from before a restart),
+// in which case the caller should tell its client to reload.
+func (h *Hub) SubscribeFrom(size int, lastID uint64, topics ...string) (sub *Subscription, missed []Message, complete bool) {
+	sub = newSubscription(size, topics)
+
+	// Collect the backlog and register under one lock, so no message falls
+	// between the two
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[sub] = struct{}{}
+
+	oldest := 0
+	if len(h.recent) == replaySize {
+		oldest = h.next
+	}
+
+	complete = lastID <= h.lastID
+	if len(h.recent) > 0 && h.recent[oldest].ID > lastID+1 {
+		complete = false
+	}
+	for i := range h.recent {
+		msg := h.recent[(oldest+i)%len(h.recent)]
+		if msg.ID > lastID && sub.follows(msg.Topics) {
+			missed = append(missed, msg)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Unsubscribe removes sub and closes its channel; it's safe to call twice
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+// Follow adds topics to sub
+func (h *Hub) Follow(sub *Subscription, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+}
+
+// Unfollow removes topics from sub
+func (h *Hub) Unfollow(sub *Subscription, topics ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		delete(sub.topics, topic)
+	}
+}
+
+// Topics lists the topics sub follows
+func (h *Hub) Topics(sub *Subscription) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	topics := make([]string, 0, len(sub.topics))
+	for topic := range sub.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Publish sends a message to the subscribers of any of topics on every
+// instance. Failures are logged: realtime updates are best-effort.
+func (h *Hub) Publish(msgType string, data interface{}, topics ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	msg := Message{Type: msgType, Topics: topics, Data: data, SentAt: time.Now().UTC()}
+	if err := h.broker.Publish(ctx, msg); err != nil {
+		log.Printf("realtime: publish %s: %v", msgType, err)
+	}
+}
+
+// deliver buffers a message from the broker and fans it out. It never
+// blocks; subscribers whose buffer is full are dropped.
+func (h *Hub) deliver(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID = max(h.lastID, msg.ID)
+	if len(h.recent) < replaySize {
+		h.recent = append(h.recent, msg)
+	} else {
+		h.recent[h.next] = msg
+	}
+	h.next = (h.next + 1) % replaySize
+
+	for sub := range h.subscribers {
+		if !sub.follows(msg.Topics) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// remove unregisters sub; h.mu must be held for writing
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+func newSubscription(size int, topics []string) *Subscription {
+	ch := make(chan Message, size)
+	sub := &Subscription{C: ch, ch: ch, topics: make(map[string]bool, len(topics))}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+	return sub
+}
+
+func (s *Subscription) follows(topics []string) bool {
+	for _, topic := range topics {
+		if s.topics[topic] {
+			return true
+		}
+	}
+	return false
+}
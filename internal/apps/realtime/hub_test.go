@@ -0,0 +1,303 @@
+package realtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+// openClubsDB opens a test database with the columns clubHandle reads, and
+// club 1 linked to @UWGamesClub and club 2 to nothing
+func openClubsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testutil.OpenDB(t)
+	for _, statement := range []string{
+		"CREATE TABLE clubs (id INTEGER PRIMARY KEY, ig TEXT, deleted_at DATETIME)",
+		"INSERT INTO clubs (id, ig) VALUES (1, 'https://instagram.com/UWGamesClub/'), (2, NULL)",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestHubFansOutByTopic(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	all := hub.Subscribe(clientBuffer, TopicEvents)
+	one := hub.Subscribe(clientBuffer, EventTopic(1))
+	both := hub.Subscribe(clientBuffer, TopicEvents, EventTopic(1))
+	games := hub.Subscribe(clientBuffer, CategoryTopic(" Games "))
+
+	hub.Publish("event.updated", nil, TopicEvents, EventTopic(1), ClubTopic("uwgamesclub"))
+	hub.Publish("event.updated", nil, TopicEvents, EventTopic(2), CategoryTopic("games"))
+
+	if msg := receive(t, all); msg.ID != 1 {
+		t.Errorf("first message on events = %+v", msg)
+	}
+	if msg := receive(t, all); msg.ID != 2 {
+		t.Errorf("second message on events = %+v", msg)
+	}
+	if msg := receive(t, one); msg.ID != 1 {
+		t.Errorf("message on event:1 = %+v", msg)
+	}
+	expectNone(t, one)
+
+	// A message on several followed topics arrives once
+	receive(t, both)
+	receive(t, both)
+	expectNone(t, both)
+
+	if msg := receive(t, games); msg.ID != 2 {
+		t.Errorf("message on category:games = %+v", msg)
+	}
+	expectNone(t, games)
+}
+
+func TestHubFollowAndUnfollow(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	sub := hub.Subscribe(clientBuffer, TopicEvents)
+
+	hub.Follow(sub, EventTopic(3), ClubTopic("uwgamesclub"))
+	hub.Unfollow(sub, TopicEvents, "never-followed")
+	topics := hub.Topics(sub)
+	sort.Strings(topics)
+	if strings.Join(topics, ",") != "club:uwgamesclub,event:3" {
+		t.Errorf("topics = %v", topics)
+	}
+
+	hub.Publish("event.created", nil, TopicEvents)
+	hub.Publish("event.updated", nil, TopicEvents, EventTopic(3))
+	if msg := receive(t, sub); msg.Type != "event.updated" {
+		t.Errorf("message = %+v, want only the event:3 update", msg)
+	}
+	expectNone(t, sub)
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	slow := hub.Subscribe(2, TopicEvents)
+	fast := hub.Subscribe(clientBuffer, TopicEvents)
+
+	for i := 0; i < 3; i++ {
+		hub.Publish("event.updated", nil, TopicEvents)
+	}
+
+	// The slow subscriber keeps what it buffered, then its channel closes
+	for i := 0; i < 2; i++ {
+		receive(t, slow)
+	}
+	select {
+	case _, ok := <-slow.C:
+		if ok {
+			t.Error("slow subscriber got a message past its buffer")
+		}
+	case <-time.After(time.Second):
+		t.Error("slow subscriber wasn't dropped")
+	}
+
+	// Publishers weren't held up, and everyone else got everything
+	for i := 1; i <= 3; i++ {
+		if msg := receive(t, fast); msg.ID != uint64(i) {
+			t.Errorf("fast subscriber message %d has ID %d", i, msg.ID)
+		}
+	}
+
+	// Unsubscribing a dropped subscription, or twice, is harmless
+	hub.Unsubscribe(slow)
+	hub.Unsubscribe(fast)
+	hub.Unsubscribe(fast)
+	hub.Publish("event.updated", nil, TopicEvents)
+	if _, ok := <-fast.C; ok {
+		t.Error("unsubscribed channel still open")
+	}
+}
+
+func TestResolveTopics(t *testing.T) {
+	h := NewHandler(openClubsDB(t), Options{})
+
+	tests := []struct {
+		topics  string
+		admin   bool
+		want    string
+		wantErr string
+	}{
+		{topics: "events, broadcast", want: "events,broadcast"},
+		{topics: "event:42", want: "event:42"},
+		{topics: "category: Games ", want: "category:games"},
+		{topics: "club:1", want: "club:uwgamesclub"},
+		{topics: "club:@UWGamesClub", want: "club:uwgamesclub"},
+		{topics: "moderation", admin: true, want: "moderation"},
+		{topics: "moderation", wantErr: "moderation topic requires admin access"},
+		{topics: "club:2", wantErr: "club has no linked Instagram handle"},
+		{topics: "club:99", wantErr: "club not found"},
+		{topics: "event:abc", wantErr: errUnknownTopic.Error()},
+		{topics: "event:", wantErr: errUnknownTopic.Error()},
+		{topics: "users", wantErr: errUnknownTopic.Error()},
+	}
+	for _, tt := range tests {
+		got, err := h.resolveTopics(strings.Split(tt.topics, ","), tt.admin)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("resolveTopics(%q) = %v, %v; want error %q", tt.topics, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || strings.Join(got, ",") != tt.want {
+			t.Errorf("resolveTopics(%q) = %v, %v; want %s", tt.topics, got, err, tt.want)
+		}
+	}
+}
+
+// newWebSocketServer serves h's WebSocket endpoint; requests with ?admin=1
+// are treated as an admin's
+func newWebSocketServer(t *testing.T, h *Handler) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) {
+		if c.Query("admin") == "1" {
+			c.Set(core.ContextClaims, &core.Claims{UserID: "admin_1", Role: core.RoleAdmin})
+		}
+		h.HandleWebSocket(c)
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dial(t *testing.T, server *httptest.Server, query string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?"+query, header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// readMessage reads the next message from conn
+func readMessage(t *testing.T, conn *websocket.Conn) Message {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+// send sends a command and returns the reply
+func send(t *testing.T, conn *websocket.Conn, cmd string) Message {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(cmd)); err != nil {
+		t.Fatal(err)
+	}
+	return readMessage(t, conn)
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	h := NewHandler(openClubsDB(t), Options{Hub: hub})
+	server := newWebSocketServer(t, h)
+
+	conn, _, err := dial(t, server, "topics=event:1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hub subscription is made after the handshake; a reply to a
+	// command shows it's in place
+	if reply := send(t, conn, `{"action": "subscribe", "topics": ["club:1"]}`); reply.Type != "subscriptions" {
+		t.Fatalf("reply = %+v", reply)
+	} else if topics := reply.Data.(map[string]interface{})["topics"].([]interface{}); len(topics) != 3 {
+		t.Errorf("topics = %v, want event:1, club:uwgamesclub and broadcast", topics)
+	}
+
+	hub.Publish("event.created", nil, TopicEvents, EventTopic(2))
+	hub.Publish("event.updated", nil, TopicEvents, ClubTopic("uwgamesclub"))
+	hub.Publish("announcement", "hello", TopicBroadcast)
+	for _, want := range []string{"event.updated", "announcement"} {
+		if msg := readMessage(t, conn); msg.Type != want {
+			t.Errorf("message = %+v, want %s", msg, want)
+		}
+	}
+
+	for cmd, wantError := range map[string]string{
+		`not json`: "invalid command",
+		`{"action": "subscribe", "topics": ["moderation"]}`: "moderation topic requires admin access",
+		`{"action": "follow", "topics": ["events"]}`:        "unknown action",
+		`{"action": "subscribe", "topics": ["users"]}`:      "unknown topic",
+	} {
+		reply := send(t, conn, cmd)
+		if data, _ := reply.Data.(map[string]interface{}); reply.Type != "error" || data["error"] != wantError {
+			t.Errorf("%s: reply = %+v, want error %q", cmd, reply, wantError)
+		}
+	}
+
+	send(t, conn, `{"action": "unsubscribe", "topics": ["event:1", "club:1"]}`)
+	hub.Publish("event.updated", nil, EventTopic(1))
+	hub.Publish("announcement", "still here", TopicBroadcast)
+	if msg := readMessage(t, conn); msg.Type != "announcement" {
+		t.Errorf("message = %+v after unsubscribing, want only the broadcast", msg)
+	}
+}
+
+func TestWebSocketModerationNeedsAdmin(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	server := newWebSocketServer(t, NewHandler(openClubsDB(t), Options{Hub: hub}))
+
+	if _, resp, err := dial(t, server, "topics=moderation", nil); err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("non-admin moderation subscription wasn't refused with 400: %v", err)
+	}
+
+	conn, _, err := dial(t, server, "topics=moderation&admin=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send(t, conn, `{"action": "subscribe", "topics": []}`) // wait for the subscription
+	hub.Publish("submission.created", nil, TopicModeration)
+	if msg := readMessage(t, conn); msg.Type != "submission.created" {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	server := newWebSocketServer(t, NewHandler(openClubsDB(t), Options{
+		Hub:            hub,
+		AllowedOrigins: []string{"https://wat2do.ca", "https://*.wat2do.ca"},
+	}))
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{"https://wat2do.ca", true},
+		{"https://preview.wat2do.ca", true},
+		{server.URL, true},
+		{"https://evil.example", false},
+		{"https://wat2do.ca.evil.example", false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		_, resp, err := dial(t, server, "", header)
+		if tt.ok && err != nil {
+			t.Errorf("origin %q refused: %v", tt.origin, err)
+		}
+		if !tt.ok && (err == nil || resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("origin %q allowed", tt.origin)
+		}
+	}
+}
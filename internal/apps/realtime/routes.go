@@ -1,19 +1,19 @@
 package realtime
 
 import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // RegisterRoutes registers realtime-related routes
-func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-	handler := NewHandler(db)
+func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
 	realtime := rg.Group("/realtime")
 	{
-		realtime.GET("/ws", handler.HandleWebSocket)
-		realtime.POST("/broadcast", handler.BroadcastUpdate)
-		// TODO: Add WebSocket upgrade middleware
-		// TODO: Add admin authentication for broadcast endpoint
+		realtime.GET("/ws", core.OptionalJWTQuery("token"), handler.HandleWebSocket)
+		realtime.GET("/events", core.OptionalJWTQuery("token"), handler.StreamEvents)
+		realtime.POST("/broadcast", core.JWTRequired(), core.AdminRequired(), handler.BroadcastUpdate)
 	}
 }
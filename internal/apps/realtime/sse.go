@@ -0,0 +1,171 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// heartbeatInterval keeps idle streams alive through proxies that close
+	// silent connections
+	heartbeatInterval = 15 * time.Second
+
+	// retryDelay is the reconnect delay suggested to EventSource clients
+	retryDelay = 5 * time.Second
+)
+
+// StreamEvents handles GET /api/realtime/events - Server-Sent Events stream of
+// event updates, for clients that can't hold a WebSocket open
+// Query params:
+//   - topics: comma-separated topics to follow, as for the WebSocket (default events)
+//   - token: JWT, since EventSource can't send an Authorization header
+//   - club, category, event: comma-separated filters; an update must match
+//     every one given
+//   - last_event_id: resume point, for clients that can't send Last-Event-ID
+func (h *Handler) StreamEvents(c *gin.Context) {
+	topics := []string{TopicEvents}
+	if raw := c.Query("topics"); raw != "" {
+		topics = strings.Split(raw, ",")
+	}
+	topics, err := h.resolveTopics(topics, core.IsAdmin(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	topics = append(topics, TopicBroadcast)
+
+	var filters [][]string
+	for _, param := range []string{"club", "category", "event"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+
+		var group []string
+		for _, value := range strings.Split(raw, ",") {
+			group = append(group, param+":"+strings.TrimSpace(value))
+		}
+		group, err := h.resolveTopics(group, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filters = append(filters, group)
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var sub *Subscription
+	var missed []Message
+	complete := true
+	if lastEventID != "" {
+		lastID, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		sub, missed, complete = h.Hub.SubscribeFrom(clientBuffer, lastID, topics...)
+	} else {
+		sub = h.Hub.Subscribe(clientBuffer, topics...)
+	}
+	defer h.Hub.Unsubscribe(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // disable nginx response buffering
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", retryDelay.Milliseconds())
+	if !complete {
+		// Updates were missed; the client should refetch what it shows
+		fmt.Fprint(c.Writer, "event: resync\ndata: {}\n\n")
+	}
+	for _, msg := range missed {
+		if matchesFilters(msg, filters) {
+			writeSSE(c.Writer, msg)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects and resumes
+				return
+			}
+			if !matchesFilters(msg, filters) {
+				continue
+			}
+			if err := writeSSE(c.Writer, msg); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// matchesFilters reports whether msg is on a topic from every filter group.
+// Broadcasts always match.
+func matchesFilters(msg Message, filters [][]string) bool {
+	if hasTopic(msg.Topics, TopicBroadcast) {
+		return true
+	}
+	for _, group := range filters {
+		matched := false
+		for _, topic := range group {
+			if hasTopic(msg.Topics, topic) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func hasTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSSE writes msg as one SSE event, using its type as the event name
+func writeSSE(w gin.ResponseWriter, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	// Broadcast types come from admins; keep them on one line
+	name := strings.NewReplacer("\r", "", "\n", "").Replace(msg.Type)
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, name, data)
+	return err
+}
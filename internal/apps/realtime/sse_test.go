@@ -0,0 +1,298 @@
+package realtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseEvent is one event read from a stream
+type sseEvent struct {
+	ID, Event, Data, Retry string
+}
+
+// sseStream reads events from an open stream in the background
+type sseStream struct {
+	events chan sseEvent
+}
+
+// openStream connects to the SSE endpoint of h with query and header
+func openStream(t *testing.T, h *Handler, query string, header http.Header) (*sseStream, *http.Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events", h.StreamEvents)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events?"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp
+	}
+
+	stream := &sseStream{events: make(chan sseEvent, 512)}
+	go func() {
+		defer close(stream.events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			field, value, _ := strings.Cut(line, ": ")
+			switch field {
+			case "":
+				stream.events <- event
+				event = sseEvent{}
+			case "id":
+				event.ID = value
+			case "event":
+				event.Event = value
+			case "data":
+				event.Data = value
+			case "retry":
+				event.Retry = value
+			}
+		}
+	}()
+	return stream, resp
+}
+
+// next returns the stream's next event
+func (s *sseStream) next(t *testing.T) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return sseEvent{}
+}
+
+// expectEvents checks the stream's next events have ids
+func (s *sseStream) expectEvents(t *testing.T, ids ...uint64) {
+	t.Helper()
+	for _, id := range ids {
+		if event := s.next(t); event.ID != strconv.FormatUint(id, 10) {
+			t.Fatalf("event = %+v, want id %d", event, id)
+		}
+	}
+}
+
+// expectNothing checks no event arrives for a short while
+func (s *sseStream) expectNothing(t *testing.T) {
+	t.Helper()
+	select {
+	case event := <-s.events:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// start reads the retry hint the stream opens with
+func (s *sseStream) start(t *testing.T) {
+	t.Helper()
+	if event := s.next(t); event.Retry != "5000" {
+		t.Fatalf("stream opened with %+v, want a retry hint", event)
+	}
+}
+
+func publishN(hub *Hub, n int, topics ...string) {
+	for i := 0; i < n; i++ {
+		hub.Publish("event.updated", nil, topics...)
+	}
+}
+
+func TestStreamEventsLive(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	stream, resp := openStream(t, NewHandler(openClubsDB(t), Options{Hub: hub}), "", nil)
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	stream.start(t)
+
+	hub.Publish("event.created", map[string]int{"event_id": 1}, TopicEvents, EventTopic(1))
+	hub.Publish("pending", nil, TopicModeration)
+	hub.Publish("line\nbreak", nil, TopicBroadcast)
+
+	event := stream.next(t)
+	var msg Message
+	if err := json.Unmarshal([]byte(event.Data), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != "1" || event.Event != "event.created" || msg.ID != 1 || msg.Type != "event.created" {
+		t.Errorf("event = %+v", event)
+	}
+	// Moderation isn't followed; broadcast names stay on one line
+	if event := stream.next(t); event.ID != "3" || event.Event != "linebreak" {
+		t.Errorf("event = %+v, want the broadcast on one line", event)
+	}
+}
+
+func TestStreamEventsReplaysAfterLastEventID(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	h := NewHandler(openClubsDB(t), Options{Hub: hub})
+	publishN(hub, 5, TopicEvents)
+	hub.Publish("pending", nil, TopicModeration)
+
+	tests := []struct {
+		name   string
+		query  string
+		header string
+	}{
+		{name: "header", header: "3"},
+		{name: "query", query: "last_event_id=3"},
+		{name: "header wins", query: "last_event_id=1", header: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Last-Event-ID", tt.header)
+			}
+			stream, _ := openStream(t, h, tt.query, header)
+			stream.start(t)
+
+			// The missed events the stream follows, then live ones, with no
+			// resync and nothing twice
+			stream.expectEvents(t, 4, 5)
+			stream.expectNothing(t)
+		})
+	}
+
+	stream, _ := openStream(t, h, "", http.Header{"Last-Event-Id": {"6"}})
+	stream.start(t)
+	publishN(hub, 1, TopicEvents)
+	stream.expectEvents(t, 7)
+}
+
+func TestStreamEventsAsksForResync(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	h := NewHandler(openClubsDB(t), Options{Hub: hub})
+	publishN(hub, replaySize+5, TopicEvents)
+
+	tests := []struct {
+		name, lastID string
+		first        uint64
+	}{
+		// Messages 2-5 have left the buffer
+		{"too old", "1", 6},
+		// An ID from before a restart
+		{"from the future", "1000", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, _ := openStream(t, h, "", http.Header{"Last-Event-Id": {tt.lastID}})
+			stream.start(t)
+			if event := stream.next(t); event.Event != "resync" {
+				t.Fatalf("event = %+v, want resync", event)
+			}
+			if tt.first != 0 {
+				stream.expectEvents(t, tt.first, tt.first+1)
+			} else {
+				stream.expectNothing(t)
+			}
+		})
+	}
+
+	// The oldest buffered message's predecessor is still a complete resume
+	stream, _ := openStream(t, h, "", http.Header{"Last-Event-Id": {"5"}})
+	stream.start(t)
+	if event := stream.next(t); event.ID != "6" {
+		t.Errorf("event = %+v, want 6 without a resync", event)
+	}
+}
+
+func TestStreamEventsFilters(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+	h := NewHandler(openClubsDB(t), Options{Hub: hub})
+
+	hub.Publish("games night", nil, TopicEvents, ClubTopic("uwgamesclub"), CategoryTopic("games"))
+	hub.Publish("other club", nil, TopicEvents, ClubTopic("uwchess"), CategoryTopic("games"))
+	hub.Publish("other category", nil, TopicEvents, ClubTopic("uwgamesclub"), CategoryTopic("food"))
+	hub.Publish("announcement", nil, TopicBroadcast)
+
+	// Replayed and live messages are filtered alike; broadcasts always pass
+	stream, _ := openStream(t, h, "club=1&category=Games,Social", http.Header{"Last-Event-Id": {"0"}})
+	stream.start(t)
+	for _, want := range []string{"games night", "announcement"} {
+		if event := stream.next(t); event.Event != want {
+			t.Errorf("event = %+v, want %s", event, want)
+		}
+	}
+	stream.expectNothing(t)
+
+	hub.Publish("other club", nil, TopicEvents, ClubTopic("uwchess"), CategoryTopic("social"))
+	hub.Publish("social night", nil, TopicEvents, ClubTopic("uwgamesclub"), CategoryTopic("social"))
+	if event := stream.next(t); event.Event != "social night" {
+		t.Errorf("event = %+v, want social night", event)
+	}
+}
+
+func TestStreamEventsRejectsBadRequests(t *testing.T) {
+	h := NewHandler(openClubsDB(t), Options{Hub: newTestHub(t, NewMemoryBroker())})
+
+	for _, tt := range []struct {
+		query  string
+		header http.Header
+	}{
+		{query: "topics=moderation"},
+		{query: "topics=users"},
+		{query: "club=99"},
+		{query: "event=abc"},
+		{header: http.Header{"Last-Event-Id": {"abc"}}},
+		{query: "last_event_id=-1"},
+	} {
+		if _, resp := openStream(t, h, tt.query, tt.header); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%q %v = %d, want 400", tt.query, tt.header, resp.StatusCode)
+		}
+	}
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	hub := newTestHub(t, NewMemoryBroker())
+
+	// Before anything is published, only a resume from 0 is complete
+	if sub, missed, complete := hub.SubscribeFrom(clientBuffer, 0, TopicEvents); !complete || len(missed) != 0 {
+		t.Errorf("SubscribeFrom(0) on an empty hub = %d missed, complete %t", len(missed), complete)
+	} else {
+		hub.Unsubscribe(sub)
+	}
+	if _, _, complete := hub.SubscribeFrom(clientBuffer, 3, TopicEvents); complete {
+		t.Error("resuming from an ID the hub never saw was complete")
+	}
+
+	publishN(hub, 2, TopicEvents)
+	publishN(hub, 1, EventTopic(9))
+	sub, missed, complete := hub.SubscribeFrom(clientBuffer, 1, EventTopic(9))
+	if !complete || len(missed) != 1 || missed[0].ID != 3 {
+		t.Errorf("SubscribeFrom(1) = %+v, complete %t; want message 3 only", missed, complete)
+	}
+
+	// Nothing published after the backlog is collected is lost or repeated
+	publishN(hub, 1, EventTopic(9))
+	if msg := receive(t, sub); msg.ID != 4 {
+		t.Errorf("live message = %+v, want 4", msg)
+	}
+	expectNone(t, sub)
+}
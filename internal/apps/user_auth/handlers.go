@@ -2,6 +2,7 @@ package user_auth
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -9,12 +10,24 @@ import (
 
 // Handler holds dependencies for user_auth handlers
 type Handler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	webhooks *WebhookVerifier
+}
+
+// Options configures the user_auth handlers
+type Options struct {
+	// WebhookSecrets are the Clerk webhook signing secrets; more than one
+	// may be given while rotating
+	WebhookSecrets   []string
+	WebhookTolerance time.Duration
 }
 
 // NewHandler creates a new user_auth handler
-func NewHandler(db *gorm.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(db *gorm.DB, opts Options) *Handler {
+	return &Handler{
+		DB:       db,
+		webhooks: NewWebhookVerifier(opts.WebhookSecrets, opts.WebhookTolerance),
+	}
 }
 
 // GetCurrentUser handles GET /api/auth/me - get current user info
@@ -47,17 +60,3 @@ func (h *Handler) UpdateUserProfile(c *gin.Context) {
 		"message": "Profile updated successfully",
 	})
 }
-
-// SyncClerkUser handles POST /api/auth/sync - sync user from Clerk webhook
-// This endpoint is called by Clerk webhooks to sync user data
-func (h *Handler) SyncClerkUser(c *gin.Context) {
-	// TODO: Implement Clerk user sync
-	// 1. Verify Clerk webhook signature
-	// 2. Parse webhook event (user.created, user.updated, user.deleted)
-	// 3. Create/update/delete user record in database
-	// 4. Return success response
-
-	c.JSON(http.StatusOK, gin.H{
-		"received": true,
-	})
-}
@@ -15,7 +15,7 @@ type User struct {
 	Email     string         `gorm:"size:255;index" json:"email"`
 	Name      *string        `gorm:"size:255" json:"name"`
 	Role      string         `gorm:"size:32;default:'user'" json:"role"` // user, admin
-	Metadata  map[string]any `gorm:"type:jsonb" json:"metadata"`
+	Metadata  map[string]any `gorm:"type:jsonb;serializer:json" json:"metadata"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -25,3 +25,20 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+// RoleUser is the role of users without elevated access
+const RoleUser = "user"
+
+// ClerkWebhookEvent records a processed Clerk webhook by its svix-id, so
+// redeliveries are acknowledged without being applied twice
+type ClerkWebhookEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SvixID    string    `gorm:"size:255;uniqueIndex;not null" json:"svix_id"`
+	Type      string    `gorm:"size:64;not null" json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ClerkWebhookEvent) TableName() string {
+	return "clerk_webhook_events"
+}
@@ -1,19 +1,19 @@
 package user_auth
 
 import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 // RegisterRoutes registers user_auth-related routes
-func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
-	handler := NewHandler(db)
+func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB, opts Options) {
+	handler := NewHandler(db, opts)
 
 	auth := rg.Group("/auth")
 	{
-		auth.GET("/me", handler.GetCurrentUser)
-		auth.PUT("/profile", handler.UpdateUserProfile)
+		auth.GET("/me", core.JWTRequired(), handler.GetCurrentUser)
+		auth.PUT("/profile", core.JWTRequired(), handler.UpdateUserProfile)
 		auth.POST("/sync", handler.SyncClerkUser)
-		// TODO: Add JWT authentication middleware for protected routes
 	}
 }
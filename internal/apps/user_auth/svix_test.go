@@ -0,0 +1,165 @@
+package user_auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testWebhookKey  = []byte("current-signing-key")
+	rotatedOutKey   = []byte("previous-signing-key")
+	testWebhookNow  = time.Unix(1767225600, 0)
+	testWebhookBody = []byte(`{"type":"user.updated","data":{"id":"user_1"}}`)
+	testSvixID      = "msg_1"
+)
+
+// whsec encodes key as a Clerk signing secret
+func whsec(key []byte) string {
+	return "whsec_" + base64.StdEncoding.EncodeToString(key)
+}
+
+// svixSignature returns a "v1,<base64>" signature of body with key
+func svixSignature(key []byte, id string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(timestamp.Unix(), 10) + "." + string(body)))
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func svixHeaders(id string, timestamp time.Time, signatures ...string) http.Header {
+	header := http.Header{}
+	header.Set("svix-id", id)
+	header.Set("svix-timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set("svix-signature", strings.Join(signatures, " "))
+	return header
+}
+
+func TestWebhookVerifier(t *testing.T) {
+	signed := svixSignature(testWebhookKey, testSvixID, testWebhookNow, testWebhookBody)
+	tests := []struct {
+		name    string
+		secrets []string
+		header  http.Header
+		body    []byte
+		want    error
+	}{
+		{
+			name:    "valid",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, signed),
+		},
+		{
+			name:    "within tolerance",
+			secrets: []string{whsec(testWebhookKey)},
+			header: svixHeaders(testSvixID, testWebhookNow.Add(-4*time.Minute),
+				svixSignature(testWebhookKey, testSvixID, testWebhookNow.Add(-4*time.Minute), testWebhookBody)),
+		},
+		{
+			name:    "one of several signatures",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, "v1,bm90IGl0", "v2,ignored", signed),
+		},
+		{
+			name:    "rotating: new secret added",
+			secrets: []string{whsec(rotatedOutKey), whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, signed),
+		},
+		{
+			name:    "rotating: sender still on the old secret",
+			secrets: []string{whsec(rotatedOutKey), whsec(testWebhookKey)},
+			header: svixHeaders(testSvixID, testWebhookNow,
+				svixSignature(rotatedOutKey, testSvixID, testWebhookNow, testWebhookBody)),
+		},
+		{
+			name:    "rotated-out secret",
+			secrets: []string{whsec(testWebhookKey)},
+			header: svixHeaders(testSvixID, testWebhookNow,
+				svixSignature(rotatedOutKey, testSvixID, testWebhookNow, testWebhookBody)),
+			want: errNoValidSignature,
+		},
+		{
+			name:    "tampered body",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, signed),
+			body:    []byte(`{"type":"user.updated","data":{"id":"user_2"}}`),
+			want:    errNoValidSignature,
+		},
+		{
+			name:    "signature for another svix-id",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  svixHeaders("msg_2", testWebhookNow, signed),
+			want:    errNoValidSignature,
+		},
+		{
+			name:    "unknown version",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, "v2"+strings.TrimPrefix(signed, "v1")),
+			want:    errNoValidSignature,
+		},
+		{
+			name:    "stale",
+			secrets: []string{whsec(testWebhookKey)},
+			header: svixHeaders(testSvixID, testWebhookNow.Add(-6*time.Minute),
+				svixSignature(testWebhookKey, testSvixID, testWebhookNow.Add(-6*time.Minute), testWebhookBody)),
+			want: errStaleTimestamp,
+		},
+		{
+			name:    "future",
+			secrets: []string{whsec(testWebhookKey)},
+			header: svixHeaders(testSvixID, testWebhookNow.Add(6*time.Minute),
+				svixSignature(testWebhookKey, testSvixID, testWebhookNow.Add(6*time.Minute), testWebhookBody)),
+			want: errStaleTimestamp,
+		},
+		{
+			name:    "malformed timestamp",
+			secrets: []string{whsec(testWebhookKey)},
+			header: func() http.Header {
+				header := svixHeaders(testSvixID, testWebhookNow, signed)
+				header.Set("svix-timestamp", "yesterday")
+				return header
+			}(),
+			want: errStaleTimestamp,
+		},
+		{
+			name:    "missing headers",
+			secrets: []string{whsec(testWebhookKey)},
+			header:  http.Header{},
+			want:    errMissingSvixHeaders,
+		},
+		{
+			name:    "invalid secret ignored",
+			secrets: []string{"whsec_!!!", whsec(testWebhookKey)},
+			header:  svixHeaders(testSvixID, testWebhookNow, signed),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewWebhookVerifier(tt.secrets, 5*time.Minute)
+			v.now = func() time.Time { return testWebhookNow }
+			body := tt.body
+			if body == nil {
+				body = testWebhookBody
+			}
+			if err := v.Verify(tt.header, body); err != tt.want {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifierConfigured(t *testing.T) {
+	if NewWebhookVerifier(nil, time.Minute).Configured() {
+		t.Error("verifier without secrets is configured")
+	}
+	if NewWebhookVerifier([]string{"whsec_"}, time.Minute).Configured() {
+		t.Error("verifier with only an empty secret is configured")
+	}
+	if !NewWebhookVerifier([]string{whsec(testWebhookKey)}, time.Minute).Configured() {
+		t.Error("verifier with a secret isn't configured")
+	}
+}
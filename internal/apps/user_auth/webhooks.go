@@ -0,0 +1,194 @@
+package user_auth
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxWebhookSize bounds the webhook body read into memory
+const maxWebhookSize = 1 << 20
+
+// clerkEvent is the envelope of a Clerk webhook
+type clerkEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// clerkUser is the data of user.created, user.updated and user.deleted
+// events; deleted users only carry ID and Deleted. UpdatedAt is in Unix
+// milliseconds.
+type clerkUser struct {
+	ID                    string         `json:"id"`
+	FirstName             *string        `json:"first_name"`
+	LastName              *string        `json:"last_name"`
+	Username              *string        `json:"username"`
+	PrimaryEmailAddressID *string        `json:"primary_email_address_id"`
+	EmailAddresses        []clerkEmail   `json:"email_addresses"`
+	PublicMetadata        map[string]any `json:"public_metadata"`
+	UpdatedAt             int64          `json:"updated_at"`
+	Deleted               bool           `json:"deleted"`
+}
+
+type clerkEmail struct {
+	ID           string `json:"id"`
+	EmailAddress string `json:"email_address"`
+}
+
+// SyncClerkUser handles POST /api/auth/sync - sync user from Clerk webhook
+// This endpoint is called by Clerk webhooks to sync user data. Deliveries are
+// verified with the Svix signature headers and applied once per svix-id.
+func (h *Handler) SyncClerkUser(c *gin.Context) {
+	if !h.webhooks.Configured() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Clerk webhooks are not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(body) > maxWebhookSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Webhook body too large"})
+		return
+	}
+
+	if err := h.webhooks.Verify(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+
+	var event clerkEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.Type == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook payload"})
+		return
+	}
+
+	var user clerkUser
+	if strings.HasPrefix(event.Type, "user.") {
+		if err := json.Unmarshal(event.Data, &user); err != nil || user.ID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user data"})
+			return
+		}
+	}
+
+	applied, err := h.applyClerkEvent(c.GetHeader("svix-id"), event.Type, user)
+	if err != nil {
+		log.Printf("Failed to apply Clerk %s webhook: %v", event.Type, err)
+		// A non-2xx response makes Svix retry the delivery
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"received":  true,
+		"duplicate": !applied,
+	})
+}
+
+// applyClerkEvent records svixID and applies the event in one transaction, so
+// a delivery is either applied and recorded or neither. It reports false for
+// deliveries that were already applied.
+func (h *Handler) applyClerkEvent(svixID, eventType string, user clerkUser) (bool, error) {
+	applied := false
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&ClerkWebhookEvent{SvixID: svixID, Type: eventType})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		applied = true
+
+		switch eventType {
+		case "user.created", "user.updated":
+			return upsertClerkUser(tx, user)
+		case "user.deleted":
+			return tx.Where("clerk_id = ?", user.ID).Delete(&User{}).Error
+		default:
+			// Other events are acknowledged so Svix stops retrying them
+			return nil
+		}
+	})
+	return applied, err
+}
+
+// upsertClerkUser creates or refreshes the user's row. The row keeps Clerk's
+// updated_at, so a delivery older than the stored version (Svix doesn't
+// guarantee order) is ignored. Soft-deleted users are left deleted, so a
+// delayed user.updated can't revive them.
+func upsertClerkUser(tx *gorm.DB, data clerkUser) error {
+	metadata := data.PublicMetadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	user := User{
+		ClerkID:  data.ID,
+		Email:    data.email(),
+		Name:     data.name(),
+		Role:     data.role(),
+		Metadata: metadata,
+	}
+	if data.UpdatedAt > 0 {
+		user.UpdatedAt = time.UnixMilli(data.UpdatedAt).UTC()
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "clerk_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "name", "role", "metadata", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "users.deleted_at IS NULL"},
+			clause.Expr{SQL: "users.updated_at <= excluded.updated_at"},
+		}},
+	}).Create(&user).Error
+}
+
+// email returns the user's primary email address, or their first one
+func (u clerkUser) email() string {
+	for _, address := range u.EmailAddresses {
+		if u.PrimaryEmailAddressID != nil && address.ID == *u.PrimaryEmailAddressID {
+			return address.EmailAddress
+		}
+	}
+	if len(u.EmailAddresses) > 0 {
+		return u.EmailAddresses[0].EmailAddress
+	}
+	return ""
+}
+
+// name returns the user's full name, falling back to their username
+func (u clerkUser) name() *string {
+	var parts []string
+	for _, part := range []*string{u.FirstName, u.LastName} {
+		if part != nil && strings.TrimSpace(*part) != "" {
+			parts = append(parts, strings.TrimSpace(*part))
+		}
+	}
+	name := strings.Join(parts, " ")
+	if name == "" && u.Username != nil {
+		name = strings.TrimSpace(*u.Username)
+	}
+	if name == "" {
+		return nil
+	}
+	return &name
+}
+
+// role maps public_metadata.role to a User role; only admin is elevated
+func (u clerkUser) role() string {
+	if role, _ := u.PublicMetadata["role"].(string); role == core.RoleAdmin {
+		return core.RoleAdmin
+	}
+	return RoleUser
+}
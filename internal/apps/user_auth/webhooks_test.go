@@ -0,0 +1,134 @@
+package user_auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+	"github.com/gin-gonic/gin"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testutil.OpenDB(t, &User{}, &ClerkWebhookEvent{})
+	return NewHandler(db, Options{WebhookSecrets: []string{whsec(testWebhookKey)}, WebhookTolerance: 5 * time.Minute})
+}
+
+// userEvent is a Clerk user webhook body for a user named name, updated at
+// updatedAt
+func userEvent(eventType, name string, updatedAt time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"type":%q,"data":{"id":"user_1","first_name":%q,
+		"primary_email_address_id":"idn_1","email_addresses":[{"id":"idn_1","email_address":"reader@example.com"}],
+		"public_metadata":{},"updated_at":%d}}`, eventType, name, updatedAt.UnixMilli()))
+}
+
+// deliver posts a webhook body signed now with key as svixID
+func deliver(t *testing.T, h *Handler, svixID string, key, body []byte) (int, map[string]any) {
+	t.Helper()
+	now := time.Now()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/sync", bytes.NewReader(body))
+	c.Request.Header = svixHeaders(svixID, now, svixSignature(key, svixID, now, body))
+	h.SyncClerkUser(c)
+
+	var response map[string]any
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func storedName(t *testing.T, h *Handler) string {
+	t.Helper()
+	var user User
+	if err := h.DB.Where("clerk_id = ?", "user_1").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.Name == nil {
+		return ""
+	}
+	return *user.Name
+}
+
+func TestSyncClerkUserRejectsBadSignatures(t *testing.T) {
+	h := newTestHandler(t)
+	body := userEvent("user.created", "Ada", time.Now())
+
+	if code, _ := deliver(t, h, "msg_1", rotatedOutKey, body); code != http.StatusUnauthorized {
+		t.Errorf("wrong key = %d, want 401", code)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/sync", bytes.NewReader(body))
+	stale := time.Now().Add(-10 * time.Minute)
+	c.Request.Header = svixHeaders("msg_1", stale, svixSignature(testWebhookKey, "msg_1", stale, body))
+	h.SyncClerkUser(c)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("stale delivery = %d, want 401", w.Code)
+	}
+
+	var count int64
+	h.DB.Model(&ClerkWebhookEvent{}).Count(&count)
+	if count != 0 {
+		t.Errorf("recorded %d rejected deliveries", count)
+	}
+}
+
+func TestSyncClerkUserAppliesEachDeliveryOnce(t *testing.T) {
+	h := newTestHandler(t)
+	created := time.Now().Add(-time.Minute)
+
+	if code, response := deliver(t, h, "msg_1", testWebhookKey, userEvent("user.created", "Ada", created)); code != http.StatusOK || response["duplicate"] != false {
+		t.Fatalf("user.created = %d %v", code, response)
+	}
+	if name := storedName(t, h); name != "Ada" {
+		t.Fatalf("name = %q, want Ada", name)
+	}
+
+	// A replayed svix-id is acknowledged but not applied, even with a
+	// different (validly signed) body
+	code, response := deliver(t, h, "msg_1", testWebhookKey, userEvent("user.updated", "Grace", created.Add(time.Second)))
+	if code != http.StatusOK || response["duplicate"] != true {
+		t.Errorf("replay = %d %v, want a duplicate", code, response)
+	}
+	if name := storedName(t, h); name != "Ada" {
+		t.Errorf("replay changed name to %q", name)
+	}
+}
+
+func TestSyncClerkUserIgnoresOutOfOrderUpdates(t *testing.T) {
+	h := newTestHandler(t)
+	created := time.Now().Add(-time.Minute)
+
+	deliver(t, h, "msg_1", testWebhookKey, userEvent("user.created", "Ada", created))
+	deliver(t, h, "msg_3", testWebhookKey, userEvent("user.updated", "Ada Lovelace", created.Add(2*time.Second)))
+
+	// An earlier update delivered late is acknowledged, but loses to the
+	// newer one already stored
+	if code, _ := deliver(t, h, "msg_2", testWebhookKey, userEvent("user.updated", "Ada L.", created.Add(time.Second))); code != http.StatusOK {
+		t.Fatalf("late user.updated = %d, want 200", code)
+	}
+	if name := storedName(t, h); name != "Ada Lovelace" {
+		t.Errorf("name = %q, want the newer Ada Lovelace", name)
+	}
+
+	deliver(t, h, "msg_4", testWebhookKey, userEvent("user.updated", "Countess", created.Add(3*time.Second)))
+	if name := storedName(t, h); name != "Countess" {
+		t.Errorf("name = %q, want the newest Countess", name)
+	}
+
+	// Deleted users stay deleted
+	deliver(t, h, "msg_5", testWebhookKey, []byte(`{"type":"user.deleted","data":{"id":"user_1","deleted":true}}`))
+	deliver(t, h, "msg_6", testWebhookKey, userEvent("user.updated", "Revived", created.Add(time.Hour)))
+	var live int64
+	h.DB.Model(&User{}).Count(&live)
+	if live != 0 {
+		t.Errorf("a user.updated after user.deleted revived the user")
+	}
+}
@@ -1,6 +1,7 @@
 package waitlist
 
 import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -12,8 +13,7 @@ func RegisterRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	waitlist := rg.Group("/waitlist")
 	{
 		waitlist.POST("/join", handler.Join)
-		waitlist.GET("/stats", handler.GetStats)
+		waitlist.GET("/stats", core.JWTRequired(), core.AdminRequired(), handler.GetStats)
 		// TODO: Add rate limiting middleware
-		// TODO: Add admin authentication for stats endpoint
 	}
 }
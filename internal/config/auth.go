@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+)
+
+// InitAuth installs the JWT verifier used by the auth middlewares
+func InitAuth(cfg *Config) {
+	authCfg, err := authConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid auth configuration: %v", err)
+	}
+	core.ConfigureAuth(authCfg)
+}
+
+// authConfig verifies tokens against JWKS_URL. The HS256 JWT_SECRET fallback
+// is for local development only: in production anyone who knew the secret
+// could mint admin tokens.
+func authConfig(cfg *Config) (core.AuthConfig, error) {
+	if cfg.JWKSURL == "" && cfg.Environment == "production" {
+		return core.AuthConfig{}, errors.New("JWKS_URL is required in production; JWT_SECRET tokens are only accepted in development")
+	}
+	if cfg.JWKSURL == "" {
+		log.Println("JWKS_URL not set, accepting HS256 tokens signed with JWT_SECRET")
+	}
+	return core.AuthConfig{
+		JWKSURL:  cfg.JWKSURL,
+		Secret:   cfg.JWTSecret,
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+		Leeway:   30 * time.Second,
+	}, nil
+}
@@ -2,22 +2,100 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	DatabaseURL    string
-	Port           string
-	Environment    string
-	OpenAIAPIKey   string
-	AWSRegion      string
-	AWSBucket      string
-	JWTSecret      string
-	RedisURL       string
-	AllowedOrigins []string
+	DatabaseURL  string
+	Port         string
+	Environment  string
+	OpenAIAPIKey string
+	AWSRegion    string
+	AWSBucket    string
+
+	// File storage: "local" (development) or "s3" (AWS or any S3-compatible service)
+	StorageBackend     string
+	StorageDir         string // local backend root directory
+	StoragePublicURL   string // URL the local backend's files are served from
+	StorageSigningKey  string // HMAC key for local backend file URLs
+	S3Endpoint         string // empty for AWS, e.g. localhost:9000 for MinIO
+	S3UseSSL           bool
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	CloudFrontURL      string
+
+	// Instagram ingestion reads recorded posts from a JSON file when set
+	InstagramPostsFile string
+	IngestInterval     time.Duration
+
+	JWTSecret   string // HS256 fallback when JWKSURL is empty; refused in production
+	JWKSURL     string
+	JWTIssuer   string
+	JWTAudience string
+
+	// Clerk webhook signing secrets (comma-separated while rotating)
+	ClerkWebhookSecrets   []string
+	ClerkWebhookTolerance time.Duration
+
+	StripeSecretKey        string
+	StripeWebhookSecret    string
+	StripeWebhookTolerance time.Duration
+	StripeCurrency         string
+
+	RedisURL         string
+	RealtimeBroker   string // "memory" (single instance) or "redis"
+	RateLimitBackend string // "memory" (per instance) or "redis" (shared)
+	SiteURL          string
+	APIURL           string // public URL of this API, for links in emails
+
+	// Client IPs, which rate limits key on, are read from X-Forwarded-For
+	// only when the connection comes from one of TrustedProxies (IPs or
+	// CIDRs), or from TrustedPlatformHeader (e.g. CF-Connecting-IP) if set.
+	// Otherwise the connection's address is used.
+	TrustedProxies        []string
+	TrustedPlatformHeader string
+
+	// Email (SMTP)
+	EmailTransport string // "smtp", "file" (writes .eml files) or "memory"
+	EmailFileDir   string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPTLS        string // "starttls", "tls" (implicit) or "none"
+	FromEmail      string
+	FromName       string
+
+	// Outbound email queue
+	EmailQueueConcurrency int
+	EmailQueueMaxAttempts int
+	EmailQueueBackoff     time.Duration
+
+	NewsletterSigningKey string // HMAC key for newsletter confirm/unsubscribe/preferences links
+
+	// Weekly digest send time, e.g. "sunday 17:00"; empty disables digests
+	DigestSchedule string
+	// Daily digest send time, e.g. "08:00"; empty sends daily subscribers
+	// the weekly digest
+	DigestDailyTime string
+	DigestTimezone  string
+
+	// CORS; AllowedOrigins also gates realtime WebSocket connections. It
+	// defaults to SiteURL's origin, and only holds "*" when
+	// CORS_ALLOW_ANY_ORIGIN is true.
+	AllowedOrigins       []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
 }
 
 // LoadConfig loads configuration from environment variables
@@ -28,20 +106,101 @@ func LoadConfig() *Config {
 	}
 
 	config := &Config{
-		DatabaseURL:    getEnv("DATABASE_URL", "postgres://localhost:5432/wat2do?sslmode=disable"),
-		Port:           getEnv("PORT", "8000"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
-		OpenAIAPIKey:   getEnv("OPENAI_API_KEY", ""),
-		AWSRegion:      getEnv("AWS_REGION", "us-east-1"),
-		AWSBucket:      getEnv("AWS_BUCKET", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		RedisURL:       getEnv("REDIS_URL", "localhost:6379"),
-		AllowedOrigins: []string{getEnv("ALLOWED_ORIGINS", "*")},
+		DatabaseURL:  getEnv("DATABASE_URL", "postgres://localhost:5432/wat2do?sslmode=disable"),
+		Port:         getEnv("PORT", "8000"),
+		Environment:  getEnv("ENVIRONMENT", "development"),
+		OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
+		AWSRegion:    getEnv("AWS_REGION", "us-east-1"),
+		AWSBucket:    getEnv("AWS_BUCKET", ""),
+
+		StorageBackend:     getEnv("STORAGE_BACKEND", "local"),
+		StorageDir:         getEnv("STORAGE_DIR", "./uploads"),
+		StoragePublicURL:   getEnv("STORAGE_PUBLIC_URL", "http://localhost:8000/storage"),
+		StorageSigningKey:  getEnv("STORAGE_SIGNING_KEY", ""),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3UseSSL:           getEnv("S3_USE_SSL", "true") != "false",
+		AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		CloudFrontURL:      getEnv("CLOUDFRONT_URL", ""),
+
+		InstagramPostsFile: getEnv("INSTAGRAM_POSTS_FILE", ""),
+		IngestInterval:     getEnvDuration("INGEST_INTERVAL", time.Hour),
+
+		JWTSecret:   getEnv("JWT_SECRET", ""),
+		JWKSURL:     getEnv("JWKS_URL", ""),
+		JWTIssuer:   getEnv("JWT_ISSUER", ""),
+		JWTAudience: getEnv("JWT_AUDIENCE", ""),
+
+		ClerkWebhookSecrets:   getEnvList("CLERK_WEBHOOK_SECRET", ""),
+		ClerkWebhookTolerance: getEnvDuration("CLERK_WEBHOOK_TOLERANCE", 5*time.Minute),
+
+		StripeSecretKey:        getEnv("STRIPE_SECRET_KEY", ""),
+		StripeWebhookSecret:    getEnv("STRIPE_WEBHOOK_SECRET", ""),
+		StripeWebhookTolerance: getEnvDuration("STRIPE_WEBHOOK_TOLERANCE", 5*time.Minute),
+		StripeCurrency:         getEnv("STRIPE_CURRENCY", "CAD"),
+
+		RedisURL:         getEnv("REDIS_URL", "localhost:6379"),
+		RealtimeBroker:   getEnv("REALTIME_BROKER", "memory"),
+		RateLimitBackend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+		SiteURL:          getEnv("SITE_URL", "https://wat2do.ca"),
+		APIURL:           getEnv("API_URL", "http://localhost:8000"),
+
+		TrustedProxies:        getEnvList("TRUSTED_PROXIES", ""),
+		TrustedPlatformHeader: getEnv("TRUSTED_PLATFORM_HEADER", ""),
+
+		EmailTransport: getEnv("EMAIL_TRANSPORT", "smtp"),
+		EmailFileDir:   getEnv("EMAIL_FILE_DIR", "./tmp/mail"),
+		SMTPHost:       getEnv("SMTP_HOST", ""),
+		SMTPPort:       getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPTLS:        getEnv("SMTP_TLS", "starttls"),
+		FromEmail:      getEnv("FROM_EMAIL", "noreply@wat2do.ca"),
+		FromName:       getEnv("FROM_NAME", "Wat2Do"),
+
+		EmailQueueConcurrency: getEnvInt("EMAIL_QUEUE_CONCURRENCY", 4),
+		EmailQueueMaxAttempts: getEnvInt("EMAIL_QUEUE_MAX_ATTEMPTS", 8),
+		EmailQueueBackoff:     getEnvDuration("EMAIL_QUEUE_BACKOFF", 30*time.Second),
+
+		NewsletterSigningKey: getEnv("NEWSLETTER_SIGNING_KEY", ""),
+
+		DigestSchedule:  getEnv("DIGEST_SCHEDULE", ""),
+		DigestDailyTime: getEnvAllowEmpty("DIGEST_DAILY_TIME", "08:00"),
+		DigestTimezone:  getEnv("DIGEST_TIMEZONE", "America/Toronto"),
+
+		AllowedOrigins:       getEnvList("ALLOWED_ORIGINS", ""),
+		CORSAllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
+		CORSAllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", "Accept,Authorization,Content-Type"),
+		CORSExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", "RateLimit-Policy,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"),
+		CORSAllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "true") != "false",
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 24*time.Hour),
 	}
+	config.AllowedOrigins = allowedOrigins(config.AllowedOrigins, config.SiteURL, getEnv("CORS_ALLOW_ANY_ORIGIN", "") == "true")
 
 	return config
 }
 
+// allowedOrigins defaults the browser origins to the site's own, and drops
+// "*" unless any origin was explicitly allowed: it would let every site
+// script the API and open realtime connections
+func allowedOrigins(origins []string, siteURL string, allowAny bool) []string {
+	if len(origins) == 0 {
+		if parsed, err := url.Parse(siteURL); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+			origins = []string{parsed.Scheme + "://" + parsed.Host}
+		}
+	}
+
+	allowed := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		if origin == "*" && !allowAny {
+			log.Println("Ignoring ALLOWED_ORIGINS=*; set CORS_ALLOW_ANY_ORIGIN=true to allow any origin")
+			continue
+		}
+		allowed = append(allowed, origin)
+	}
+	return allowed
+}
+
 // getEnv gets an environment variable with a fallback default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -50,3 +209,53 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvAllowEmpty is like getEnv, but an explicitly empty variable stays
+// empty so it can turn a feature off
+func getEnvAllowEmpty(key, defaultValue string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt parses an integer environment variable
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvList splits a comma-separated environment variable, dropping blank
+// entries
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvDuration parses a duration such as "30m" from an environment variable
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
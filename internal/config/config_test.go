@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name     string
+		origins  []string
+		allowAny bool
+		want     []string
+	}{
+		{"defaults to the site", nil, false, []string{"https://wat2do.ca"}},
+		{"configured", []string{"http://localhost:3000"}, false, []string{"http://localhost:3000"}},
+		{"any without opt-in", []string{"*", "https://wat2do.ca"}, false, []string{"https://wat2do.ca"}},
+		{"any with opt-in", []string{"*"}, true, []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := allowedOrigins(tt.origins, "https://wat2do.ca/events", tt.allowAny)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("allowedOrigins = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"jwks in production", Config{Environment: "production", JWKSURL: "https://clerk.example.com/.well-known/jwks.json"}, false},
+		{"secret in production", Config{Environment: "production", JWTSecret: "your_jwt_secret_here"}, true},
+		{"secret in development", Config{Environment: "development", JWTSecret: "dev-secret"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authCfg, err := authConfig(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authConfig error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (authCfg.JWKSURL != tt.cfg.JWKSURL || authCfg.Secret != tt.cfg.JWTSecret) {
+				t.Errorf("authConfig = %+v", authCfg)
+			}
+		})
+	}
+}
+
+func TestDigestDailyTime(t *testing.T) {
+	t.Setenv("DIGEST_DAILY_TIME", "")
+	if got := LoadConfig().DigestDailyTime; got != "" {
+		t.Errorf("empty DIGEST_DAILY_TIME = %q, want daily digests off", got)
+	}
+
+	t.Setenv("DIGEST_DAILY_TIME", "07:30")
+	if got := LoadConfig().DigestDailyTime; got != "07:30" {
+		t.Errorf("DIGEST_DAILY_TIME = %q, want 07:30", got)
+	}
+
+	os.Unsetenv("DIGEST_DAILY_TIME")
+	if got := LoadConfig().DigestDailyTime; got != "08:00" {
+		t.Errorf("unset DIGEST_DAILY_TIME = %q, want the 08:00 default", got)
+	}
+}
@@ -20,7 +20,7 @@ func InitDatabase(cfg *Config) *gorm.DB {
 
 	// Open database connection
 	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{
-		Logger: logger.Default.LogLevel(logLevel),
+		Logger: logger.Default.LogMode(logLevel),
 	})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
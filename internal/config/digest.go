@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/newsletter"
+	"gorm.io/gorm"
+)
+
+// StartDigest sends the weekly, and daily if configured, newsletter digests
+// in the background when a schedule is configured. Stop it by cancelling ctx.
+func StartDigest(ctx context.Context, db *gorm.DB, cfg *Config, svc *Services) {
+	if cfg.DigestSchedule == "" {
+		return
+	}
+
+	loc, err := time.LoadLocation(cfg.DigestTimezone)
+	if err != nil {
+		log.Fatalf("Invalid DIGEST_TIMEZONE %q: %v", cfg.DigestTimezone, err)
+	}
+	schedule, err := newsletter.ParseWeeklySchedule(cfg.DigestSchedule, loc)
+	if err != nil {
+		log.Fatalf("Invalid DIGEST_SCHEDULE: %v", err)
+	}
+
+	scheduler := &newsletter.DigestScheduler{
+		DB:       db,
+		Mailer:   svc.Newsletter,
+		Schedule: schedule,
+	}
+	log.Printf("Sending the newsletter digest every %s (%s)", cfg.DigestSchedule, cfg.DigestTimezone)
+	if cfg.DigestDailyTime != "" {
+		daily, err := newsletter.ParseDailySchedule(cfg.DigestDailyTime, loc)
+		if err != nil {
+			log.Fatalf("Invalid DIGEST_DAILY_TIME: %v", err)
+		}
+		scheduler.Daily = &daily
+		log.Printf("Sending the daily newsletter digest at %s (%s)", cfg.DigestDailyTime, cfg.DigestTimezone)
+	}
+	go scheduler.Run(ctx)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"context"
+	"log"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"gorm.io/gorm"
+)
+
+// InitEmail creates the email service with the transport selected by
+// cfg.EmailTransport. It sends synchronously until StartEmailQueue gives it
+// a queue.
+func InitEmail(cfg *Config) *services.EmailService {
+	email := services.NewEmailService(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername,
+		cfg.SMTPPassword, cfg.FromEmail, cfg.FromName)
+
+	transport := cfg.EmailTransport
+	if transport == "smtp" && cfg.SMTPHost == "" {
+		if cfg.Environment == "production" {
+			log.Fatal("SMTP_HOST is required in production")
+		}
+		log.Printf("SMTP_HOST not set, writing emails to %s", cfg.EmailFileDir)
+		transport = "file"
+	}
+
+	switch transport {
+	case "smtp":
+		switch cfg.SMTPTLS {
+		case services.SMTPStartTLS, services.SMTPImplicitTLS, services.SMTPNoTLS:
+		default:
+			log.Fatalf("Unknown SMTP_TLS %q (want starttls, tls or none)", cfg.SMTPTLS)
+		}
+		email.Transport = &services.SMTPTransport{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			TLS:      cfg.SMTPTLS,
+		}
+		log.Printf("Sending email through %s:%d (%s)", cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPTLS)
+
+	case "file":
+		files, err := services.NewFileTransport(cfg.EmailFileDir)
+		if err != nil {
+			log.Fatalf("Failed to initialize file email transport: %v", err)
+		}
+		email.Transport = files
+
+	case "memory":
+		email.Transport = &services.MemoryTransport{}
+
+	default:
+		log.Fatalf("Unknown EMAIL_TRANSPORT %q (want smtp, file or memory)", cfg.EmailTransport)
+	}
+
+	email.BulkConcurrency = cfg.EmailQueueConcurrency
+	return email
+}
+
+// StartEmailQueue switches svc.Email to the persistent outbound queue and
+// delivers from it in the background. Call it before anything sends email;
+// stop it by cancelling ctx.
+func StartEmailQueue(ctx context.Context, db *gorm.DB, cfg *Config, svc *Services) {
+	queue := services.NewEmailQueue(db, svc.Email.Transport, services.EmailQueueConfig{
+		Concurrency: cfg.EmailQueueConcurrency,
+		MaxAttempts: cfg.EmailQueueMaxAttempts,
+		Backoff:     cfg.EmailQueueBackoff,
+	})
+	svc.Email.Queue = queue
+	go queue.Run(ctx)
+}
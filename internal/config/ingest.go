@@ -0,0 +1,33 @@
+package config
+
+import (
+	"context"
+	"log"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+	"gorm.io/gorm"
+)
+
+// StartIngestion runs Instagram post ingestion in the background when a post
+// source is configured. Stop it by cancelling ctx.
+func StartIngestion(ctx context.Context, db *gorm.DB, cfg *Config, svc *Services) {
+	if cfg.InstagramPostsFile == "" {
+		return
+	}
+	if cfg.OpenAIAPIKey == "" {
+		log.Println("INSTAGRAM_POSTS_FILE is set but OPENAI_API_KEY isn't, skipping ingestion")
+		return
+	}
+
+	ingester := &events.Ingester{
+		DB:        db,
+		Source:    events.FilePostSource{Path: cfg.InstagramPostsFile},
+		Extractor: services.NewOpenAIService(cfg.OpenAIAPIKey),
+		Images:    services.NewImageService(svc.Storage),
+		Realtime:  svc.Realtime,
+		Interval:  cfg.IngestInterval,
+	}
+	log.Printf("Ingesting Instagram posts from %s every %s", cfg.InstagramPostsFile, cfg.IngestInterval)
+	go ingester.Run(ctx)
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+)
+
+// InitRateLimiter creates the rate limiter selected by cfg.RateLimitBackend
+func InitRateLimiter(cfg *Config) middleware.Limiter {
+	switch cfg.RateLimitBackend {
+	case "redis":
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		limiter, err := middleware.NewRedisLimiter(ctx, cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Failed to initialize Redis rate limiter: %v", err)
+		}
+		log.Printf("Using Redis rate limiter at %s", cfg.RedisURL)
+		return limiter
+
+	case "memory":
+		return middleware.NewMemoryLimiter(time.Minute)
+
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_BACKEND %q (want memory or redis)", cfg.RateLimitBackend)
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+)
+
+// InitRealtime creates the hub realtime updates are published through, on
+// the broker selected by cfg.RealtimeBroker
+func InitRealtime(cfg *Config) *realtime.Hub {
+	var broker realtime.Broker
+	switch cfg.RealtimeBroker {
+	case "redis":
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		redisBroker, err := realtime.NewRedisBroker(ctx, cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Failed to initialize Redis realtime broker: %v", err)
+		}
+		log.Printf("Using Redis realtime broker at %s", cfg.RedisURL)
+		broker = redisBroker
+
+	case "memory":
+		broker = realtime.NewMemoryBroker()
+
+	default:
+		log.Fatalf("Unknown REALTIME_BROKER %q (want memory or redis)", cfg.RealtimeBroker)
+	}
+
+	hub, err := realtime.NewHub(broker)
+	if err != nil {
+		log.Fatalf("Failed to initialize realtime hub: %v", err)
+	}
+	return hub
+}
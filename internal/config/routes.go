@@ -1,32 +1,81 @@
 package config
 
 import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/clubs"
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/emails"
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/events"
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/newsletter"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/payments"
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/promotions"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/user_auth"
 	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/waitlist"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// apiRateLimit is the default per-client limit across all /api routes;
+// stricter limits are set on individual routes
+var apiRateLimit = middleware.Policy{Name: "api", Limit: 300, Window: time.Minute}
+
+// apiRateLimitExempt are the webhook routes apiRateLimit doesn't apply to
+var apiRateLimitExempt = []string{"/api/payments/webhook", "/api/auth/sync"}
+
 // RegisterRoutes registers all application routes
-func RegisterRoutes(router *gin.Engine, db *gorm.DB) {
+func RegisterRoutes(router *gin.Engine, db *gorm.DB, cfg *Config, svc *Services) {
 	// Core routes
 	core.RegisterRoutes(router, db)
 
-	// API routes
-	api := router.Group("/api")
+	// Signed file URLs for the local storage backend
+	if local, ok := svc.Storage.(*services.LocalStorage); ok {
+		registerLocalStorageRoutes(router, local)
+	}
+
+	eventOptions := events.Options{
+		SiteURL:     cfg.SiteURL,
+		APIURL:      cfg.APIURL,
+		Images:      services.NewImageService(svc.Storage),
+		OpenAI:      services.NewOpenAIService(cfg.OpenAIAPIKey),
+		Realtime:    svc.Realtime,
+		RateLimiter: svc.RateLimiter,
+	}
+
+	// Root level feeds
+	events.RegisterRootRoutes(router, db, eventOptions)
+
+	// API routes. Claims are read before the limiter so signed-in users are
+	// limited per user rather than per IP. Webhooks arrive from a few provider
+	// IPs and are authenticated by signature, so they're exempt.
+	api := router.Group("/api",
+		core.OptionalJWT(),
+		middleware.SkipPaths(middleware.RateLimit(svc.RateLimiter, apiRateLimit), apiRateLimitExempt...),
+	)
 	{
 		// Events routes
-		events.RegisterRoutes(api, db)
+		events.RegisterRoutes(api, db, eventOptions)
 
 		// Clubs routes
 		clubs.RegisterRoutes(api, db)
 
 		// Newsletter routes
-		newsletter.RegisterRoutes(api, db)
+		newsletter.RegisterRoutes(api, db, newsletter.Options{
+			Mailer:       svc.Newsletter,
+			RateLimiter:  svc.RateLimiter,
+			DailyDigests: cfg.DigestSchedule != "" && cfg.DigestDailyTime != "",
+		})
+
+		// Email template preview routes
+		emails.RegisterRoutes(api, emails.Options{
+			Samples: newsletter.EmailSamples(cfg.SiteURL),
+		})
 
 		// Promotions routes
 		promotions.RegisterRoutes(api, db)
@@ -34,6 +83,38 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB) {
 		// Waitlist routes
 		waitlist.RegisterRoutes(api, db)
 
-		// TODO: Add other app routes (payments, realtime, user_auth)
+		// Realtime routes
+		realtime.RegisterRoutes(api, db, realtime.Options{
+			Hub:            svc.Realtime,
+			AllowedOrigins: cfg.AllowedOrigins,
+		})
+
+		// User auth routes
+		user_auth.RegisterRoutes(api, db, user_auth.Options{
+			WebhookSecrets:   cfg.ClerkWebhookSecrets,
+			WebhookTolerance: cfg.ClerkWebhookTolerance,
+		})
+
+		// Payments routes
+		payments.RegisterRoutes(api, db, payments.Options{
+			Stripe:           services.NewStripeAPIClient(cfg.StripeSecretKey),
+			WebhookSecret:    cfg.StripeWebhookSecret,
+			WebhookTolerance: cfg.StripeWebhookTolerance,
+			Currency:         cfg.StripeCurrency,
+			SiteURL:          cfg.SiteURL,
+		})
 	}
 }
+
+// registerLocalStorageRoutes serves local storage files under the path of
+// its BaseURL
+func registerLocalStorageRoutes(router *gin.Engine, storage *services.LocalStorage) {
+	mount := "/storage"
+	if base, err := url.Parse(storage.BaseURL); err == nil && base.Path != "" && base.Path != "/" {
+		mount = strings.TrimRight(base.Path, "/")
+	}
+
+	handler := gin.WrapH(http.StripPrefix(mount, storage))
+	router.GET(mount+"/*key", handler)
+	router.HEAD(mount+"/*key", handler)
+}
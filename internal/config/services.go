@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/newsletter"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/realtime"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/middleware"
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/services"
+)
+
+// Services holds the shared backends the apps are wired to
+type Services struct {
+	Storage     services.Storage
+	Realtime    *realtime.Hub
+	RateLimiter middleware.Limiter
+	Email       *services.EmailService
+	Newsletter  *newsletter.Mailer
+}
+
+// InitServices creates the backends selected by cfg
+func InitServices(cfg *Config) *Services {
+	svc := &Services{
+		Storage:     InitStorage(cfg),
+		Realtime:    InitRealtime(cfg),
+		RateLimiter: InitRateLimiter(cfg),
+		Email:       InitEmail(cfg),
+	}
+	svc.Newsletter = &newsletter.Mailer{
+		Email:   svc.Email,
+		Signer:  newsletter.NewSigner(signingKey(cfg, cfg.NewsletterSigningKey, "NEWSLETTER_SIGNING_KEY")),
+		SiteURL: cfg.SiteURL,
+		APIURL:  cfg.APIURL,
+	}
+	return svc
+}
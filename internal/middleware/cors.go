@@ -1,42 +1,225 @@
 package middleware
 
 import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-// CORS returns a middleware that handles Cross-Origin Resource Sharing
-func CORS(allowedOrigins []string) gin.HandlerFunc {
+// CORSConfig configures the CORS middleware
+type CORSConfig struct {
+	// AllowedOrigins are exact origins ("https://wat2do.ca"), wildcard
+	// subdomain patterns ("https://*.wat2do.ca") or "*" for any origin
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string // "*" allows any requested header
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies and read responses to
+	// credentialed requests. It never applies to origins matched only by
+	// "*", which the CORS spec forbids.
+	AllowCredentials bool
+	MaxAge           time.Duration // how long browsers may cache a preflight
+}
+
+// CORS returns a middleware that handles Cross-Origin Resource Sharing. It
+// must be registered with router.Use before any routes so that preflight
+// requests to unregistered OPTIONS routes reach it.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	origins := NewOriginMatcher(cfg.AllowedOrigins)
+	methods := toSet(cfg.AllowedMethods, strings.ToUpper)
+	headers := toSet(cfg.AllowedHeaders, http.CanonicalHeaderKey)
+	_, anyHeader := headers["*"]
+
+	allowMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
 	return func(c *gin.Context) {
-		// TODO: Implement CORS headers
-		// 1. Check if origin is in allowed list
-		// 2. Set appropriate CORS headers
-		// 3. Handle preflight OPTIONS requests
-
-		origin := c.Request.Header.Get("Origin")
-
-		// Check if origin is allowed
-		allowed := false
-		for _, allowedOrigin := range allowedOrigins {
-			if allowedOrigin == "*" || allowedOrigin == origin {
-				allowed = true
-				break
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		header := c.Writer.Header()
+		// Responses differ by Origin, so caches must key on it
+		header.Add("Vary", "Origin")
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			// Not a cross-origin browser request
+			c.Next()
+			return
+		}
+
+		explicit, allowed := origins.Match(origin)
+		if !allowed {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// Let the request through without CORS headers; the browser
+			// withholds the response from the page
+			c.Next()
+			return
+		}
+
+		allowOrigin := func() {
+			if explicit {
+				header.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+			} else {
+				header.Set("Access-Control-Allow-Origin", "*")
 			}
 		}
 
-		if allowed {
-			c.Header("Access-Control-Allow-Origin", origin)
-			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Header("Access-Control-Max-Age", "86400")
+		if !preflight {
+			allowOrigin()
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
 		}
 
-		// Handle preflight request
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		// Preflight: only approve the method and headers we accept
+		method := strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))
+		if _, ok := methods[method]; !ok {
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		c.Next()
+		requested := c.GetHeader("Access-Control-Request-Headers")
+		for _, name := range strings.Split(requested, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if _, ok := headers[name]; name != "" && !ok && !anyHeader {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+
+		allowOrigin()
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if anyHeader {
+			// Echo the request, since "*" isn't honoured with credentials
+			header.Set("Access-Control-Allow-Headers", requested)
+		} else if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func toSet(values []string, normalize func(string) string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[normalize(strings.TrimSpace(value))] = struct{}{}
+	}
+	return set
+}
+
+// OriginMatcher matches browser origins against configured origin patterns
+type OriginMatcher struct {
+	any      bool
+	exact    map[string]struct{}
+	suffixes []originSuffix
+}
+
+// originSuffix is a parsed "scheme://*.domain[:port]" pattern
+type originSuffix struct {
+	prefix string // "scheme://"
+	suffix string // ".domain[:port]"
+}
+
+// NewOriginMatcher parses patterns: exact origins, wildcard subdomain
+// patterns such as "https://*.wat2do.ca" and "*". Invalid patterns are
+// logged and ignored.
+func NewOriginMatcher(patterns []string) *OriginMatcher {
+	m := &OriginMatcher{exact: map[string]struct{}{}}
+	for _, pattern := range patterns {
+		pattern = normalizeOrigin(pattern)
+		switch {
+		case pattern == "":
+			continue
+
+		case pattern == "*":
+			m.any = true
+
+		case strings.Contains(pattern, "*"):
+			scheme, host, ok := strings.Cut(pattern, "://")
+			if !ok || scheme == "" || !strings.HasPrefix(host, "*.") ||
+				len(host) == len("*.") || strings.ContainsAny(host[1:], "*/") {
+				log.Printf("Ignoring invalid CORS origin pattern %q", pattern)
+				continue
+			}
+			m.suffixes = append(m.suffixes, originSuffix{prefix: scheme + "://", suffix: host[1:]})
+
+		default:
+			if !strings.Contains(pattern, "://") {
+				log.Printf("Ignoring CORS origin %q without a scheme", pattern)
+				continue
+			}
+			m.exact[pattern] = struct{}{}
+		}
+	}
+	return m
+}
+
+// Match reports whether origin is allowed, and whether it's allowed by an
+// exact origin or subdomain pattern rather than only by "*"
+func (m *OriginMatcher) Match(origin string) (explicit, allowed bool) {
+	origin = normalizeOrigin(origin)
+	if _, ok := m.exact[origin]; ok {
+		return true, true
+	}
+	for _, s := range m.suffixes {
+		if !strings.HasPrefix(origin, s.prefix) || !strings.HasSuffix(origin, s.suffix) {
+			continue
+		}
+		// The wildcard covers one or more subdomain labels
+		sub := strings.TrimSuffix(strings.TrimPrefix(origin, s.prefix), s.suffix)
+		if isSubdomain(sub) {
+			return true, true
+		}
+	}
+	return false, m.any
+}
+
+// Allows reports whether origin is allowed
+func (m *OriginMatcher) Allows(origin string) bool {
+	_, allowed := m.Match(origin)
+	return allowed
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+}
+
+// isSubdomain reports whether s is a dot-separated list of DNS labels
+func isSubdomain(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
 	}
+	return true
 }
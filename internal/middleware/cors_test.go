@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsRouter serves GET and POST /api/events behind the CORS middleware
+func corsRouter(cfg CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(cfg))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	router.GET("/api/events", ok)
+	router.POST("/api/events", ok)
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/events", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// preflight asks to send a method with headers from origin
+func preflight(router *gin.Engine, origin, method, headers string) *httptest.ResponseRecorder {
+	header := map[string]string{"Access-Control-Request-Method": method}
+	if headers != "" {
+		header["Access-Control-Request-Headers"] = headers
+	}
+	return corsRequest(router, http.MethodOptions, origin, header)
+}
+
+var siteCORS = CORSConfig{
+	AllowedOrigins:   []string{"https://wat2do.ca", "https://*.wat2do.ca", "http://localhost:5173"},
+	AllowedMethods:   []string{"GET", "POST", "DELETE"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type"},
+	ExposedHeaders:   []string{"Retry-After"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := corsRouter(siteCORS)
+
+	tests := []struct {
+		name, origin, method, headers string
+		code                          int
+	}{
+		{"allowed", "https://wat2do.ca", "POST", "content-type, authorization", http.StatusNoContent},
+		{"no headers", "https://wat2do.ca", "DELETE", "", http.StatusNoContent},
+		{"lower-case method", "https://wat2do.ca", "post", "", http.StatusNoContent},
+		{"subdomain", "https://preview.wat2do.ca", "GET", "", http.StatusNoContent},
+		{"local dev", "http://localhost:5173", "GET", "", http.StatusNoContent},
+		{"disallowed method", "https://wat2do.ca", "PUT", "", http.StatusForbidden},
+		{"disallowed header", "https://wat2do.ca", "POST", "Content-Type, X-Admin", http.StatusForbidden},
+		{"disallowed origin", "https://evil.example", "GET", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := preflight(router, tt.origin, tt.method, tt.headers)
+		if w.Code != tt.code {
+			t.Errorf("%s: preflight = %d, want %d", tt.name, w.Code, tt.code)
+			continue
+		}
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.code != http.StatusNoContent {
+			if got != "" {
+				t.Errorf("%s: refused preflight allowed origin %q", tt.name, got)
+			}
+			continue
+		}
+		if got != tt.origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", tt.name, got, tt.origin)
+		}
+		for name, want := range map[string]string{
+			"Access-Control-Allow-Methods":     "GET, POST, DELETE",
+			"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Max-Age":           "600",
+		} {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got, want)
+			}
+		}
+		if vary := strings.Join(w.Header().Values("Vary"), ", "); vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+			t.Errorf("%s: Vary = %q", tt.name, vary)
+		}
+	}
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	router := corsRouter(siteCORS)
+
+	tests := []struct {
+		name, origin, allowOrigin string
+	}{
+		{"allowed", "https://wat2do.ca", "https://wat2do.ca"},
+		{"trailing slash and case", "HTTPS://WAT2DO.CA/", "HTTPS://WAT2DO.CA/"},
+		{"nested subdomain", "https://a.b.wat2do.ca", "https://a.b.wat2do.ca"},
+		{"bare wildcard", "https://.wat2do.ca", ""},
+		{"lookalike domain", "https://evilwat2do.ca", ""},
+		{"suffix attack", "https://wat2do.ca.evil.example", ""},
+		{"wrong scheme", "http://preview.wat2do.ca", ""},
+		{"wrong port", "https://preview.wat2do.ca:8443", ""},
+		{"invalid label", "https://-bad.wat2do.ca", ""},
+		{"path smuggling", "https://evil.example/x.wat2do.ca", ""},
+		{"same origin", "", ""},
+	}
+	for _, tt := range tests {
+		// The request always reaches the handler; only the headers decide
+		// whether the browser shares the response
+		w := corsRequest(router, http.MethodGet, tt.origin, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: GET = %d", tt.name, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want %q", tt.name, got, tt.allowOrigin)
+		}
+		wantExposed := map[bool]string{true: "Retry-After"}[tt.allowOrigin != ""]
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != wantExposed {
+			t.Errorf("%s: Access-Control-Expose-Headers = %q, want %q", tt.name, got, wantExposed)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("%s: Vary = %q", tt.name, got)
+		}
+	}
+
+	// An OPTIONS request that isn't a preflight goes to the router
+	if w := corsRequest(router, http.MethodOptions, "https://wat2do.ca", nil); w.Code == http.StatusNoContent {
+		t.Error("plain OPTIONS request was answered as a preflight")
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := siteCORS
+	cfg.AllowedOrigins = []string{"*", "https://wat2do.ca"}
+	cfg.AllowedHeaders = []string{"*"}
+	router := corsRouter(cfg)
+
+	// Origins matched only by "*" never get credentials
+	w := preflight(router, "https://anywhere.example", "POST", "X-Custom, Content-Type")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight = %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("wildcard origin allowed credentials")
+	}
+	// Any header is approved by echoing the request
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "X-Custom, Content-Type" {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+
+	w = corsRequest(router, http.MethodGet, "https://wat2do.ca", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://wat2do.ca" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("listed origin lost its credentials: %v", w.Header())
+	}
+}
+
+func TestNewOriginMatcherIgnoresInvalidPatterns(t *testing.T) {
+	m := NewOriginMatcher([]string{"wat2do.ca", "https://*", "https://a.*.wat2do.ca", "*.wat2do.ca", "https://*.wat2do.ca/path", " https://ok.example/ "})
+
+	for origin, want := range map[string]bool{
+		"https://wat2do.ca":         false,
+		"https://x.wat2do.ca":       false,
+		"https://a.b.wat2do.ca":     false,
+		"https://anything":          false,
+		"https://ok.example":        true,
+		"https://x.wat2do.ca/path":  false,
+		"https://x.wat2do.ca/path/": false,
+	} {
+		if got := m.Allows(origin); got != want {
+			t.Errorf("Allows(%q) = %t, want %t", origin, got, want)
+		}
+	}
+}
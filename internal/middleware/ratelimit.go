@@ -1,64 +1,74 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimiter provides rate limiting functionality
-type RateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.Mutex
-	rate     int           // requests per window
-	window   time.Duration // time window
+// Policy allows Limit requests per Window for each client, refilling
+// continuously (a token bucket), so a client that used its allowance gets a
+// request back every Window/Limit rather than all at once
+type Policy struct {
+	Name   string // separates counters of policies applied to the same client
+	Limit  int
+	Window time.Duration
 }
 
-// NewRateLimiter creates a new rate limiter
-// Parameters:
-//   - rate: maximum number of requests allowed
-//   - window: time window for rate limiting (e.g., 1 hour)
-func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		requests: make(map[string][]time.Time),
-		rate:     rate,
-		window:   window,
-	}
+// Result is the outcome of a limiter check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the allowance is fully restored
+	RetryAfter time.Duration // until the next request is allowed; 0 if allowed
+}
+
+// Limiter takes one request from key's allowance under policy
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
 }
 
-// Limit returns a middleware that enforces rate limiting
-func (rl *RateLimiter) Limit() gin.HandlerFunc {
+// RateLimit returns a middleware that enforces policy through limiter. Clients
+// are identified by user ID when an auth middleware has run before it, and
+// by IP otherwise. The IP is gin's ClientIP, so the engine's trusted proxies
+// must be set, or clients can choose it. If the limiter fails, requests are
+// let through; a nil limiter disables limiting.
+func RateLimit(limiter Limiter, policy Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: Implement rate limiting logic
-		// 1. Get client identifier (IP address)
-		// 2. Check request history for this client
-		// 3. Count requests in current window
-		// 4. If over limit, return 429 Too Many Requests
-		// 5. Otherwise, record request and continue
-
-		clientIP := c.ClientIP()
-
-		rl.mu.Lock()
-		defer rl.mu.Unlock()
-
-		now := time.Now()
-		cutoff := now.Add(-rl.window)
-
-		// Clean old requests
-		if requests, exists := rl.requests[clientIP]; exists {
-			filtered := []time.Time{}
-			for _, reqTime := range requests {
-				if reqTime.After(cutoff) {
-					filtered = append(filtered, reqTime)
-				}
-			}
-			rl.requests[clientIP] = filtered
+		if limiter == nil {
+			c.Next()
+			return
 		}
 
-		// Check rate limit
-		if len(rl.requests[clientIP]) >= rl.rate {
+		key := "ip:" + c.ClientIP()
+		if userID := c.GetString(core.ContextUserID); userID != "" {
+			key = "user:" + userID
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), policy.Name+":"+key, policy)
+		if err != nil {
+			log.Printf("Rate limiter failed, allowing request: %v", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Rate limit exceeded",
 			})
@@ -66,17 +76,123 @@ func (rl *RateLimiter) Limit() gin.HandlerFunc {
 			return
 		}
 
-		// Record request
-		rl.requests[clientIP] = append(rl.requests[clientIP], now)
-
 		c.Next()
 	}
 }
 
-// RateLimit creates a simple rate limiting middleware
-// Parameters:
-//   - requestsPerHour: maximum requests per hour
-func RateLimit(requestsPerHour int) gin.HandlerFunc {
-	limiter := NewRateLimiter(requestsPerHour, time.Hour)
-	return limiter.Limit()
+// SkipPaths wraps a middleware so it doesn't run for requests to the given
+// routes, named as registered (e.g. "/api/payments/webhook")
+func SkipPaths(handler gin.HandlerFunc, paths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucketResult derives a Result from the tokens left in a bucket
+func bucketResult(policy Policy, tokens float64, allowed bool) Result {
+	perToken := policy.Window / time.Duration(policy.Limit)
+	result := Result{
+		Allowed:   allowed,
+		Limit:     policy.Limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(policy.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return result
+}
+
+// MemoryLimiter is a per-process Limiter. Buckets that have refilled are
+// evicted in the background, so idle clients don't accumulate.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	stop    chan struct{}
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+// NewMemoryLimiter creates a limiter that evicts idle buckets every
+// evictEvery. Call Close to stop eviction.
+func NewMemoryLimiter(evictEvery time.Duration) *MemoryLimiter {
+	l := &MemoryLimiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+	go l.evictLoop(evictEvery)
+	return l
+}
+
+// Allow takes a token from key's bucket
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), updated: now, window: policy.Window}
+		l.buckets[key] = b
+	}
+
+	rate := float64(policy.Limit) / float64(policy.Window)
+	b.tokens = math.Min(float64(policy.Limit), b.tokens+float64(now.Sub(b.updated))*rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(policy, b.tokens, allowed), nil
+}
+
+// Close stops background eviction
+func (l *MemoryLimiter) Close() {
+	close(l.stop)
+}
+
+func (l *MemoryLimiter) evictLoop(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.evictIdle()
+		}
+	}
+}
+
+// evictIdle drops buckets untouched for a whole window; they'd be full again
+func (l *MemoryLimiter) evictIdle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(l.buckets, key)
+		}
+	}
 }
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/apps/core"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func testToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// limitedRouter mirrors the server: no trusted proxies, and an /api group
+// with optional claims, then a global limit of one request per client with
+// a webhook exempt
+func limitedRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	core.ConfigureAuth(core.AuthConfig{Secret: testSecret})
+
+	limiter := NewMemoryLimiter(time.Minute)
+	t.Cleanup(limiter.Close)
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatal(err)
+	}
+	api := router.Group("/api",
+		core.OptionalJWT(),
+		SkipPaths(RateLimit(limiter, Policy{Name: "api", Limit: 1, Window: time.Minute}), "/api/webhook"),
+	)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, c.GetString(core.ContextUserID)) }
+	api.GET("/events", ok)
+	api.GET("/me", core.JWTRequired(), ok)
+	api.POST("/webhook", ok)
+	return router
+}
+
+func request(router *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	return forwardedRequest(router, method, path, token, "")
+}
+
+// forwardedRequest sends a request claiming to be forwarded for forwardedFor
+func forwardedRequest(router *gin.Engine, method, path, token, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	req.RemoteAddr = "203.0.113.7:1234" // every client behind one NAT
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitKeysSignedInUsersByID(t *testing.T) {
+	router := limitedRouter(t)
+	alice, bob := testToken(t, "alice"), testToken(t, "bob")
+
+	if w := request(router, http.MethodGet, "/api/events", alice); w.Code != http.StatusOK {
+		t.Fatalf("alice = %d", w.Code)
+	}
+	// Same IP, different user: a separate allowance
+	if w := request(router, http.MethodGet, "/api/me", bob); w.Code != http.StatusOK || w.Body.String() != "bob" {
+		t.Fatalf("bob = %d %q", w.Code, w.Body)
+	}
+	if w := request(router, http.MethodGet, "/api/events", ""); w.Code != http.StatusOK {
+		t.Fatalf("anonymous = %d", w.Code)
+	}
+
+	if w := request(router, http.MethodGet, "/api/me", alice); w.Code != http.StatusTooManyRequests {
+		t.Errorf("alice again = %d, want 429", w.Code)
+	}
+	if w := request(router, http.MethodGet, "/api/events", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("anonymous again = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitSkipsExemptPaths(t *testing.T) {
+	router := limitedRouter(t)
+
+	for i := 0; i < 3; i++ {
+		w := request(router, http.MethodPost, "/api/webhook", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("webhook %d = %d, want 200", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("webhook %d carries rate limit headers", i)
+		}
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	router := limitedRouter(t)
+
+	if w := forwardedRequest(router, http.MethodGet, "/api/events", "", "198.51.100.1"); w.Code != http.StatusOK {
+		t.Fatalf("first request = %d", w.Code)
+	}
+	// A new X-Forwarded-For from the same connection is the same client
+	if w := forwardedRequest(router, http.MethodGet, "/api/events", "", "198.51.100.2"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitTrustsConfiguredProxies(t *testing.T) {
+	router := limitedRouter(t)
+	if err := router.SetTrustedProxies([]string{"203.0.113.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Behind our proxy, forwarded clients get their own allowance
+	for _, client := range []string{"198.51.100.1", "198.51.100.2"} {
+		if w := forwardedRequest(router, http.MethodGet, "/api/events", "", client); w.Code != http.StatusOK {
+			t.Errorf("client %s = %d, want 200", client, w.Code)
+		}
+	}
+	if w := forwardedRequest(router, http.MethodGet, "/api/events", "", "198.51.100.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("client 198.51.100.1 again = %d, want 429", w.Code)
+	}
+}
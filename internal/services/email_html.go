@@ -0,0 +1,334 @@
+package services
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// cssRule is one selector of a stylesheet rule, e.g. "a.button"
+type cssRule struct {
+	tag          string   // empty matches any element
+	classes      []string // all must be present
+	declarations [][2]string
+	specificity  int
+	order        int
+}
+
+var cssComments = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// InlineCSS copies the rules of the document's <style> elements onto the
+// style attributes of the elements they match, since many mail clients
+// ignore stylesheets. Only tag, .class and tag.class selectors are inlined;
+// @-rules such as @media stay in the stylesheet for clients that support it.
+// Existing style attributes take precedence.
+func InlineCSS(document string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	var rules []cssRule
+	walk(root, func(n *html.Node) {
+		if n.DataAtom == atom.Style && n.FirstChild != nil {
+			rules = append(rules, parseCSS(n.FirstChild.Data, len(rules))...)
+		}
+	})
+	if len(rules) == 0 {
+		return document, nil
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].specificity != rules[j].specificity {
+			return rules[i].specificity < rules[j].specificity
+		}
+		return rules[i].order < rules[j].order
+	})
+
+	walk(root, func(n *html.Node) {
+		if n.Type != html.ElementNode || n.DataAtom == atom.Style || n.DataAtom == atom.Head {
+			return
+		}
+		var properties []string
+		values := map[string]string{}
+		set := func(property, value string) {
+			if _, ok := values[property]; !ok {
+				properties = append(properties, property)
+			}
+			values[property] = value
+		}
+
+		classes := strings.Fields(attr(n, "class"))
+		for _, rule := range rules {
+			if rule.matches(n.Data, classes) {
+				for _, d := range rule.declarations {
+					set(d[0], d[1])
+				}
+			}
+		}
+		if len(properties) == 0 {
+			return
+		}
+		for _, d := range parseDeclarations(attr(n, "style")) {
+			set(d[0], d[1])
+		}
+
+		declarations := make([]string, len(properties))
+		for i, property := range properties {
+			declarations[i] = property + ":" + values[property]
+		}
+		setAttr(n, "style", strings.Join(declarations, ";"))
+	})
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, root); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parseCSS parses the inlinable rules of a stylesheet, numbering them from
+// order
+func parseCSS(css string, order int) []cssRule {
+	css = cssComments.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	for len(css) > 0 {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		selectors := strings.TrimSpace(css[:open])
+
+		// Skip @-rules, including any nested blocks
+		end, depth := open, 0
+		for ; end < len(css); end++ {
+			if css[end] == '{' {
+				depth++
+			} else if css[end] == '}' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		body := css[open+1 : min(end, len(css))]
+		css = css[min(end+1, len(css)):]
+		if strings.HasPrefix(selectors, "@") {
+			continue
+		}
+
+		declarations := parseDeclarations(body)
+		for _, selector := range strings.Split(selectors, ",") {
+			rule, ok := parseSelector(strings.TrimSpace(selector))
+			if !ok {
+				continue
+			}
+			rule.declarations = declarations
+			rule.order = order
+			order++
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseSelector parses "tag", ".class" or "tag.class.other"
+func parseSelector(selector string) (cssRule, bool) {
+	if selector == "" || strings.ContainsAny(selector, " >+~:[#*") {
+		return cssRule{}, false
+	}
+	parts := strings.Split(selector, ".")
+	rule := cssRule{tag: strings.ToLower(parts[0]), classes: parts[1:]}
+	for _, class := range rule.classes {
+		if class == "" {
+			return cssRule{}, false
+		}
+	}
+	rule.specificity = 10 * len(rule.classes)
+	if rule.tag != "" {
+		rule.specificity++
+	}
+	return rule, true
+}
+
+// parseDeclarations parses "color: red; margin: 0" into property-value pairs
+func parseDeclarations(block string) [][2]string {
+	var declarations [][2]string
+	for _, declaration := range strings.Split(block, ";") {
+		property, value, ok := strings.Cut(declaration, ":")
+		property, value = strings.ToLower(strings.TrimSpace(property)), strings.TrimSpace(value)
+		if ok && property != "" && value != "" {
+			declarations = append(declarations, [2]string{property, value})
+		}
+	}
+	return declarations
+}
+
+func (r cssRule) matches(tag string, classes []string) bool {
+	if r.tag != "" && r.tag != tag {
+		return false
+	}
+	for _, want := range r.classes {
+		found := false
+		for _, class := range classes {
+			if class == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// blockElements start on a new line in HTMLToText
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.H1: true, atom.H2: true, atom.H3: true,
+	atom.H4: true, atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true,
+	atom.Tr: true, atom.Blockquote: true, atom.Hr: true,
+}
+
+// HTMLToText renders an HTML email as plain text: headings upper-cased, list
+// items bulleted and links followed by their URL
+func HTMLToText(document string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+
+	var b textBuilder
+	var render func(n *html.Node)
+	render = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			b.text(n.Data)
+			return
+		case html.ElementNode:
+		default:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				render(c)
+			}
+			return
+		}
+
+		switch n.DataAtom {
+		case atom.Head, atom.Style, atom.Script, atom.Title:
+			return
+		case atom.Br:
+			b.newline(1)
+			return
+		case atom.Img:
+			b.text(attr(n, "alt"))
+			return
+		}
+
+		block := blockElements[n.DataAtom]
+		heading := n.DataAtom == atom.H1 || n.DataAtom == atom.H2
+		if block {
+			b.newline(1)
+		}
+		if heading {
+			b.newline(2)
+			b.upper++
+		}
+		if n.DataAtom == atom.Li {
+			b.text("- ")
+		}
+
+		start := b.buf.Len()
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			render(c)
+		}
+
+		if href := attr(n, "href"); n.DataAtom == atom.A && strings.HasPrefix(href, "http") {
+			if label := strings.TrimSpace(b.buf.String()[start:]); label != href {
+				upper := b.upper
+				b.upper = 0 // URLs are case-sensitive
+				b.text(" (" + href + ")")
+				b.upper = upper
+			}
+		}
+		if heading {
+			b.upper--
+		}
+		if block {
+			b.newline(1)
+		}
+		if n.DataAtom == atom.P || n.DataAtom == atom.Ul || n.DataAtom == atom.Ol || heading {
+			b.newline(2)
+		}
+	}
+	render(root)
+
+	return strings.TrimSpace(b.buf.String()) + "\n", nil
+}
+
+// textBuilder accumulates HTMLToText output, collapsing whitespace within
+// text and limiting blank lines
+type textBuilder struct {
+	buf      strings.Builder
+	newlines int  // trailing newlines written
+	space    bool // a space is pending before the next text
+	upper    int  // inside a heading
+}
+
+func (b *textBuilder) text(s string) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		b.space = b.space || s != ""
+		return
+	}
+	if b.buf.Len() > 0 && b.newlines == 0 && (b.space || s[0] == ' ' || s[0] == '\n' || s[0] == '\t') {
+		b.buf.WriteByte(' ')
+	}
+	text := strings.Join(words, " ")
+	if b.upper > 0 {
+		text = strings.ToUpper(text)
+	}
+	b.buf.WriteString(text)
+	last := s[len(s)-1]
+	b.space = last == ' ' || last == '\n' || last == '\t'
+	b.newlines = 0
+}
+
+// newline ends the line, leaving up to n-1 blank lines
+func (b *textBuilder) newline(n int) {
+	if b.buf.Len() == 0 {
+		return
+	}
+	for ; b.newlines < n; b.newlines++ {
+		b.buf.WriteByte('\n')
+	}
+	b.space = false
+}
+
+func walk(n *html.Node, visit func(*html.Node)) {
+	visit(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, value string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: value})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineCSS(t *testing.T) {
+	document := `<html><head><style>
+/* p { color: gray } */
+p { color: black; margin: 0 }
+.note { color: blue }
+p.note.big { font-size: 20px }
+a, .link { color: red }
+div p, p:hover, #id, * { color: green }
+@media (max-width: 600px) { p { color: purple } }
+p { margin: 4px }
+</style></head><body>
+<p>plain</p>
+<p class="note">note</p>
+<p class="big note" style="color: orange">big</p>
+<span class="link">link</span>
+<em>unstyled</em>
+</body></html>`
+
+	got, err := InlineCSS(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		// Later rules of equal specificity win; comments are ignored
+		`<p style="color:black;margin:4px">plain</p>`,
+		// Class selectors outrank tags
+		`<p class="note" style="color:blue;margin:4px">note</p>`,
+		// Existing style attributes outrank every rule
+		`<p class="big note" style="color:orange;margin:4px;font-size:20px">big</p>`,
+		// Selector lists apply to each selector
+		`<span class="link" style="color:red">link</span>`,
+		`<em>unstyled</em>`,
+		// The stylesheet stays for clients that use it
+		`@media (max-width: 600px)`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("inlined document is missing %s:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"color:green", "color:purple", "color:gray"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("unsupported, @media or commented-out rule inlined as %s:\n%s", unwanted, got)
+		}
+	}
+
+	// Documents without a stylesheet are returned as they are
+	plain := "<p>hello</p>"
+	if got, err := InlineCSS(plain); err != nil || got != plain {
+		t.Errorf("InlineCSS(%q) = %q, %v", plain, got, err)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	document := `<html><head><title>Subject</title><style>p { color: red }</style></head><body>
+<h1>This   week</h1>
+<p>Three events,
+   all <b>free</b>.</p>
+<h2>Popular</h2>
+<ul>
+  <li><a href="https://wat2do.ca/events/1">Games night</a><br>Mon · 7:00 PM</li>
+  <li><a href="https://wat2do.ca/events/2">https://wat2do.ca/events/2</a></li>
+</ul>
+<p><img src="logo.png" alt="Wat2Do"> <a href="/relative">relative link</a></p>
+<script>alert(1)</script>
+</body></html>`
+
+	got, err := HTMLToText(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `THIS WEEK
+
+Three events, all free.
+
+POPULAR
+
+- Games night (https://wat2do.ca/events/1)
+Mon · 7:00 PM
+- https://wat2do.ca/events/2
+
+Wat2Do relative link
+`
+	if got != want {
+		t.Errorf("HTMLToText =\n%s\nwant\n%s", got, want)
+	}
+
+	// Link URLs keep their case inside headings
+	got, _ = HTMLToText(`<h2><a href="https://wat2do.ca/Events">Events</a></h2>`)
+	if got != "EVENTS (https://wat2do.ca/Events)\n" {
+		t.Errorf("heading link = %q", got)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFrom = mail.Address{Name: "UW Events", Address: "events@example.com"}
+
+// parseMessage reads a composed message back with net/mail
+func parseMessage(t *testing.T, raw []byte) *mail.Message {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("composed message doesn't parse: %v\n%s", err, raw)
+	}
+	return msg
+}
+
+// decodePart checks header declares UTF-8 quoted-printable contentType and
+// returns the decoded body
+func decodePart(t *testing.T, header interface{ Get(string) string }, body io.Reader, contentType string) string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType != contentType || params["charset"] != "utf-8" {
+		t.Errorf("Content-Type = %q, want %s; charset=utf-8", header.Get("Content-Type"), contentType)
+	}
+	if encoding := header.Get("Content-Transfer-Encoding"); encoding != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q", encoding)
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(decoded)
+}
+
+func TestComposeMessageSinglePart(t *testing.T) {
+	now := time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+	text := "Café night is on ☕ — " + strings.Repeat("long line ", 20)
+	raw, err := composeMessage(testFrom, &EmailMessage{
+		To:      "Reader <reader@example.com>",
+		Subject: "This week’s events",
+		Text:    text,
+		Headers: map[string]string{"list-unsubscribe": "<https://example.com/u>", "X-Campaign": "weekly"},
+	}, now)
+	if err != nil {
+		t.Fatalf("composeMessage: %v", err)
+	}
+
+	msg := parseMessage(t, raw)
+	if got := msg.Header.Get("From"); got != `"UW Events" <events@example.com>` {
+		t.Errorf("From = %q", got)
+	}
+	if got := msg.Header.Get("To"); got != `"Reader" <reader@example.com>` {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "This week’s events" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if date, err := msg.Header.Date(); err != nil || !date.Equal(now) {
+		t.Errorf("Date = %v (%v), want %v", date, err, now)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID = %q, want one in the sender's domain", id)
+	}
+	if got := msg.Header.Get("List-Unsubscribe"); got != "<https://example.com/u>" {
+		t.Errorf("List-Unsubscribe = %q", got)
+	}
+	if !bytes.Contains(raw, []byte("\r\nList-Unsubscribe: ")) || !bytes.Contains(raw, []byte("\r\nX-Campaign: weekly\r\n")) {
+		t.Errorf("extra headers aren't canonical:\n%s", raw)
+	}
+
+	if body := decodePart(t, msg.Header, msg.Body, "text/plain"); body != text {
+		t.Errorf("body = %q, want %q", body, text)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("line longer than 78 characters: %q", line)
+		}
+	}
+}
+
+func TestComposeMessageHTMLOnly(t *testing.T) {
+	raw, err := composeMessage(testFrom, &EmailMessage{To: "reader@example.com", Subject: "Hi", HTML: "<p>Hello</p>"}, time.Now())
+	if err != nil {
+		t.Fatalf("composeMessage: %v", err)
+	}
+	msg := parseMessage(t, raw)
+	if body := decodePart(t, msg.Header, msg.Body, "text/html"); body != "<p>Hello</p>" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestComposeMessageMultipart(t *testing.T) {
+	raw, err := composeMessage(testFrom, &EmailMessage{
+		To:      "reader@example.com",
+		Subject: "Hi",
+		Text:    "Hello = world",
+		HTML:    `<p style="color: red">Hello</p>`,
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("composeMessage: %v", err)
+	}
+
+	msg := parseMessage(t, raw)
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	// Plain text first, so it's the fallback
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain", "Hello = world"},
+		{"text/html", `<p style="color: red">Hello</p>`},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %v", want.contentType, err)
+		}
+		if body := decodePart(t, part.Header, part, want.contentType); body != want.body {
+			t.Errorf("%s body = %q, want %q", want.contentType, body, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("extra part: %v", err)
+	}
+}
+
+func TestComposeMessageRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  EmailMessage
+		want string
+	}{
+		{"invalid recipient", EmailMessage{To: "not an address", Text: "Hi"}, "invalid recipient"},
+		{"recipient list", EmailMessage{To: "a@example.com, b@example.com", Text: "Hi"}, "invalid recipient"},
+		{"no body", EmailMessage{To: "reader@example.com"}, "has no body"},
+		{"header value injection", EmailMessage{To: "reader@example.com", Text: "Hi", Headers: map[string]string{"X-Tag": "a\r\nBcc: victim@example.com"}}, "invalid email header"},
+		{"header name injection", EmailMessage{To: "reader@example.com", Text: "Hi", Headers: map[string]string{"Bcc: victim@example.com\r\nX-Tag": "a"}}, "invalid email header"},
+		{"header name with colon", EmailMessage{To: "reader@example.com", Text: "Hi", Headers: map[string]string{"X-Tag:": "a"}}, "invalid email header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := composeMessage(testFrom, &tt.msg, time.Now())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("composeMessage error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Subjects can't inject headers either: they're encoded
+	raw, err := composeMessage(testFrom, &EmailMessage{To: "reader@example.com", Subject: "Hi\r\nBcc: victim@example.com", Text: "Hi"}, time.Now())
+	if err != nil {
+		t.Fatalf("composeMessage: %v", err)
+	}
+	if msg := parseMessage(t, raw); msg.Header.Get("Bcc") != "" {
+		t.Errorf("subject injected a Bcc header:\n%s", raw)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Queued email statuses stored in QueuedEmail.Status
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSending = "sending"
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead" // gave up; kept for inspection
+)
+
+const (
+	// emailSendTimeout bounds a single delivery attempt
+	emailSendTimeout = time.Minute
+
+	// emailSendLease is how long a claimed email is left alone. A worker that
+	// dies mid-send leaves its email sending; it's retried once this passes.
+	emailSendLease = 5 * time.Minute
+)
+
+// QueuedEmail is a composed message waiting in, or delivered from, the
+// outbound queue
+type QueuedEmail struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Sender        string     `gorm:"size:255;not null" json:"sender"`
+	Recipient     string     `gorm:"size:255;not null" json:"recipient"`
+	Subject       string     `gorm:"type:text" json:"subject"`
+	Message       []byte     `gorm:"not null" json:"-"`
+	Status        string     `gorm:"size:16;not null;index:idx_email_queue_due" json:"status"`
+	Attempts      int        `gorm:"default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_email_queue_due" json:"next_attempt_at"`
+	LastError     *string    `gorm:"type:text" json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (QueuedEmail) TableName() string {
+	return "email_queue"
+}
+
+// EmailQueueConfig configures an EmailQueue; zero values use the defaults
+type EmailQueueConfig struct {
+	Concurrency  int           // parallel deliveries, default 4
+	MaxAttempts  int           // attempts before an email is dead, default 8
+	Backoff      time.Duration // first retry delay, doubled each attempt, default 30s
+	MaxBackoff   time.Duration // cap on the retry delay, default 6h
+	PollInterval time.Duration // how often idle workers look for due emails, default 5s
+}
+
+// EmailQueue is a persistent outbound queue in the database. Enqueued emails
+// are delivered by Run's workers through Transport; failures are retried
+// with exponential backoff until MaxAttempts, or a permanent rejection,
+// marks them dead.
+type EmailQueue struct {
+	DB        *gorm.DB
+	Transport EmailTransport
+	EmailQueueConfig
+
+	wake chan struct{}
+}
+
+// NewEmailQueue creates a queue delivering through transport
+func NewEmailQueue(db *gorm.DB, transport EmailTransport, cfg EmailQueueConfig) *EmailQueue {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 6 * time.Hour
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	return &EmailQueue{
+		DB:               db,
+		Transport:        transport,
+		EmailQueueConfig: cfg,
+		wake:             make(chan struct{}, 1),
+	}
+}
+
+// Enqueue stores a composed message for delivery
+func (q *EmailQueue) Enqueue(from, to, subject string, msg []byte) error {
+	err := q.DB.Create(&QueuedEmail{
+		Sender:        from,
+		Recipient:     to,
+		Subject:       subject,
+		Message:       msg,
+		Status:        EmailStatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers queued emails with Concurrency workers until ctx is cancelled
+func (q *EmailQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *EmailQueue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		email, err := q.claim()
+		if err != nil {
+			log.Printf("Failed to claim queued email: %v", err)
+		}
+		if email != nil {
+			q.deliver(ctx, email)
+			continue
+		}
+
+		timer := time.NewTimer(q.PollInterval)
+		select {
+		case <-ctx.Done():
+		case <-q.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// claim takes the next due email, marking it sending for emailSendLease.
+// SKIP LOCKED lets workers on every instance claim concurrently.
+func (q *EmailQueue) claim() (*QueuedEmail, error) {
+	var email QueuedEmail
+	now := time.Now()
+	err := q.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{EmailStatusPending, EmailStatusSending}, now).
+			Order("next_attempt_at").
+			Take(&email).Error
+		if err != nil {
+			return err
+		}
+
+		email.Attempts++
+		return tx.Model(&email).Updates(map[string]interface{}{
+			"status":          EmailStatusSending,
+			"attempts":        email.Attempts,
+			"next_attempt_at": now.Add(emailSendLease),
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// deliver attempts email and records the outcome
+func (q *EmailQueue) deliver(ctx context.Context, email *QueuedEmail) {
+	sendCtx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+	err := q.Transport.Deliver(sendCtx, email.Sender, []string{email.Recipient}, email.Message)
+	cancel()
+
+	now := time.Now()
+	var updates map[string]interface{}
+	switch {
+	case err == nil:
+		updates = map[string]interface{}{"status": EmailStatusSent, "sent_at": now, "last_error": nil}
+	case IsPermanentEmailError(err) || email.Attempts >= q.MaxAttempts:
+		log.Printf("Giving up on email %d to %s after %d attempts: %v", email.ID, email.Recipient, email.Attempts, err)
+		updates = map[string]interface{}{"status": EmailStatusDead, "last_error": err.Error()}
+	default:
+		retryAt := now.Add(q.backoff(email.Attempts))
+		log.Printf("Email %d to %s failed, retrying at %s: %v", email.ID, email.Recipient, retryAt.Format(time.RFC3339), err)
+		updates = map[string]interface{}{"status": EmailStatusPending, "next_attempt_at": retryAt, "last_error": err.Error()}
+	}
+
+	// Not ctx: the outcome must be recorded even while shutting down
+	if err := q.DB.Model(email).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update queued email %d: %v", email.ID, err)
+	}
+}
+
+// backoff is the delay before retrying after attempts failures: Backoff
+// doubled per attempt, capped at MaxBackoff, with up to 10% jitter so a
+// burst of failures doesn't retry in lockstep
+func (q *EmailQueue) backoff(attempts int) time.Duration {
+	delay := q.Backoff
+	for i := 1; i < attempts && delay < q.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, q.MaxBackoff)
+	return delay + rand.N(delay/10+1)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/testutil"
+)
+
+func newTestEmailQueue(t *testing.T, transport EmailTransport, cfg EmailQueueConfig) *EmailQueue {
+	t.Helper()
+	return NewEmailQueue(testutil.OpenDB(t, &QueuedEmail{}), transport, cfg)
+}
+
+// claimNext claims the next due email, failing if there's none
+func claimNext(t *testing.T, q *EmailQueue) *QueuedEmail {
+	t.Helper()
+	email, err := q.claim()
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if email == nil {
+		t.Fatal("claim found no due email")
+	}
+	return email
+}
+
+func expectNoneDue(t *testing.T, q *EmailQueue) {
+	t.Helper()
+	if email, err := q.claim(); err != nil || email != nil {
+		t.Fatalf("claim = %+v, %v; want nothing due", email, err)
+	}
+}
+
+func queued(t *testing.T, q *EmailQueue, id uint) QueuedEmail {
+	t.Helper()
+	var email QueuedEmail
+	if err := q.DB.First(&email, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return email
+}
+
+// makeDue moves an email's next attempt into the past, as waiting would
+func makeDue(t *testing.T, q *EmailQueue, id uint) {
+	t.Helper()
+	if err := q.DB.Model(&QueuedEmail{}).Where("id = ?", id).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmailQueueBackoff(t *testing.T) {
+	q := NewEmailQueue(nil, nil, EmailQueueConfig{Backoff: time.Second, MaxBackoff: 10 * time.Second})
+
+	for _, tt := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second}, // capped
+		{50, 10 * time.Second},
+	} {
+		for i := 0; i < 20; i++ {
+			delay := q.backoff(tt.attempts)
+			if delay < tt.want || delay > tt.want+tt.want/10 {
+				t.Fatalf("backoff(%d) = %s, want %s plus up to 10%%", tt.attempts, delay, tt.want)
+			}
+		}
+	}
+}
+
+func TestEmailQueueDelivers(t *testing.T) {
+	transport := &MemoryTransport{}
+	q := newTestEmailQueue(t, transport, EmailQueueConfig{})
+
+	if err := q.Enqueue("events@example.com", "reader@example.com", "Hi", []byte("message")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	email := claimNext(t, q)
+	if email.Status != EmailStatusSending || email.Attempts != 1 {
+		t.Fatalf("claimed = %+v, want sending on attempt 1", email)
+	}
+	q.deliver(context.Background(), email)
+
+	if got := queued(t, q, email.ID); got.Status != EmailStatusSent || got.SentAt == nil || got.LastError != nil {
+		t.Errorf("email = %+v, want sent", got)
+	}
+	sent := transport.Sent()
+	if len(sent) != 1 || sent[0].From != "events@example.com" || sent[0].To[0] != "reader@example.com" || string(sent[0].Message) != "message" {
+		t.Errorf("sent = %+v", sent)
+	}
+	expectNoneDue(t, q)
+}
+
+func TestEmailQueueRetriesThenDeadLetters(t *testing.T) {
+	transport := &MemoryTransport{Err: errors.New("connect to smtp.example.com:587: connection refused")}
+	q := newTestEmailQueue(t, transport, EmailQueueConfig{MaxAttempts: 3, Backoff: time.Minute})
+	if err := q.Enqueue("events@example.com", "reader@example.com", "Hi", []byte("message")); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		email := claimNext(t, q)
+		if email.Attempts != attempt {
+			t.Fatalf("attempts = %d, want %d", email.Attempts, attempt)
+		}
+		before := time.Now()
+		q.deliver(context.Background(), email)
+
+		got := queued(t, q, email.ID)
+		if got.Status != EmailStatusPending || got.LastError == nil || *got.LastError != transport.Err.Error() {
+			t.Fatalf("after attempt %d: %+v, want pending with the error", attempt, got)
+		}
+		// Backoff doubles: a minute after the first failure, two after the second
+		wait := time.Duration(1<<(attempt-1)) * time.Minute
+		if delay := got.NextAttemptAt.Sub(before); delay < wait || delay > wait+wait/10+time.Second {
+			t.Errorf("after attempt %d: retry in %s, want about %s", attempt, delay, wait)
+		}
+		expectNoneDue(t, q)
+		makeDue(t, q, email.ID)
+	}
+
+	email := claimNext(t, q)
+	q.deliver(context.Background(), email)
+	if got := queued(t, q, email.ID); got.Status != EmailStatusDead || got.Attempts != 3 {
+		t.Errorf("after the last attempt: %+v, want dead after 3", got)
+	}
+	makeDue(t, q, email.ID)
+	expectNoneDue(t, q)
+}
+
+func TestEmailQueueDeadLettersPermanentFailures(t *testing.T) {
+	transport := &MemoryTransport{Err: fmt.Errorf("smtp rcpt to reader@example.com: %w", &textproto.Error{Code: 550, Msg: "5.1.1 No such user"})}
+	q := newTestEmailQueue(t, transport, EmailQueueConfig{MaxAttempts: 8})
+	if err := q.Enqueue("events@example.com", "reader@example.com", "Hi", []byte("message")); err != nil {
+		t.Fatal(err)
+	}
+
+	email := claimNext(t, q)
+	q.deliver(context.Background(), email)
+	if got := queued(t, q, email.ID); got.Status != EmailStatusDead || got.Attempts != 1 || got.LastError == nil {
+		t.Errorf("email = %+v, want dead after the first attempt", got)
+	}
+}
+
+func TestEmailQueueReclaimsExpiredLeases(t *testing.T) {
+	transport := &MemoryTransport{}
+	q := newTestEmailQueue(t, transport, EmailQueueConfig{})
+	if err := q.Enqueue("events@example.com", "reader@example.com", "Hi", []byte("message")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A worker claims the email and dies before recording the outcome
+	abandoned := claimNext(t, q)
+	if lease := time.Until(queued(t, q, abandoned.ID).NextAttemptAt); lease < emailSendLease-time.Minute {
+		t.Fatalf("lease = %s, want about %s", lease, emailSendLease)
+	}
+	expectNoneDue(t, q)
+
+	// Once the lease runs out another worker takes it over
+	makeDue(t, q, abandoned.ID)
+	email := claimNext(t, q)
+	if email.ID != abandoned.ID || email.Attempts != 2 {
+		t.Fatalf("reclaimed = %+v, want email %d on attempt 2", email, abandoned.ID)
+	}
+	q.deliver(context.Background(), email)
+	if got := queued(t, q, email.ID); got.Status != EmailStatusSent {
+		t.Errorf("status = %q, want sent", got.Status)
+	}
+}
+
+func TestEmailQueueRun(t *testing.T) {
+	transport := &MemoryTransport{}
+	q := newTestEmailQueue(t, transport, EmailQueueConfig{Concurrency: 2, PollInterval: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Enqueue wakes an idle worker rather than waiting for the next poll
+	for i := 0; i < 3; i++ {
+		if err := q.Enqueue("events@example.com", fmt.Sprintf("reader%d@example.com", i), "Hi", []byte("message")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(transport.Sent()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("sent %d of 3 emails", len(transport.Sent()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var sent int64
+	q.DB.Model(&QueuedEmail{}).Where("status = ?", EmailStatusSent).Count(&sent)
+	if sent != 3 {
+		t.Errorf("sent emails = %d, want 3", sent)
+	}
+}
@@ -1,8 +1,19 @@
 package services
 
-import "io"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"sync"
+	"time"
+)
 
-// EmailService provides email sending functionality
+// defaultBulkConcurrency caps parallel sends in SendNewsletterEmail
+const defaultBulkConcurrency = 4
+
+// EmailService composes emails and hands them to the outbound Queue, or
+// delivers them directly through Transport when there's no queue
 type EmailService struct {
 	SMTPHost     string
 	SMTPPort     int
@@ -10,9 +21,17 @@ type EmailService struct {
 	SMTPPassword string
 	FromEmail    string
 	FromName     string
+
+	Transport EmailTransport
+	Queue     *EmailQueue // nil sends synchronously
+
+	// BulkConcurrency caps parallel sends in SendNewsletterEmail; 0 uses
+	// defaultBulkConcurrency
+	BulkConcurrency int
 }
 
-// NewEmailService creates a new email service instance
+// NewEmailService creates a new email service instance that sends through
+// the SMTP server with STARTTLS; set Transport and Queue to change that
 func NewEmailService(host string, port int, username, password, fromEmail, fromName string) *EmailService {
 	return &EmailService{
 		SMTPHost:     host,
@@ -21,7 +40,40 @@ func NewEmailService(host string, port int, username, password, fromEmail, fromN
 		SMTPPassword: password,
 		FromEmail:    fromEmail,
 		FromName:     fromName,
+		Transport: &SMTPTransport{
+			Host:     host,
+			Port:     port,
+			Username: username,
+			Password: password,
+			TLS:      SMTPStartTLS,
+		},
+	}
+}
+
+// EmailMessage is a single email with a plain text body, an optional HTML
+// alternative and extra headers
+type EmailMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	Headers map[string]string // e.g. List-Unsubscribe
+}
+
+// Send composes msg and queues it, or delivers it if there's no queue
+func (s *EmailService) Send(msg *EmailMessage) error {
+	raw, err := composeMessage(mail.Address{Name: s.FromName, Address: s.FromEmail}, msg, time.Now())
+	if err != nil {
+		return err
 	}
+	to, _ := mail.ParseAddress(msg.To) // validated by composeMessage
+
+	if s.Queue != nil {
+		return s.Queue.Enqueue(s.FromEmail, to.Address, msg.Subject, raw)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), emailSendTimeout)
+	defer cancel()
+	return s.Transport.Deliver(ctx, s.FromEmail, []string{to.Address}, raw)
 }
 
 // SendEmail sends a plain text email
@@ -30,46 +82,67 @@ func NewEmailService(host string, port int, username, password, fromEmail, fromN
 //   - subject: Email subject
 //   - body: Email body (plain text)
 func (s *EmailService) SendEmail(to, subject, body string) error {
-	// TODO: Implement email sending via SMTP
-	// 1. Connect to SMTP server
-	// 2. Authenticate
-	// 3. Compose email message
-	// 4. Send email
-	// 5. Close connection
-
-	return nil
+	return s.Send(&EmailMessage{To: to, Subject: subject, Text: body})
 }
 
 // SendHTMLEmail sends an HTML email
 func (s *EmailService) SendHTMLEmail(to, subject, htmlBody string) error {
-	// TODO: Implement HTML email sending
-	// Similar to SendEmail but with HTML content type
-
-	return nil
+	return s.Send(&EmailMessage{To: to, Subject: subject, HTML: htmlBody})
 }
 
 // SendTemplatedEmail sends an email using a template
 // Parameters:
 //   - to: Recipient email address
-//   - subject: Email subject
-//   - templateName: Template identifier
+//   - templateName: Template identifier, e.g. "digest"
+//   - locale: Preferred locale, e.g. "fr"; falls back to DefaultLocale
 //   - data: Template data
-func (s *EmailService) SendTemplatedEmail(to, subject, templateName string, data map[string]interface{}) error {
-	// TODO: Implement templated email sending
-	// 1. Load template by name
-	// 2. Render template with data
-	// 3. Send rendered email
+//   - headers: Extra headers, e.g. List-Unsubscribe; may be nil
+func (s *EmailService) SendTemplatedEmail(to, templateName, locale string, data map[string]interface{}, headers map[string]string) error {
+	email, err := RenderTemplate(templateName, locale, data)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return s.Send(&EmailMessage{
+		To:      to,
+		Subject: email.Subject,
+		Text:    email.Text,
+		HTML:    email.HTML,
+		Headers: headers,
+	})
 }
 
-// SendNewsletterEmail sends newsletter to subscribers
+// SendNewsletterEmail sends newsletter to subscribers, each as their own
+// email, at most BulkConcurrency at a time. It sends to every recipient and
+// returns the failures joined.
 func (s *EmailService) SendNewsletterEmail(recipients []string, subject, htmlBody string) error {
-	// TODO: Implement bulk email sending
-	// 1. Batch recipients if needed
-	// 2. Send to each recipient
-	// 3. Track send status
-	// 4. Handle failures and retries
+	concurrency := s.BulkConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBulkConcurrency
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, to := range recipients {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := s.SendHTMLEmail(to, subject, htmlBody); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("send to %s: %w", to, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
 
-	return nil
+	return errors.Join(errs...)
 }
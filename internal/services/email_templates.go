@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email templates live in templates/<locale>/<name>.html.tmpl. Each defines
+// "subject" and "content", and may override "footer"; they're rendered inside
+// the shared "layout" from templates/layout.html.tmpl. An optional
+// templates/<locale>/<name>.txt.tmpl is the plain-text version, otherwise
+// it's generated from the HTML.
+//
+//go:embed templates/*.tmpl templates/*/*.tmpl
+var templateFS embed.FS
+
+// DefaultLocale is used for unsupported locales, and when a template has no
+// variant for the requested one
+const DefaultLocale = "en"
+
+// EmailLocales are the locales emails can be sent in
+var EmailLocales = []string{"en", "fr"}
+
+// RenderedEmail is a rendered email template
+type RenderedEmail struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+	Locale  string `json:"locale"` // the variant rendered
+}
+
+// emailTemplate is one locale's variant of a template
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template // nil generates text from the HTML
+}
+
+// emailTemplates maps template name to locale to variant
+var emailTemplates = mustLoadEmailTemplates()
+
+// templateFuncs are available to every email template
+var templateFuncs = map[string]interface{}{
+	"date": FormatDate,
+}
+
+func mustLoadEmailTemplates() map[string]map[string]*emailTemplate {
+	layout := htmltemplate.Must(htmltemplate.New("layout").Funcs(templateFuncs).
+		ParseFS(templateFS, "templates/layout.html.tmpl"))
+
+	templates := map[string]map[string]*emailTemplate{}
+	for _, locale := range EmailLocales {
+		files, err := fs.Glob(templateFS, "templates/"+locale+"/*.html.tmpl")
+		if err != nil {
+			panic(err)
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(path.Base(file), ".html.tmpl")
+			variant := &emailTemplate{
+				html: htmltemplate.Must(htmltemplate.Must(layout.Clone()).ParseFS(templateFS, file)),
+			}
+			if variant.html.Lookup("subject") == nil || variant.html.Lookup("content") == nil {
+				panic(fmt.Sprintf("email template %s must define subject and content", file))
+			}
+
+			textFile := strings.TrimSuffix(file, ".html.tmpl") + ".txt.tmpl"
+			if _, err := fs.Stat(templateFS, textFile); err == nil {
+				variant.text = texttemplate.Must(texttemplate.New(path.Base(textFile)).Funcs(templateFuncs).
+					ParseFS(templateFS, textFile))
+			}
+
+			if templates[name] == nil {
+				templates[name] = map[string]*emailTemplate{}
+			}
+			templates[name][locale] = variant
+		}
+	}
+	return templates
+}
+
+// EmailTemplateNames lists the templates with the locales each has a
+// variant for
+func EmailTemplateNames() map[string][]string {
+	names := make(map[string][]string, len(emailTemplates))
+	for name, variants := range emailTemplates {
+		for locale := range variants {
+			names[name] = append(names[name], locale)
+		}
+		sort.Strings(names[name])
+	}
+	return names
+}
+
+// SupportedLocale reports whether emails can be sent in locale
+func SupportedLocale(locale string) bool {
+	for _, supported := range EmailLocales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLocale picks the first supported language from an Accept-Language
+// header or a tag like "fr-CA", or DefaultLocale
+func ParseLocale(raw string) string {
+	for _, tag := range strings.Split(raw, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		base, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+		if base = strings.ToLower(base); SupportedLocale(base) {
+			return base
+		}
+	}
+	return DefaultLocale
+}
+
+// RenderTemplate renders the named email in locale, falling back to
+// DefaultLocale. Templates get data plus Locale. The HTML has its stylesheet
+// inlined, and the subject is plain text.
+func RenderTemplate(name, locale string, data map[string]interface{}) (*RenderedEmail, error) {
+	variants, ok := emailTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	variant, ok := variants[locale]
+	if !ok {
+		locale = DefaultLocale
+		if variant, ok = variants[locale]; !ok {
+			return nil, fmt.Errorf("email template %q has no %s variant", name, locale)
+		}
+	}
+
+	merged := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		merged[key] = value
+	}
+	merged["Locale"] = locale
+
+	var subject, body bytes.Buffer
+	if err := variant.html.ExecuteTemplate(&subject, "subject", merged); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := variant.html.ExecuteTemplate(&body, "layout", merged); err != nil {
+		return nil, fmt.Errorf("render %s html: %w", name, err)
+	}
+	htmlBody, err := InlineCSS(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("inline %s css: %w", name, err)
+	}
+
+	email := &RenderedEmail{
+		// The subject went through HTML escaping, so undo it
+		Subject: strings.Join(strings.Fields(html.UnescapeString(subject.String())), " "),
+		HTML:    htmlBody,
+		Locale:  locale,
+	}
+	if variant.text != nil {
+		var text bytes.Buffer
+		if err := variant.text.Execute(&text, merged); err != nil {
+			return nil, fmt.Errorf("render %s text: %w", name, err)
+		}
+		email.Text = text.String()
+	} else if email.Text, err = HTMLToText(body.String()); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", name, err)
+	}
+	return email, nil
+}
+
+// frenchDates translates the English names time.Format produces. Longer
+// names come first so "Monday" isn't replaced as "Mon" + "day".
+var frenchDates = strings.NewReplacer(
+	"Monday", "lundi", "Tuesday", "mardi", "Wednesday", "mercredi", "Thursday", "jeudi",
+	"Friday", "vendredi", "Saturday", "samedi", "Sunday", "dimanche",
+	"January", "janvier", "February", "février", "March", "mars", "April", "avril",
+	"June", "juin", "July", "juillet", "August", "août", "September", "septembre",
+	"October", "octobre", "November", "novembre", "December", "décembre",
+	"Mon", "lun.", "Tue", "mar.", "Wed", "mer.", "Thu", "jeu.", "Fri", "ven.", "Sat", "sam.", "Sun", "dim.",
+	"Jan", "janv.", "Feb", "févr.", "Mar", "mars", "Apr", "avr.", "May", "mai", "Jun", "juin",
+	"Jul", "juil.", "Aug", "août", "Sep", "sept.", "Oct", "oct.", "Nov", "nov.", "Dec", "déc.",
+)
+
+// FormatDate formats t with a time.Format layout, translating day and month
+// names into locale
+func FormatDate(locale string, t time.Time, layout string) string {
+	formatted := t.Format(layout)
+	if locale == "fr" {
+		return frenchDates.Replace(formatted)
+	}
+	return formatted
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderConfirmTemplate(t *testing.T) {
+	data := map[string]interface{}{
+		"SiteURL":    "https://wat2do.ca",
+		"ConfirmURL": `https://wat2do.ca/newsletter/confirm?token=1.2.a"b`,
+		"Days":       7,
+	}
+
+	tests := []struct {
+		locale, want, subject, heading string
+	}{
+		{"en", "en", "Confirm your Wat2Do newsletter subscription", "CONFIRM YOUR SUBSCRIPTION"},
+		{"fr", "fr", "Confirmez votre abonnement à l'infolettre Wat2Do", "CONFIRMEZ VOTRE ABONNEMENT"},
+		// Locales without a variant fall back to English
+		{"de", "en", "Confirm your Wat2Do newsletter subscription", "CONFIRM YOUR SUBSCRIPTION"},
+		{"", "en", "Confirm your Wat2Do newsletter subscription", "CONFIRM YOUR SUBSCRIPTION"},
+	}
+	for _, tt := range tests {
+		email, err := RenderTemplate("confirm", tt.locale, data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.locale, err)
+		}
+		if email.Locale != tt.want {
+			t.Errorf("%s: rendered the %s variant, want %s", tt.locale, email.Locale, tt.want)
+		}
+		// Subjects are plain text, not HTML-escaped
+		if email.Subject != tt.subject {
+			t.Errorf("%s: subject = %q, want %q", tt.locale, email.Subject, tt.subject)
+		}
+
+		for _, want := range []string{
+			`<html lang="` + tt.want + `">`,
+			// The layout wraps the content, with the stylesheet inlined
+			`<a class="brand" href="https://wat2do.ca" style="color:#1a56db;font-size:14px;font-weight:bold;text-decoration:none">`,
+			`style="color:#fff;display:inline-block;padding:10px 16px;border-radius:4px;background:#1a56db;font-weight:bold;text-decoration:none"`,
+			`<div class="footer" style="margin-top:24px;font-size:12px;color:#666">`,
+			// Data is escaped
+			`href="https://wat2do.ca/newsletter/confirm?token=1.2.a%22b"`,
+		} {
+			if !strings.Contains(email.HTML, want) {
+				t.Errorf("%s: HTML is missing %s:\n%s", tt.locale, want, email.HTML)
+			}
+		}
+		if !strings.Contains(email.HTML, "@media (max-width: 620px)") {
+			t.Errorf("%s: @media rule dropped from the stylesheet", tt.locale)
+		}
+
+		// Without a text template the text is generated from the HTML
+		for _, want := range []string{tt.heading + "\n", "(https://wat2do.ca/newsletter/confirm?token=1.2.a%22b)", " 7 "} {
+			if !strings.Contains(email.Text, want) {
+				t.Errorf("%s: text is missing %q:\n%s", tt.locale, want, email.Text)
+			}
+		}
+		if strings.Contains(email.Text, "<") || strings.Contains(email.Text, "{") {
+			t.Errorf("%s: text has markup or styles:\n%s", tt.locale, email.Text)
+		}
+	}
+
+	if _, err := RenderTemplate("welcome", "en", data); err == nil {
+		t.Error("rendering an unknown template succeeded")
+	}
+}
+
+func TestEmailTemplateNames(t *testing.T) {
+	want := map[string][]string{"confirm": {"en", "fr"}, "digest": {"en", "fr"}}
+	if got := EmailTemplateNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("EmailTemplateNames() = %v, want %v", got, want)
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := map[string]string{
+		"fr":                         "fr",
+		"FR-ca":                      "fr",
+		"en-US,en;q=0.9":             "en",
+		"de-DE,fr-CA;q=0.8,en;q=0.5": "fr",
+		" fr-CA ; q=1 ":              "fr",
+		"de, es;q=0.9":               "en",
+		"french":                     "en",
+		"*":                          "en",
+		"":                           "en",
+	}
+	for raw, want := range tests {
+		if got := ParseLocale(raw); got != want {
+			t.Errorf("ParseLocale(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		locale string
+		date   time.Time
+		layout string
+		want   string
+	}{
+		{"fr", time.Date(2026, 3, 2, 19, 5, 0, 0, time.UTC), "Monday 2 January", "lundi 2 mars"},
+		{"fr", time.Date(2026, 3, 2, 19, 5, 0, 0, time.UTC), "Mon 2 Jan", "lun. 2 mars"},
+		{"fr", time.Date(2026, 3, 3, 19, 5, 0, 0, time.UTC), "Mon 2 Jan", "mar. 3 mars"},
+		{"fr", time.Date(2026, 5, 16, 9, 0, 0, 0, time.UTC), "Monday 2 January", "samedi 16 mai"},
+		{"fr", time.Date(2026, 8, 20, 9, 0, 0, 0, time.UTC), "Mon 2 Jan", "jeu. 20 août"},
+		{"fr", time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC), "Monday 2 January", "dimanche 1 février"},
+		{"fr", time.Date(2026, 3, 2, 19, 5, 0, 0, time.UTC), "15 h 04", "19 h 05"},
+		{"en", time.Date(2026, 3, 2, 19, 5, 0, 0, time.UTC), "Mon 2 Jan", "Mon 2 Mar"},
+	}
+	for _, tt := range tests {
+		if got := FormatDate(tt.locale, tt.date, tt.layout); got != tt.want {
+			t.Errorf("FormatDate(%s, %s, %q) = %q, want %q", tt.locale, tt.date.Format(time.DateOnly), tt.layout, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmailTransport delivers a composed message to its recipients.
+// SMTPTransport is the production implementation; FileTransport and
+// MemoryTransport stand in for it in development and tests.
+type EmailTransport interface {
+	Deliver(ctx context.Context, from string, to []string, msg []byte) error
+}
+
+// IsPermanentEmailError reports whether retrying a delivery that failed with
+// err is pointless, i.e. the server rejected it with a 5xx reply
+func IsPermanentEmailError(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
+
+// SMTP connection security modes
+const (
+	SMTPStartTLS    = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SMTPImplicitTLS = "tls"      // TLS from the start, usually port 465
+	SMTPNoTLS       = "none"     // local relays only; auth is refused off localhost
+)
+
+// defaultSMTPTimeout bounds a delivery when its context has no deadline
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPTransport is an EmailTransport for an SMTP submission server
+type SMTPTransport struct {
+	Host     string
+	Port     int
+	Username string // empty skips authentication
+	Password string
+	TLS      string // SMTPStartTLS, SMTPImplicitTLS or SMTPNoTLS
+	// HelloName is the EHLO hostname; empty sends "localhost"
+	HelloName string
+
+	// rootCAs replaces the system roots when set, so tests can trust a
+	// local server
+	rootCAs *x509.CertPool
+}
+
+// Deliver sends msg over a new connection, which is closed afterwards
+func (t *SMTPTransport) Deliver(ctx context.Context, from string, to []string, msg []byte) error {
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultSMTPTimeout)
+	}
+	tlsConfig := &tls.Config{ServerName: t.Host, MinVersion: tls.VersionTLS12, RootCAs: t.rootCAs}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	var conn net.Conn
+	var err error
+	switch t.TLS {
+	case SMTPImplicitTLS:
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	case SMTPStartTLS, SMTPNoTLS:
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return fmt.Errorf("unknown SMTP TLS mode %q", t.TLS)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer client.Close()
+
+	if t.HelloName != "" {
+		if err := client.Hello(t.HelloName); err != nil {
+			return fmt.Errorf("smtp hello: %w", err)
+		}
+	}
+	if t.TLS == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if t.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server %s does not support AUTH", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp rcpt to %s: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	return client.Quit()
+}
+
+// FileTransport is an EmailTransport that writes each message to a .eml file
+// in Dir instead of sending it, for local development
+type FileTransport struct {
+	Dir string
+}
+
+// NewFileTransport creates a file transport writing to dir, creating the
+// directory if needed
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileTransport{Dir: dir}, nil
+}
+
+// Deliver writes msg to <time>-<recipient>.eml, prefixed with its envelope
+func (t *FileTransport) Deliver(ctx context.Context, from string, to []string, msg []byte) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"),
+		strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(strings.Join(to, ",")))
+
+	envelope := fmt.Sprintf("X-Envelope-From: %s\r\nX-Envelope-To: %s\r\n", from, strings.Join(to, ", "))
+	return os.WriteFile(filepath.Join(t.Dir, name), append([]byte(envelope), msg...), 0o644)
+}
+
+// SentEmail is a message captured by MemoryTransport
+type SentEmail struct {
+	From    string
+	To      []string
+	Message []byte
+}
+
+// MemoryTransport is an EmailTransport that keeps messages in memory, for
+// tests. Err, if set, is returned instead of delivering.
+type MemoryTransport struct {
+	Err error
+
+	mu   sync.Mutex
+	sent []SentEmail
+}
+
+// Deliver records msg
+func (t *MemoryTransport) Deliver(ctx context.Context, from string, to []string, msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Err != nil {
+		return t.Err
+	}
+	t.sent = append(t.sent, SentEmail{
+		From:    from,
+		To:      append([]string(nil), to...),
+		Message: append([]byte(nil), msg...),
+	})
+	return nil
+}
+
+// Sent returns the messages delivered so far
+func (t *MemoryTransport) Sent() []SentEmail {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]SentEmail(nil), t.sent...)
+}
+
+// Reset forgets the delivered messages
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = nil
+}
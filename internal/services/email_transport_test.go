@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCertificate creates a self-signed certificate for 127.0.0.1 and a
+// pool trusting it
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp.test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, roots
+}
+
+// smtpServer is an in-process stand-in for an SMTP submission server. It
+// offers STARTTLS, or TLS from the start when implicitTLS is set, and AUTH
+// PLAIN once the connection is encrypted, and records what it's sent.
+type smtpServer struct {
+	addr        *net.TCPAddr
+	roots       *x509.CertPool
+	username    string
+	password    string
+	implicitTLS bool
+	noStartTLS  bool
+	rejects     map[string]string // recipient to RCPT reply, e.g. "550 5.1.1 No such user"
+
+	tlsConfig *tls.Config
+	listener  net.Listener
+
+	mu       sync.Mutex
+	mails    []smtpMail
+	sessions []smtpSession
+}
+
+// smtpMail is a message the server accepted
+type smtpMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpSession is what a connection did before it quit
+type smtpSession struct {
+	TLS  bool
+	User string // authenticated user
+}
+
+func newSMTPServer(t *testing.T, configure func(*smtpServer)) *smtpServer {
+	t.Helper()
+	cert, roots := testCertificate(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{
+		addr:      listener.Addr().(*net.TCPAddr),
+		roots:     roots,
+		username:  "mailer",
+		password:  "secret",
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		listener:  listener,
+	}
+	if configure != nil {
+		configure(s)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// transport is an SMTPTransport for the server trusting its certificate
+func (s *smtpServer) transport(mode string) *SMTPTransport {
+	return &SMTPTransport{
+		Host:     s.addr.IP.String(),
+		Port:     s.addr.Port,
+		Username: s.username,
+		Password: s.password,
+		TLS:      mode,
+		rootCAs:  s.roots,
+	}
+}
+
+func (s *smtpServer) received() ([]smtpMail, []smtpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMail(nil), s.mails...), append([]smtpSession(nil), s.sessions...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	var session smtpSession
+	defer func() { conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if s.implicitTLS {
+		conn = tls.Server(conn, s.tlsConfig)
+		session.TLS = true
+	}
+	text := textproto.NewConn(conn)
+	reply := func(lines ...string) {
+		for i, line := range lines {
+			sep := " "
+			if i < len(lines)-1 {
+				sep = "-"
+			}
+			_ = text.PrintfLine("%s%s%s", line[:3], sep, line[4:])
+		}
+	}
+
+	var mail smtpMail
+	reply("220 smtp.test ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"250 smtp.test"}
+			if !session.TLS && !s.noStartTLS {
+				lines = append(lines, "250 STARTTLS")
+			}
+			if session.TLS {
+				lines = append(lines, "250 AUTH PLAIN")
+			}
+			reply(lines...)
+		case "STARTTLS":
+			if session.TLS || s.noStartTLS {
+				reply("503 5.5.1 TLS not available")
+				continue
+			}
+			reply("220 2.0.0 Ready to start TLS")
+			conn = tls.Server(conn, s.tlsConfig)
+			text = textproto.NewConn(conn)
+			session.TLS = true
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			credentials, _ := base64.StdEncoding.DecodeString(initial)
+			if !session.TLS || mechanism != "PLAIN" || string(credentials) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 5.7.8 Authentication credentials invalid")
+				continue
+			}
+			session.User = s.username
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			if s.username != "" && session.User == "" {
+				reply("530 5.7.0 Authentication required")
+				continue
+			}
+			mail = smtpMail{From: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+			reply("250 2.1.0 Ok")
+		case "RCPT":
+			rcpt := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if rejection, ok := s.rejects[rcpt]; ok {
+				reply(rejection)
+				continue
+			}
+			mail.To = append(mail.To, rcpt)
+			reply("250 2.1.5 Ok")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			reply("250 2.0.0 Ok: queued")
+		case "RSET", "NOOP":
+			reply("250 2.0.0 Ok")
+		case "QUIT":
+			// Recorded before replying, so it's seen once Deliver returns
+			s.mu.Lock()
+			s.sessions = append(s.sessions, session)
+			s.mu.Unlock()
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+const testMessage = "From: events@example.com\r\nTo: reader@example.com\r\nSubject: Hi\r\n\r\nHello\r\n.leading dot\r\n"
+
+func TestSMTPTransportStartTLS(t *testing.T) {
+	server := newSMTPServer(t, nil)
+
+	err := server.transport(SMTPStartTLS).Deliver(context.Background(), "events@example.com", []string{"reader@example.com"}, []byte(testMessage))
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	mails, sessions := server.received()
+	if len(mails) != 1 {
+		t.Fatalf("mails = %d, want 1", len(mails))
+	}
+	mail := mails[0]
+	if mail.From != "events@example.com" || len(mail.To) != 1 || mail.To[0] != "reader@example.com" {
+		t.Errorf("envelope = %+v", mail)
+	}
+	// Dot-stuffing is undone by the server, so the message arrives intact
+	if mail.Data != strings.ReplaceAll(testMessage, "\r\n", "\n") {
+		t.Errorf("data = %q", mail.Data)
+	}
+	if len(sessions) != 1 || !sessions[0].TLS || sessions[0].User != "mailer" {
+		t.Errorf("sessions = %+v, want one encrypted, authenticated session", sessions)
+	}
+}
+
+func TestSMTPTransportImplicitTLS(t *testing.T) {
+	server := newSMTPServer(t, func(s *smtpServer) { s.implicitTLS = true })
+
+	err := server.transport(SMTPImplicitTLS).Deliver(context.Background(), "events@example.com", []string{"reader@example.com"}, []byte(testMessage))
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if mails, sessions := server.received(); len(mails) != 1 || sessions[0].User != "mailer" {
+		t.Errorf("mails = %+v, sessions = %+v", mails, sessions)
+	}
+}
+
+func TestSMTPTransportRequiresTLS(t *testing.T) {
+	t.Run("no STARTTLS", func(t *testing.T) {
+		server := newSMTPServer(t, func(s *smtpServer) { s.noStartTLS = true })
+		err := server.transport(SMTPStartTLS).Deliver(context.Background(), "events@example.com", []string{"reader@example.com"}, []byte(testMessage))
+		if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+			t.Fatalf("Deliver error = %v, want STARTTLS unsupported", err)
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		server := newSMTPServer(t, nil)
+		transport := server.transport(SMTPStartTLS)
+		transport.rootCAs = x509.NewCertPool()
+		err := transport.Deliver(context.Background(), "events@example.com", []string{"reader@example.com"}, []byte(testMessage))
+		var unknownAuthority x509.UnknownAuthorityError
+		if !errors.As(err, &unknownAuthority) {
+			t.Fatalf("Deliver error = %v, want an unknown authority", err)
+		}
+		if mails, _ := server.received(); len(mails) != 0 {
+			t.Errorf("mails = %+v, want none", mails)
+		}
+	})
+}
+
+func TestSMTPTransportErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*smtpServer)
+		transport func(*SMTPTransport)
+		want      string
+		permanent bool
+	}{
+		{
+			name:      "unknown recipient",
+			configure: func(s *smtpServer) { s.rejects = map[string]string{"reader@example.com": "550 5.1.1 No such user"} },
+			want:      "smtp rcpt to reader@example.com: 550",
+			permanent: true,
+		},
+		{
+			name:      "greylisted recipient",
+			configure: func(s *smtpServer) { s.rejects = map[string]string{"reader@example.com": "451 4.7.1 Try again later"} },
+			want:      "smtp rcpt to reader@example.com: 451",
+		},
+		{
+			name:      "wrong password",
+			transport: func(t *SMTPTransport) { t.Password = "wrong" },
+			want:      "smtp auth: 535",
+			permanent: true,
+		},
+		{
+			name:      "unknown TLS mode",
+			transport: func(t *SMTPTransport) { t.TLS = "ssl" },
+			want:      `unknown SMTP TLS mode "ssl"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t, tt.configure)
+			transport := server.transport(SMTPStartTLS)
+			if tt.transport != nil {
+				tt.transport(transport)
+			}
+
+			err := transport.Deliver(context.Background(), "events@example.com", []string{"reader@example.com"}, []byte(testMessage))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Deliver error = %v, want %q", err, tt.want)
+			}
+			if IsPermanentEmailError(err) != tt.permanent {
+				t.Errorf("IsPermanentEmailError(%v) = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+		})
+	}
+
+	t.Run("connection refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := listener.Addr().(*net.TCPAddr)
+		listener.Close()
+
+		err = (&SMTPTransport{Host: "127.0.0.1", Port: addr.Port, TLS: SMTPStartTLS}).Deliver(context.Background(), "a@example.com", []string{"b@example.com"}, []byte(testMessage))
+		if err == nil || IsPermanentEmailError(err) {
+			t.Fatalf("Deliver error = %v, want a temporary error", err)
+		}
+	})
+}
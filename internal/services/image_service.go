@@ -0,0 +1,390 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"math/bits"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// maxImagePixels rejects decompression bombs before decoding
+	maxImagePixels = 50_000_000
+
+	// jpegQuality is used for all JPEG variants
+	jpegQuality = 82
+
+	// VariantWebP names the WebP rendition, which is card-sized
+	VariantWebP = "webp"
+)
+
+// ImageVariantSizes lists the JPEG variants generated for every image and
+// the longest edge, in pixels, each is scaled down to, smallest first
+var ImageVariantSizes = []struct {
+	Name    string
+	MaxEdge int
+}{
+	{"thumbnail", 320},
+	{"card", 800},
+	{"full", 1600},
+}
+
+// EncodedImage is one rendition of a processed image
+type EncodedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// ProcessedImage holds the renditions and perceptual hash of an image
+type ProcessedImage struct {
+	Variants map[string]EncodedImage // ImageVariantSizes names plus VariantWebP
+	PHash    string                  // 64-bit perceptual hash, 16 hex digits
+}
+
+// ProcessImage decodes an uploaded JPEG, PNG, GIF or WebP image, applies its
+// EXIF orientation and re-encodes it into the standard variants. Re-encoding
+// from pixels drops all metadata, including EXIF GPS coordinates.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errors.New("image is too large")
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	// Shrink to the largest variant before reorienting, which is per-pixel
+	largest := ImageVariantSizes[len(ImageVariantSizes)-1].MaxEdge
+	src := applyOrientation(scaleToFit(decoded, largest), exifOrientation(data))
+
+	processed := &ProcessedImage{
+		Variants: make(map[string]EncodedImage, len(ImageVariantSizes)+1),
+		PHash:    PerceptualHash(src),
+	}
+
+	for _, size := range ImageVariantSizes {
+		scaled := scaleToFit(src, size.MaxEdge)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", size.Name, err)
+		}
+		processed.Variants[size.Name] = EncodedImage{
+			Data:        buf.Bytes(),
+			ContentType: "image/jpeg",
+			Ext:         ".jpg",
+			Width:       scaled.Bounds().Dx(),
+			Height:      scaled.Bounds().Dy(),
+		}
+
+		if size.Name == "card" {
+			var webp bytes.Buffer
+			if err := nativewebp.Encode(&webp, scaled, nil); err != nil {
+				return nil, fmt.Errorf("encode webp: %w", err)
+			}
+			processed.Variants[VariantWebP] = EncodedImage{
+				Data:        webp.Bytes(),
+				ContentType: "image/webp",
+				Ext:         ".webp",
+				Width:       scaled.Bounds().Dx(),
+				Height:      scaled.Bounds().Dy(),
+			}
+		}
+	}
+
+	return processed, nil
+}
+
+// scaleToFit scales img down so its longest edge is at most maxEdge,
+// flattening any transparency onto white
+func scaleToFit(img image.Image, maxEdge int) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > maxEdge {
+		width = max(1, width*maxEdge/longest)
+		height = max(1, height*maxEdge/longest)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// PerceptualHash computes a DCT-based perceptual hash (pHash): the image is
+// reduced to 32x32 greyscale, and each bit of the result says whether one of
+// the 64 lowest-frequency DCT coefficients is above their median. Similar
+// images have hashes a small HammingDistance apart.
+func PerceptualHash(img image.Image) string {
+	const size, low = 32, 8
+
+	small := image.NewGray(image.Rect(0, 0, size, size))
+	xdraw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	pixels := make([][]float64, size)
+	for y := range pixels {
+		pixels[y] = make([]float64, size)
+		for x := range pixels[y] {
+			pixels[y][x] = float64(small.GrayAt(x, y).Y)
+		}
+	}
+
+	coefficients := make([]float64, 0, low*low)
+	for v := 0; v < low; v++ {
+		for u := 0; u < low; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					sum += pixels[y][x] *
+						math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*size)) *
+						math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*size))
+				}
+			}
+			coefficients = append(coefficients, sum)
+		}
+	}
+
+	// The DC term only reflects overall brightness, so leave it out of the median
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, coefficient := range coefficients {
+		if coefficient > median {
+			hash |= 1 << uint(63-i)
+		}
+	}
+
+	return fmt.Sprintf("%016x", hash)
+}
+
+// HammingDistance counts the differing bits between two perceptual hashes
+func HammingDistance(a, b string) (int, error) {
+	rawA, errA := hex.DecodeString(a)
+	rawB, errB := hex.DecodeString(b)
+	if errA != nil || errB != nil || len(rawA) != 8 || len(rawB) != 8 {
+		return 0, errors.New("invalid perceptual hash")
+	}
+
+	distance := 0
+	for i := range rawA {
+		distance += bits.OnesCount8(rawA[i] ^ rawB[i])
+	}
+	return distance, nil
+}
+
+// StoredImage describes a processed image saved to storage
+type StoredImage struct {
+	URL      string            // the full variant
+	Variants map[string]string // variant name to URL
+	Sizes    map[string]int64  // variant name to size in bytes
+	PHash    string
+}
+
+// ImageService processes uploaded images and stores their variants
+type ImageService struct {
+	Storage    Storage
+	HTTPClient *http.Client // used by StoreImageFromURL
+}
+
+// NewImageService creates an image service storing into storage
+func NewImageService(storage Storage) *ImageService {
+	return &ImageService{
+		Storage:    storage,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// StoreImage processes data and uploads every variant under keyPrefix,
+// e.g. keyPrefix/thumbnail.jpg. The original upload is never stored, so
+// its metadata doesn't leak.
+func (s *ImageService) StoreImage(ctx context.Context, keyPrefix string, data []byte) (*StoredImage, error) {
+	if s.Storage == nil {
+		return nil, errors.New("storage is not configured")
+	}
+
+	processed, err := ProcessImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &StoredImage{
+		Variants: make(map[string]string, len(processed.Variants)),
+		Sizes:    make(map[string]int64, len(processed.Variants)),
+		PHash:    processed.PHash,
+	}
+	for name, variant := range processed.Variants {
+		url, err := s.Storage.Upload(ctx, keyPrefix+"/"+name+variant.Ext, variant.Data, variant.ContentType)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %w", name, err)
+		}
+		stored.Variants[name] = url
+		stored.Sizes[name] = int64(len(variant.Data))
+	}
+	stored.URL = stored.Variants["full"]
+
+	return stored, nil
+}
+
+// maxRemoteImageSize caps images fetched by StoreImageFromURL
+const maxRemoteImageSize = 20 << 20
+
+// StoreImageFromURL downloads an image (e.g. a scraped post's, whose CDN
+// URL will expire) and stores it like StoreImage
+func (s *ImageService) StoreImageFromURL(ctx context.Context, keyPrefix, imageURL string) (*StoredImage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download image: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	if len(data) > maxRemoteImageSize {
+		return nil, errors.New("download image: image is too large")
+	}
+
+	return s.StoreImage(ctx, keyPrefix, data)
+}
+
+// applyOrientation rotates/flips img per an EXIF orientation value (1-8)
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// Orientations 5-8 swap the axes
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = width-1-x, y
+			case 3: // rotated 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	return dst
+}
+
+// exifOrientation reads the EXIF orientation tag from a JPEG, returning 1
+// (upright) when there is none
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the JPEG segments looking for the APP1 Exif block
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		length := int(data[offset+2])<<8 | int(data[offset+3])
+		if marker == 0xDA || length < 2 || offset+2+length > len(data) {
+			return 1 // start of scan, or malformed
+		}
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation finds tag 0x0112 in the first IFD of a TIFF block
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var u16 func([]byte) int
+	var u32 func([]byte) int
+	switch string(tiff[:2]) {
+	case "II":
+		u16 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 }
+		u32 = func(b []byte) int { return u16(b) | u16(b[2:])<<16 }
+	case "MM":
+		u16 = func(b []byte) int { return int(b[0])<<8 | int(b[1]) }
+		u32 = func(b []byte) int { return u16(b)<<16 | u16(b[2:]) }
+	default:
+		return 1
+	}
+
+	ifd := u32(tiff[4:])
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := u16(tiff[ifd:])
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if u16(tiff[entry:]) == 0x0112 {
+			return u16(tiff[entry+8:])
+		}
+	}
+	return 1
+}
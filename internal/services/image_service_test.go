@@ -0,0 +1,400 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+var (
+	red   = color.RGBA{R: 255, A: 255}
+	green = color.RGBA{G: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// gpsMarker stands in for location metadata in test EXIF blocks
+const gpsMarker = "GPS 43.4723N 80.5449W"
+
+// quadrants draws a width x height image with red, green, blue and white
+// top-left, top-right, bottom-left and bottom-right quarters
+func quadrants(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := [2][2]color.RGBA{{red, green}, {blue, white}}[2*y/height][2*x/width]
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// poster draws a busy width x height image whose structure survives
+// scaling, unlike quadrants'
+func poster(width, height int, seed int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			u, v := x*64/width, y*64/height
+			shade := uint8((u*u*seed + v*(seed+3) + (u^v)*7) % 256)
+			img.SetRGBA(x, y, color.RGBA{R: shade, G: 255 - shade, B: uint8(u * 4), A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment builds an APP1 Exif segment in the given byte order whose
+// first IFD holds a Make tag, then the orientation, then gpsMarker
+func exifSegment(order binary.AppendByteOrder, orientation uint16) []byte {
+	tiff := []byte("II")
+	if order == binary.BigEndian {
+		tiff = []byte("MM")
+	}
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 2)
+	for _, entry := range [][2]uint16{{0x010F, 0}, {0x0112, orientation}} {
+		tiff = order.AppendUint16(tiff, entry[0])
+		tiff = order.AppendUint16(tiff, 3) // SHORT
+		tiff = order.AppendUint32(tiff, 1)
+		tiff = order.AppendUint16(tiff, entry[1])
+		tiff = order.AppendUint16(tiff, 0)
+	}
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, gpsMarker...)
+
+	body := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(body)+2))
+	return append(segment, body...)
+}
+
+// withSegment inserts segment into a JPEG straight after its SOI marker
+func withSegment(jpegData, segment []byte) []byte {
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestExifOrientation(t *testing.T) {
+	plain := encodeJPEG(t, quadrants(8, 8))
+	comment := append([]byte{0xFF, 0xFE, 0x00, 0x07}, "hello"...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"little endian", withSegment(plain, exifSegment(binary.LittleEndian, 6)), 6},
+		{"big endian", withSegment(plain, exifSegment(binary.BigEndian, 8)), 8},
+		{"after another segment", withSegment(withSegment(plain, exifSegment(binary.BigEndian, 3)), comment), 3},
+		{"truncated segment", withSegment(plain, exifSegment(binary.LittleEndian, 6))[:20], 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: orientation = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// nearest names which of the quadrant colours c is closest to
+func nearest(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	names := map[string]color.RGBA{"red": red, "green": green, "blue": blue, "white": white}
+	best, bestDistance := "", -1
+	for name, candidate := range names {
+		dr, dg, db := int(r>>8)-int(candidate.R), int(g>>8)-int(candidate.G), int(b>>8)-int(candidate.B)
+		if distance := dr*dr + dg*dg + db*db; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+func TestProcessImageAppliesOrientation(t *testing.T) {
+	plain := encodeJPEG(t, quadrants(60, 40))
+
+	// Corners of the upright result: top-left, top-right, bottom-left,
+	// bottom-right
+	tests := []struct {
+		orientation int
+		corners     string
+	}{
+		{1, "red green blue white"},
+		{2, "green red white blue"},
+		{3, "white blue green red"},
+		{4, "blue white red green"},
+		{5, "red blue green white"},
+		{6, "blue red white green"},
+		{7, "white green blue red"},
+		{8, "green white red blue"},
+	}
+	for _, tt := range tests {
+		processed, err := ProcessImage(withSegment(plain, exifSegment(binary.LittleEndian, uint16(tt.orientation))))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		full := processed.Variants["full"]
+		img, err := jpeg.Decode(bytes.NewReader(full.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantWidth, wantHeight := 60, 40
+		if tt.orientation >= 5 {
+			wantWidth, wantHeight = 40, 60
+		}
+		if full.Width != wantWidth || full.Height != wantHeight || img.Bounds().Dx() != wantWidth || img.Bounds().Dy() != wantHeight {
+			t.Errorf("orientation %d: %dx%d, want %dx%d", tt.orientation, img.Bounds().Dx(), img.Bounds().Dy(), wantWidth, wantHeight)
+			continue
+		}
+
+		const inset = 5
+		corners := strings.Join([]string{
+			nearest(img.At(inset, inset)),
+			nearest(img.At(wantWidth-1-inset, inset)),
+			nearest(img.At(inset, wantHeight-1-inset)),
+			nearest(img.At(wantWidth-1-inset, wantHeight-1-inset)),
+		}, " ")
+		if corners != tt.corners {
+			t.Errorf("orientation %d: corners = %s, want %s", tt.orientation, corners, tt.corners)
+		}
+	}
+}
+
+func TestProcessImageStripsMetadata(t *testing.T) {
+	upload := withSegment(encodeJPEG(t, quadrants(60, 40)), exifSegment(binary.BigEndian, 6))
+	processed, err := ProcessImage(upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, variant := range processed.Variants {
+		if bytes.Contains(variant.Data, []byte("Exif")) || bytes.Contains(variant.Data, []byte(gpsMarker)) {
+			t.Errorf("%s variant kept the upload's EXIF block", name)
+		}
+		// Reorientation is baked in, so nothing may rotate it again
+		if got := exifOrientation(variant.Data); got != 1 {
+			t.Errorf("%s variant has orientation %d", name, got)
+		}
+	}
+}
+
+func TestProcessImageVariants(t *testing.T) {
+	var upload bytes.Buffer
+	if err := png.Encode(&upload, poster(2000, 1000, 5)); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := ProcessImage(upload.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, contentType string
+		width, height     int
+	}{
+		{"thumbnail", "image/jpeg", 320, 160},
+		{"card", "image/jpeg", 800, 400},
+		{"full", "image/jpeg", 1600, 800},
+		{VariantWebP, "image/webp", 800, 400},
+	}
+	if len(processed.Variants) != len(tests) {
+		t.Errorf("%d variants, want %d", len(processed.Variants), len(tests))
+	}
+	for _, tt := range tests {
+		variant, ok := processed.Variants[tt.name]
+		if !ok {
+			t.Errorf("no %s variant", tt.name)
+			continue
+		}
+		var config image.Config
+		if tt.contentType == "image/webp" {
+			config, err = webp.DecodeConfig(bytes.NewReader(variant.Data))
+		} else {
+			config, err = jpeg.DecodeConfig(bytes.NewReader(variant.Data))
+		}
+		if err != nil {
+			t.Errorf("%s doesn't decode as %s: %v", tt.name, tt.contentType, err)
+			continue
+		}
+		if variant.ContentType != tt.contentType || config.Width != tt.width || config.Height != tt.height || variant.Width != tt.width {
+			t.Errorf("%s = %s %dx%d, want %s %dx%d", tt.name, variant.ContentType, config.Width, config.Height, tt.contentType, tt.width, tt.height)
+		}
+	}
+	if len(processed.PHash) != 16 {
+		t.Errorf("phash = %q, want 16 hex digits", processed.PHash)
+	}
+}
+
+func TestProcessImageFlattensTransparency(t *testing.T) {
+	var upload bytes.Buffer
+	if err := png.Encode(&upload, image.NewNRGBA(image.Rect(0, 0, 20, 20))); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := ProcessImage(upload.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(processed.Variants["card"].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nearest(img.At(10, 10)); got != "white" {
+		t.Errorf("transparent pixels became %s, want white", got)
+	}
+}
+
+func TestProcessImageRejectsBadInput(t *testing.T) {
+	// A GIF whose header claims 65535x65535 pixels
+	var bomb bytes.Buffer
+	if err := gif.Encode(&bomb, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{white}), nil); err != nil {
+		t.Fatal(err)
+	}
+	header := bomb.Bytes()
+	copy(header[6:10], []byte{0xFF, 0xFF, 0xFF, 0xFF})
+
+	for name, data := range map[string][]byte{
+		"decompression bomb": header,
+		"not an image":       []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"),
+		"truncated":          encodeJPEG(t, quadrants(60, 40))[:100],
+	} {
+		if _, err := ProcessImage(data); err == nil {
+			t.Errorf("%s: processed without error", name)
+		}
+	}
+}
+
+func TestPerceptualHash(t *testing.T) {
+	distance := func(a, b image.Image) int {
+		t.Helper()
+		d, err := HammingDistance(PerceptualHash(a), PerceptualHash(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	original := poster(800, 600, 5)
+
+	// A smaller, heavily recompressed repost still matches
+	var repost bytes.Buffer
+	jpeg.Encode(&repost, scaleToFit(original, 300), &jpeg.Options{Quality: 30})
+	reposted, err := jpeg.Decode(&repost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := distance(original, reposted); d > 10 {
+		t.Errorf("repost is %d bits away, want at most 10", d)
+	}
+	if d := distance(original, original); d != 0 {
+		t.Errorf("identical images are %d bits apart", d)
+	}
+
+	// Different posters don't
+	if d := distance(original, poster(800, 600, 11)); d <= 10 {
+		t.Errorf("different posters are only %d bits apart", d)
+	}
+	if d := distance(original, applyOrientation(original, 3)); d <= 10 {
+		t.Errorf("a rotated poster is only %d bits away", d)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{"0000000000000000", "0000000000000000", 0, false},
+		{"0000000000000000", "ffffffffffffffff", 64, false},
+		{"f0f0f0f0f0f0f0f0", "f0f0f0f0f0f0f0f1", 1, false},
+		{"8000000000000001", "0000000000000000", 2, false},
+		{"00000000", "00000000", 0, true},
+		{"000000000000000g", "0000000000000000", 0, true},
+		{"", "0000000000000000", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := HammingDistance(tt.a, tt.b)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("HammingDistance(%q, %q) = %d, %v; want %d, error %t", tt.a, tt.b, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestStoreImage(t *testing.T) {
+	storage := newTestLocalStorage(t)
+	s := NewImageService(storage)
+	upload := withSegment(encodeJPEG(t, quadrants(60, 40)), exifSegment(binary.LittleEndian, 6))
+
+	stored, err := s.StoreImage(context.Background(), "screenshots/abc", upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := storage.List(context.Background(), "screenshots/abc/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "screenshots/abc/card.jpg screenshots/abc/full.jpg screenshots/abc/thumbnail.jpg screenshots/abc/webp.webp"
+	if strings.Join(keys, " ") != want {
+		t.Errorf("stored %q, want only the variants, not the original", keys)
+	}
+	if stored.URL != stored.Variants["full"] || len(stored.Variants) != 4 {
+		t.Errorf("stored = %+v", stored)
+	}
+	for name, url := range stored.Variants {
+		w := fetch(storage, http.MethodGet, url)
+		if int64(w.Body.Len()) != stored.Sizes[name] || w.Body.Len() == 0 {
+			t.Errorf("%s is %d bytes, recorded as %d", name, w.Body.Len(), stored.Sizes[name])
+		}
+	}
+}
+
+func TestStoreImageFromURL(t *testing.T) {
+	upload := encodeJPEG(t, quadrants(60, 40))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/poster.jpg":
+			w.Write(upload)
+		case "/huge.jpg":
+			w.Write(make([]byte, maxRemoteImageSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := NewImageService(newTestLocalStorage(t))
+	if _, err := s.StoreImageFromURL(context.Background(), "posts/1", server.URL+"/poster.jpg"); err != nil {
+		t.Errorf("poster: %v", err)
+	}
+	for _, path := range []string{"/expired.jpg", "/huge.jpg"} {
+		if _, err := s.StoreImageFromURL(context.Background(), "posts/2", server.URL+path); err == nil {
+			t.Errorf("%s stored without error", path)
+		}
+	}
+	if _, err := NewImageService(nil).StoreImage(context.Background(), "posts/3", upload); err == nil {
+		t.Error("stored an image with no storage configured")
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tempUploadPrefix marks in-progress uploads, which List skips
+const tempUploadPrefix = ".upload-"
+
+// LocalStorage is a Storage on the local filesystem, for development and
+// tests. Files are served by its ServeHTTP, mounted on the router at BaseURL,
+// which only answers URLs carrying a valid HMAC signature: Upload returns
+// URLs signed without expiry, PresignedURL ones that expire.
+type LocalStorage struct {
+	Root    string // directory files are written under
+	BaseURL string // URL ServeHTTP is mounted at, e.g. http://localhost:8000/storage
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewLocalStorage creates a local storage rooted at root, creating the
+// directory if needed. secret signs file URLs.
+func NewLocalStorage(root, baseURL string, secret []byte) (*LocalStorage, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("local storage needs a signing secret")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage root: %w", err)
+	}
+
+	return &LocalStorage{
+		Root:    root,
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+		now:     time.Now,
+	}, nil
+}
+
+// Upload writes data to key
+func (s *LocalStorage) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return s.UploadFromReader(ctx, key, bytes.NewReader(data), contentType)
+}
+
+// UploadFromReader writes reader's content to key. The file is written to a
+// temporary name and renamed into place, so readers never see partial files.
+func (s *LocalStorage) UploadFromReader(ctx context.Context, key string, reader io.Reader, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	target := s.filePath(key)
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), tempUploadPrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, &contextReader{ctx: ctx, reader: reader}); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+
+	return s.signedURL(key, 0), nil
+}
+
+// Delete removes key
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(s.filePath(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// PresignedURL returns a URL for key that stops working after expiry
+func (s *LocalStorage) PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expiry <= 0 {
+		return "", errors.New("expiry must be positive")
+	}
+
+	return s.signedURL(key, s.now().Add(expiry).Unix()), nil
+}
+
+// List walks the directories under prefix and returns matching keys
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	if strings.Contains(prefix, "..") || strings.ContainsAny(prefix, "\\\x00") {
+		return nil, ErrInvalidKey
+	}
+	prefix = strings.TrimPrefix(prefix, "/")
+
+	// Only walk the deepest directory the prefix names
+	start := s.Root
+	if dir := path.Dir(prefix + "x"); dir != "." {
+		start = s.filePath(dir)
+	}
+
+	keys := []string{}
+	err := filepath.WalkDir(start, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), tempUploadPrefix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.Root, name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ServeHTTP serves a file whose signed URL path, relative to BaseURL, is the
+// request path. Mount it with http.StripPrefix.
+func (s *LocalStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, err := cleanKey(r.URL.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	var expires int64
+	if raw := query.Get("expires"); raw != "" {
+		if expires, err = strconv.ParseInt(raw, 10, 64); err != nil || expires <= 0 {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+	}
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(s.sign(key, expires))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+	if expires > 0 && s.now().Unix() > expires {
+		http.Error(w, "link expired", http.StatusForbidden)
+		return
+	}
+
+	file, err := os.Open(s.filePath(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	// Uploaded files are untrusted; never let them run as a page on our origin
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox")
+	if expires > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", max(expires-s.now().Unix(), 0)))
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// signedURL builds the URL for key; expires is a Unix time, or 0 for never
+func (s *LocalStorage) signedURL(key string, expires int64) string {
+	query := url.Values{}
+	if expires > 0 {
+		query.Set("expires", strconv.FormatInt(expires, 10))
+	}
+	query.Set("signature", s.sign(key, expires))
+
+	return s.BaseURL + "/" + escapeKey(key) + "?" + query.Encode()
+}
+
+// sign computes the URL signature over key and expiry
+func (s *LocalStorage) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStorage) filePath(key string) string {
+	return filepath.Join(s.Root, filepath.FromSlash(key))
+}
+
+// contextReader stops a copy once ctx is cancelled
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
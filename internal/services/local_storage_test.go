@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	s, err := NewLocalStorage(t.TempDir(), "http://localhost:8000/storage/", []byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// fetch requests a URL s returned through s.ServeHTTP, mounted as the
+// router mounts it
+func fetch(s *LocalStorage, method, rawURL string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	http.StripPrefix("/storage", s).ServeHTTP(w, httptest.NewRequest(method, rawURL, nil))
+	return w
+}
+
+// withQuery returns rawURL with its query parameter name set to value, or
+// removed when value is empty
+func withQuery(t *testing.T, rawURL, name, value string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if value == "" {
+		query.Del(name)
+	} else {
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func TestNewLocalStorageNeedsSecret(t *testing.T) {
+	if _, err := NewLocalStorage(t.TempDir(), "http://localhost:8000/storage", nil); err == nil {
+		t.Error("created local storage without a signing secret")
+	}
+}
+
+func TestLocalStorageSignedURLs(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+
+	fileURL, err := s.Upload(ctx, "screenshots/2025/poster one.png", []byte("png bytes"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(fileURL, "http://localhost:8000/storage/screenshots/2025/poster%20one.png?signature=") {
+		t.Fatalf("url = %q", fileURL)
+	}
+
+	w := fetch(s, http.MethodGet, fileURL)
+	if w.Code != http.StatusOK || w.Body.String() != "png bytes" {
+		t.Fatalf("GET = %d %q", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || !strings.Contains(w.Header().Get("Content-Security-Policy"), "sandbox") {
+		t.Errorf("uploaded files are served without nosniff and a sandboxing CSP: %v", w.Header())
+	}
+	if w := fetch(s, http.MethodHead, fileURL); w.Code != http.StatusOK {
+		t.Errorf("HEAD = %d", w.Code)
+	}
+	if w := fetch(s, http.MethodPost, fileURL); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", w.Code)
+	}
+
+	// Upload a second file to borrow its signature
+	otherURL, err := s.Upload(ctx, "screenshots/2025/other.png", []byte("other"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := url.Parse(otherURL)
+
+	otherSecret := &LocalStorage{Root: s.Root, BaseURL: s.BaseURL, secret: []byte("another-secret"), now: time.Now}
+	forged, _ := url.Parse(otherSecret.signedURL("screenshots/2025/poster one.png", 0))
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"no signature", withQuery(t, fileURL, "signature", "")},
+		{"tampered signature", withQuery(t, fileURL, "signature", "AAAA"+other.Query().Get("signature")[4:])},
+		{"another key's signature", withQuery(t, fileURL, "signature", other.Query().Get("signature"))},
+		{"signed with another secret", withQuery(t, fileURL, "signature", forged.Query().Get("signature"))},
+		{"expiry added to an unexpiring URL", withQuery(t, fileURL, "expires", "9999999999")},
+		{"invalid expiry", withQuery(t, fileURL, "expires", "soon")},
+	}
+	for _, tt := range tests {
+		if w := fetch(s, http.MethodGet, tt.url); w.Code != http.StatusForbidden {
+			t.Errorf("%s = %d, want 403", tt.name, w.Code)
+		}
+	}
+
+	// A validly signed URL for a file that's gone is a plain 404
+	if err := s.Delete(ctx, "screenshots/2025/other.png"); err != nil {
+		t.Fatal(err)
+	}
+	if w := fetch(s, http.MethodGet, otherURL); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted file = %d, want 404", w.Code)
+	}
+	if err := s.Delete(ctx, "screenshots/2025/other.png"); err != nil {
+		t.Errorf("deleting a missing key = %v, want nil", err)
+	}
+}
+
+func TestLocalStoragePresignedURLExpiry(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	if _, err := s.Upload(ctx, "exports/events.csv", []byte("id,title"), "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.PresignedURL(ctx, "exports/events.csv", 0); err == nil {
+		t.Error("presigned a URL with no expiry")
+	}
+	presigned, err := s.PresignedURL(ctx, "exports/events.csv", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := fetch(s, http.MethodGet, presigned)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET before expiry = %d", w.Code)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=600" {
+		t.Errorf("Cache-Control = %q, want it cached no longer than the link lives", got)
+	}
+
+	// The signature covers the expiry, so it can't be extended or dropped
+	for name, tampered := range map[string]string{
+		"extended": withQuery(t, presigned, "expires", "1700003600"),
+		"dropped":  withQuery(t, presigned, "expires", ""),
+	} {
+		if w := fetch(s, http.MethodGet, tampered); w.Code != http.StatusForbidden {
+			t.Errorf("%s expiry = %d, want 403", name, w.Code)
+		}
+	}
+
+	now = now.Add(10 * time.Minute)
+	if w := fetch(s, http.MethodGet, presigned); w.Code != http.StatusOK {
+		t.Errorf("GET at expiry = %d, want 200", w.Code)
+	}
+	now = now.Add(time.Second)
+	if w := fetch(s, http.MethodGet, presigned); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "expired") {
+		t.Errorf("GET after expiry = %d %q, want 403", w.Code, w.Body)
+	}
+}
+
+func TestLocalStorageRejectsEscapingKeys(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	outside := filepath.Join(filepath.Dir(s.Root), "outside.txt")
+
+	for _, key := range []string{"", "/", "../outside.txt", "a/../../outside.txt", "a/./b", "a//b", `a\b`, "a\x00b", "dir/"} {
+		if _, err := s.Upload(ctx, key, []byte("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Upload(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := s.PresignedURL(ctx, key, time.Minute); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("PresignedURL(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if _, err := os.Stat(outside); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("a file was written outside the root")
+	}
+	if _, err := s.List(ctx, "../"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("List(../) = %v, want ErrInvalidKey", err)
+	}
+
+	// A signed URL can't be walked out of the root either
+	escaped := s.BaseURL + "/../outside.txt?signature=" + s.sign("../outside.txt", 0)
+	if w := fetch(s, http.MethodGet, escaped); w.Code != http.StatusNotFound {
+		t.Errorf("GET outside the root = %d, want 404", w.Code)
+	}
+}
+
+func TestLocalStorageList(t *testing.T) {
+	s := newTestLocalStorage(t)
+	ctx := context.Background()
+	for _, key := range []string{"screenshots/2025/b.png", "screenshots/2025/a.png", "screenshots/2026/c.png", "exports/d.csv"} {
+		if _, err := s.Upload(ctx, key, []byte(key), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An upload still in progress
+	if err := os.WriteFile(filepath.Join(s.Root, "screenshots", "2025", tempUploadPrefix+"123"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"screenshots/2025/", []string{"screenshots/2025/a.png", "screenshots/2025/b.png"}},
+		{"screenshots/20", []string{"screenshots/2025/a.png", "screenshots/2025/b.png", "screenshots/2026/c.png"}},
+		{"/exports", []string{"exports/d.csv"}},
+		{"missing/", []string{}},
+	}
+	for _, tt := range tests {
+		keys, err := s.List(ctx, tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) = %v", tt.prefix, err)
+		}
+		if strings.Join(keys, ",") != strings.Join(tt.want, ",") || keys == nil {
+			t.Errorf("List(%q) = %q, want %q", tt.prefix, keys, tt.want)
+		}
+	}
+}
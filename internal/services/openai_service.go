@@ -1,16 +1,149 @@
 package services
 
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ericahan22/bug-free-octo-spork/backend-go/internal/utils"
+)
+
+// defaultOpenAIBaseURL is the API root used when OpenAIChatClient.BaseURL is empty
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// defaultEventTimezone is assumed for extracted times that carry no zone
+const defaultEventTimezone = "America/Toronto"
+
+// VisionRequest is a single prompt-plus-image request to a vision model
+type VisionRequest struct {
+	Model        string
+	SystemPrompt string
+	Prompt       string
+	ImageURL     string // http(s) URL or data: URL; empty for text-only prompts
+}
+
+// VisionClient sends a request to a vision-capable chat model and returns the
+// model's text reply. OpenAIChatClient is the production implementation.
+type VisionClient interface {
+	Complete(ctx context.Context, req VisionRequest) (string, error)
+}
+
+// OpenAIChatClient is a VisionClient for the OpenAI chat completions API or
+// any server compatible with it
+type OpenAIChatClient struct {
+	APIKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewOpenAIChatClient creates a chat completions client for the OpenAI API
+func NewOpenAIChatClient(apiKey string) *OpenAIChatClient {
+	return &OpenAIChatClient{
+		APIKey:     apiKey,
+		BaseURL:    defaultOpenAIBaseURL,
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Complete calls POST /chat/completions with a JSON-object response format
+func (c *OpenAIChatClient) Complete(ctx context.Context, req VisionRequest) (string, error) {
+	if c.APIKey == "" {
+		return "", errors.New("OpenAI API key is not configured")
+	}
+
+	type contentPart struct {
+		Type     string            `json:"type"`
+		Text     string            `json:"text,omitempty"`
+		ImageURL map[string]string `json:"image_url,omitempty"`
+	}
+	messages := []map[string]interface{}{}
+	if req.SystemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": req.SystemPrompt})
+	}
+	content := []contentPart{{Type: "text", Text: req.Prompt}}
+	if req.ImageURL != "" {
+		content = append(content, contentPart{Type: "image_url", ImageURL: map[string]string{"url": req.ImageURL}})
+	}
+	messages = append(messages, map[string]interface{}{"role": "user", "content": content})
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":           req.Model,
+		"messages":        messages,
+		"response_format": map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("openai request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("openai response: status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != nil {
+			return "", fmt.Errorf("openai response: status %d: %s", resp.StatusCode, body.Error.Message)
+		}
+		return "", fmt.Errorf("openai response: status %d", resp.StatusCode)
+	}
+	if len(body.Choices) == 0 {
+		return "", errors.New("openai response: no choices")
+	}
+
+	return body.Choices[0].Message.Content, nil
+}
+
 // OpenAIService provides OpenAI API integration
 type OpenAIService struct {
 	APIKey string
+	Client VisionClient
 }
 
 // NewOpenAIService creates a new OpenAI service instance
 func NewOpenAIService(apiKey string) *OpenAIService {
-	return &OpenAIService{APIKey: apiKey}
+	return &OpenAIService{APIKey: apiKey, Client: NewOpenAIChatClient(apiKey)}
 }
 
-// EventExtractionResult represents extracted event data from image analysis
+// EventExtractionResult represents extracted event data from image analysis.
+// Occurrences hold normalised dtstart_utc/dtend_utc (RFC 3339, UTC) and tz
+// strings, the shape SubmitEvent accepts. Warnings list what couldn't be
+// extracted, so a partial result can still prefill a submission form.
 type EventExtractionResult struct {
 	Title        string                   `json:"title"`
 	Description  string                   `json:"description"`
@@ -18,9 +151,21 @@ type EventExtractionResult struct {
 	Price        *float64                 `json:"price"`
 	Food         string                   `json:"food"`
 	Registration bool                     `json:"registration"`
+	Categories   []string                 `json:"categories"`
 	Occurrences  []map[string]interface{} `json:"occurrences"`
+	Warnings     []string                 `json:"warnings,omitempty"`
 }
 
+const extractionSystemPrompt = `You extract university club events from social media posts and posters.
+Reply with a JSON object {"events": [...]} where each event has:
+  "title" (string), "description" (string), "location" (string),
+  "price" (number, 0 if free, null if not stated), "food" (string, empty if none),
+  "registration" (boolean, true if sign-up is required), "categories" (array of short strings),
+  "occurrences": array of {"dtstart": string, "dtend": string or null, "tz": IANA zone name}.
+Give dtstart/dtend as local wall-clock time "YYYY-MM-DDTHH:MM:SS" in the zone named by tz
+(default ` + defaultEventTimezone + `). List every date a recurring or multi-day event happens on.
+Use {"events": []} if the image does not describe an event. Never invent details.`
+
 // ExtractEventsFromCaption extracts event information from an image
 // Parameters:
 //   - sourceImageURL: URL of the image to analyze
@@ -28,13 +173,192 @@ type EventExtractionResult struct {
 //
 // Returns: Array of extracted event data
 func (s *OpenAIService) ExtractEventsFromCaption(sourceImageURL string, model string) ([]EventExtractionResult, error) {
-	// TODO: Implement OpenAI vision API call
-	// 1. Prepare request with image URL and prompt
-	// 2. Call OpenAI API to extract event information from image
-	// 3. Parse response into EventExtractionResult structs
-	// 4. Return array of extracted events
+	return s.extractEvents(context.Background(), sourceImageURL, model)
+}
+
+// ExtractEventsFromImage is ExtractEventsFromCaption for raw image bytes,
+// which are sent inline so the image needn't be publicly reachable
+func (s *OpenAIService) ExtractEventsFromImage(ctx context.Context, data []byte, contentType, model string) ([]EventExtractionResult, error) {
+	dataURL := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return s.extractEvents(ctx, dataURL, model)
+}
+
+// ExtractEventsFromPost extracts events from a social media post's caption
+// and (optional) image
+func (s *OpenAIService) ExtractEventsFromPost(ctx context.Context, caption, imageURL, model string) ([]EventExtractionResult, error) {
+	prompt := "Extract the events announced by this Instagram post."
+	if imageURL != "" {
+		prompt += " The post's image is attached."
+	}
+	prompt += "\n\nCaption:\n" + caption
+	return s.extract(ctx, prompt, imageURL, model)
+}
+
+func (s *OpenAIService) extractEvents(ctx context.Context, imageURL, model string) ([]EventExtractionResult, error) {
+	return s.extract(ctx, "Extract the events in this image.", imageURL, model)
+}
+
+func (s *OpenAIService) extract(ctx context.Context, prompt, imageURL, model string) ([]EventExtractionResult, error) {
+	if s.Client == nil {
+		return nil, errors.New("no vision client configured")
+	}
+
+	today := time.Now().In(loadLocationOrUTC(defaultEventTimezone)).Format("Monday, 2006-01-02")
+	reply, err := s.Client.Complete(ctx, VisionRequest{
+		Model:        model,
+		SystemPrompt: extractionSystemPrompt,
+		Prompt:       "Today is " + today + ". " + prompt,
+		ImageURL:     imageURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseEventExtraction(reply)
+}
+
+// ParseEventExtraction parses a model reply into extraction results. Dates are
+// normalised to UTC; occurrences that can't be parsed are dropped with a
+// warning rather than failing the whole result.
+func ParseEventExtraction(reply string) ([]EventExtractionResult, error) {
+	reply = strings.TrimSpace(reply)
+	reply = strings.TrimPrefix(reply, "```json")
+	reply = strings.TrimPrefix(reply, "```")
+	reply = strings.TrimSuffix(reply, "```")
+
+	type rawOccurrence struct {
+		Dtstart    string `json:"dtstart"`
+		Dtend      string `json:"dtend"`
+		DtstartUTC string `json:"dtstart_utc"`
+		DtendUTC   string `json:"dtend_utc"`
+		TZ         string `json:"tz"`
+	}
+	type rawEvent struct {
+		Title        string          `json:"title"`
+		Description  string          `json:"description"`
+		Location     string          `json:"location"`
+		Price        *float64        `json:"price"`
+		Food         string          `json:"food"`
+		Registration bool            `json:"registration"`
+		Categories   []string        `json:"categories"`
+		Occurrences  []rawOccurrence `json:"occurrences"`
+	}
+
+	var envelope struct {
+		Events []rawEvent `json:"events"`
+	}
+	if err := json.Unmarshal([]byte(reply), &envelope); err != nil {
+		// Some models answer with a bare array
+		if arrErr := json.Unmarshal([]byte(reply), &envelope.Events); arrErr != nil {
+			return nil, fmt.Errorf("parse extraction: %w", err)
+		}
+	}
+
+	results := make([]EventExtractionResult, 0, len(envelope.Events))
+	for _, raw := range envelope.Events {
+		result := EventExtractionResult{
+			Title:        strings.TrimSpace(raw.Title),
+			Description:  strings.TrimSpace(raw.Description),
+			Location:     strings.TrimSpace(raw.Location),
+			Price:        raw.Price,
+			Food:         strings.TrimSpace(raw.Food),
+			Registration: raw.Registration,
+			Categories:   raw.Categories,
+			Occurrences:  []map[string]interface{}{},
+		}
+		if result.Categories == nil {
+			result.Categories = []string{}
+		}
+		if result.Price != nil && *result.Price < 0 {
+			result.Price = nil
+		}
+
+		for i, occ := range raw.Occurrences {
+			tz := occ.TZ
+			if _, err := time.LoadLocation(tz); tz == "" || err != nil {
+				tz = defaultEventTimezone
+			}
+
+			start, err := normaliseDateTime(firstNonEmpty(occ.Dtstart, occ.DtstartUTC), tz)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("occurrence %d: could not read the start time", i+1))
+				continue
+			}
+			occurrence := map[string]interface{}{
+				"dtstart_utc": utils.FormatUTCDateTime(start),
+				"dtend_utc":   nil,
+				"tz":          tz,
+			}
+
+			if rawEnd := firstNonEmpty(occ.Dtend, occ.DtendUTC); rawEnd != "" {
+				end, err := normaliseDateTime(rawEnd, tz)
+				switch {
+				case err != nil:
+					result.Warnings = append(result.Warnings, fmt.Sprintf("occurrence %d: could not read the end time", i+1))
+				case !end.After(start):
+					result.Warnings = append(result.Warnings, fmt.Sprintf("occurrence %d: end time is before the start", i+1))
+				default:
+					occurrence["dtend_utc"] = utils.FormatUTCDateTime(end)
+				}
+			}
+
+			result.Occurrences = append(result.Occurrences, occurrence)
+		}
+
+		if result.Title == "" {
+			result.Warnings = append(result.Warnings, "no title found")
+		}
+		if result.Location == "" {
+			result.Warnings = append(result.Warnings, "no location found")
+		}
+		if len(result.Occurrences) == 0 {
+			result.Warnings = append(result.Warnings, "no dates found")
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// localDateTimeLayouts are zone-less forms read as wall-clock time in the
+// occurrence's tz
+var localDateTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// normaliseDateTime parses value as local time in tz when it has no zone,
+// falling back to utils.ParseUTCDateTime for RFC 3339 and date-only values
+func normaliseDateTime(value, tz string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if loc, err := time.LoadLocation(tz); err == nil {
+		for _, layout := range localDateTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+	return utils.ParseUTCDateTime(value)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
 
-	return []EventExtractionResult{}, nil
+func loadLocationOrUTC(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 // GenerateEventDescription generates a description for an event
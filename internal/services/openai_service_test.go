@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatCompletionsServer is a local stand-in for the chat completions API. It
+// records the last request body and answers with status and response.
+func chatCompletionsServer(t *testing.T, status int, response string) (*httptest.Server, *map[string]interface{}) {
+	t.Helper()
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request = %s %s, want POST /v1/chat/completions", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func chatReply(content string) string {
+	reply, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{
+			map[string]interface{}{"message": map[string]string{"role": "assistant", "content": content}},
+		},
+	})
+	return string(reply)
+}
+
+func TestOpenAIChatClientComplete(t *testing.T) {
+	server, received := chatCompletionsServer(t, http.StatusOK, chatReply(`{"events": []}`))
+	client := &OpenAIChatClient{APIKey: "test-key", BaseURL: server.URL + "/v1/", HTTPClient: server.Client()}
+
+	reply, err := client.Complete(context.Background(), VisionRequest{
+		Model:        "gpt-4o-mini",
+		SystemPrompt: "system",
+		Prompt:       "prompt",
+		ImageURL:     "data:image/png;base64,AAAA",
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if reply != `{"events": []}` {
+		t.Errorf("reply = %q", reply)
+	}
+
+	body := *received
+	if body["model"] != "gpt-4o-mini" {
+		t.Errorf("model = %v", body["model"])
+	}
+	if format, _ := body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("response_format = %v", body["response_format"])
+	}
+	messages, _ := body["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Fatalf("messages = %v, want system and user", messages)
+	}
+	if system := messages[0].(map[string]interface{}); system["role"] != "system" || system["content"] != "system" {
+		t.Errorf("system message = %v", system)
+	}
+	user := messages[1].(map[string]interface{})
+	parts, _ := user["content"].([]interface{})
+	if user["role"] != "user" || len(parts) != 2 {
+		t.Fatalf("user message = %v, want text and image parts", user)
+	}
+	image := parts[1].(map[string]interface{})
+	if url := image["image_url"].(map[string]interface{})["url"]; image["type"] != "image_url" || url != "data:image/png;base64,AAAA" {
+		t.Errorf("image part = %v", image)
+	}
+}
+
+func TestOpenAIChatClientCompleteErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+	}{
+		{"api error", http.StatusTooManyRequests, `{"error": {"message": "Rate limit reached"}}`, "status 429: Rate limit reached"},
+		{"bare error status", http.StatusBadGateway, `{}`, "status 502"},
+		{"no choices", http.StatusOK, `{"choices": []}`, "no choices"},
+		{"malformed body", http.StatusOK, `<html>`, "openai response: status 200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := chatCompletionsServer(t, tt.status, tt.response)
+			client := &OpenAIChatClient{APIKey: "test-key", BaseURL: server.URL + "/v1", HTTPClient: server.Client()}
+
+			_, err := client.Complete(context.Background(), VisionRequest{Model: "m", Prompt: "p"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Complete error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing api key", func(t *testing.T) {
+		if _, err := (&OpenAIChatClient{}).Complete(context.Background(), VisionRequest{}); err == nil {
+			t.Fatal("Complete without an API key succeeded")
+		}
+	})
+}
+
+func TestExtractEventsFromImagePartialResult(t *testing.T) {
+	content := `{"events": [{
+		"title": "Board Games Night",
+		"price": 0,
+		"categories": ["Social"],
+		"occurrences": [
+			{"dtstart": "2026-03-05T18:30:00", "dtend": "2026-03-05T21:00:00", "tz": "America/Toronto"},
+			{"dtstart": "next thursday", "tz": "America/Toronto"},
+			{"dtstart": "2026-03-19T18:30:00", "dtend": "2026-03-19T17:00:00", "tz": "Not/AZone"}
+		]
+	}]}`
+	server, received := chatCompletionsServer(t, http.StatusOK, chatReply(content))
+	service := &OpenAIService{Client: &OpenAIChatClient{APIKey: "test-key", BaseURL: server.URL + "/v1", HTTPClient: server.Client()}}
+
+	results, err := service.ExtractEventsFromImage(context.Background(), []byte("png"), "image/png", "gpt-4o-mini")
+	if err != nil {
+		t.Fatalf("ExtractEventsFromImage: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %d, want 1", len(results))
+	}
+
+	messages := (*received)["messages"].([]interface{})
+	parts := messages[1].(map[string]interface{})["content"].([]interface{})
+	if url := parts[1].(map[string]interface{})["image_url"].(map[string]interface{})["url"]; url != "data:image/png;base64,cG5n" {
+		t.Errorf("image url = %v, want the inline data URL", url)
+	}
+
+	result := results[0]
+	if result.Title != "Board Games Night" {
+		t.Errorf("title = %q", result.Title)
+	}
+	if len(result.Occurrences) != 2 {
+		t.Fatalf("occurrences = %v, want the two readable ones", result.Occurrences)
+	}
+	first := result.Occurrences[0]
+	if first["dtstart_utc"] != "2026-03-05T23:30:00Z" || first["dtend_utc"] != "2026-03-06T02:00:00Z" {
+		t.Errorf("first occurrence = %v, want Toronto time in UTC", first)
+	}
+	if second := result.Occurrences[1]; second["tz"] != defaultEventTimezone || second["dtend_utc"] != nil {
+		t.Errorf("second occurrence = %v, want the default zone and no end", second)
+	}
+
+	want := []string{
+		"occurrence 2: could not read the start time",
+		"occurrence 3: end time is before the start",
+		"no location found",
+	}
+	if strings.Join(result.Warnings, "|") != strings.Join(want, "|") {
+		t.Errorf("warnings = %q, want %q", result.Warnings, want)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3Storage
+type S3Config struct {
+	Endpoint  string // host[:port]; empty means AWS (s3.amazonaws.com)
+	Region    string
+	Bucket    string
+	AccessKey string // empty uses the AWS environment or instance credentials
+	SecretKey string
+	UseSSL    bool
+	PublicURL string // base URL objects are served from (e.g. CloudFront); defaults to the bucket URL
+}
+
+// S3Storage is a Storage backed by Amazon S3 or any S3-compatible service
+// such as MinIO
+type S3Storage struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewS3Storage creates an S3 storage for cfg.Bucket
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3 storage needs a bucket")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	creds := credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
+	if cfg.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		})
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client: %w", err)
+	}
+
+	publicURL := strings.TrimRight(cfg.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = strings.TrimRight(client.EndpointURL().String(), "/") + "/" + cfg.Bucket
+	}
+
+	return &S3Storage{client: client, bucket: cfg.Bucket, publicURL: publicURL}, nil
+}
+
+// Upload stores data under key
+func (s *S3Storage) Upload(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return s.put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// UploadFromReader streams reader to key; unknown lengths are sent as a
+// multipart upload
+func (s *S3Storage) UploadFromReader(ctx context.Context, key string, reader io.Reader, contentType string) (string, error) {
+	return s.put(ctx, key, reader, -1, contentType)
+}
+
+func (s *S3Storage) put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.client.PutObject(ctx, s.bucket, key, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("upload %s: %w", key, err)
+	}
+
+	return s.publicURL + "/" + escapeKey(key), nil
+}
+
+// Delete removes key
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// PresignedURL returns a SigV4 presigned GET URL for key
+func (s *S3Storage) PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	presigned, err := s.client.PresignedGetObject(ctx, s.bucket, key, expiry, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("presign %s: %w", key, err)
+	}
+	return presigned.String(), nil
+}
+
+// List returns the keys under prefix
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    strings.TrimPrefix(prefix, "/"),
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("list %s: %w", prefix, object.Err)
+		}
+		keys = append(keys, object.Key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// escapeKey escapes each segment of key for use in a URL path
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
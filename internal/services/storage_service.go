@@ -1,80 +1,49 @@
 package services
 
-import "io"
-
-// StorageService provides cloud storage functionality (S3)
-type StorageService struct {
-	AWSRegion      string
-	AWSBucket      string
-	AWSAccessKey   string
-	AWSSecretKey   string
-	CloudFrontURL  string
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrInvalidKey is returned for storage keys that are empty or escape the
+// storage root
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage stores uploaded files (event images, screenshots) under
+// slash-separated keys such as "screenshots/2025/01/31/abc.png".
+// LocalStorage and S3Storage implement it; config.InitStorage picks one.
+type Storage interface {
+	// Upload stores data under key and returns its public URL
+	Upload(ctx context.Context, key string, data []byte, contentType string) (string, error)
+
+	// UploadFromReader is Upload for streamed content
+	UploadFromReader(ctx context.Context, key string, reader io.Reader, contentType string) (string, error)
+
+	// Delete removes key; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+
+	// PresignedURL returns a URL granting read access to key until expiry elapses
+	PresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// List returns the keys that start with prefix, sorted
+	List(ctx context.Context, prefix string) ([]string, error)
 }
 
-// NewStorageService creates a new storage service instance
-func NewStorageService(region, bucket, accessKey, secretKey, cloudFrontURL string) *StorageService {
-	return &StorageService{
-		AWSRegion:     region,
-		AWSBucket:     bucket,
-		AWSAccessKey:  accessKey,
-		AWSSecretKey:  secretKey,
-		CloudFrontURL: cloudFrontURL,
+// cleanKey normalises key and rejects keys that are empty or would escape
+// the storage root
+func cleanKey(key string) (string, error) {
+	if strings.ContainsAny(key, "\\\x00") {
+		return "", ErrInvalidKey
 	}
-}
-
-// UploadImage uploads an image to S3
-// Parameters:
-//   - data: Image data as byte slice
-//   - filename: Destination filename (including path)
-//
-// Returns: Public URL of uploaded image
-func (s *StorageService) UploadImage(data []byte, filename string) (string, error) {
-	// TODO: Implement S3 upload
-	// 1. Initialize S3 client with AWS credentials
-	// 2. Prepare upload request with content type
-	// 3. Upload data to S3 bucket
-	// 4. Return public URL (CloudFront or S3 URL)
-
-	return "", nil
-}
-
-// UploadImageFromReader uploads an image from an io.Reader
-func (s *StorageService) UploadImageFromReader(reader io.Reader, filename string, contentType string) (string, error) {
-	// TODO: Implement S3 upload from reader
-	// Similar to UploadImage but reads from io.Reader
-
-	return "", nil
-}
 
-// DeleteImage deletes an image from S3
-func (s *StorageService) DeleteImage(filename string) error {
-	// TODO: Implement S3 delete
-	// 1. Initialize S3 client
-	// 2. Delete object from bucket
-	// 3. Return error if any
-
-	return nil
-}
-
-// GetPresignedURL generates a presigned URL for temporary access
-// Parameters:
-//   - filename: File path in S3
-//   - expirationMinutes: URL expiration time in minutes
-func (s *StorageService) GetPresignedURL(filename string, expirationMinutes int) (string, error) {
-	// TODO: Implement presigned URL generation
-	// 1. Initialize S3 client
-	// 2. Generate presigned URL with expiration
-	// 3. Return URL
-
-	return "", nil
-}
-
-// ListFiles lists files in a specific S3 prefix
-func (s *StorageService) ListFiles(prefix string) ([]string, error) {
-	// TODO: Implement S3 list operation
-	// 1. Initialize S3 client
-	// 2. List objects with given prefix
-	// 3. Return array of file keys
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
 
-	return []string{}, nil
+	return cleaned, nil
 }
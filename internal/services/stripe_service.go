@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStripeBaseURL is the API root used when StripeAPIClient.BaseURL is empty
+const defaultStripeBaseURL = "https://api.stripe.com/v1"
+
+// CheckoutSessionParams describes a one-item Stripe Checkout payment
+type CheckoutSessionParams struct {
+	ProductName       string
+	UnitAmount        int64  // in the currency's minor unit, e.g. cents
+	Currency          string // ISO code, e.g. "cad"
+	SuccessURL        string // may contain {CHECKOUT_SESSION_ID}
+	CancelURL         string
+	ClientReferenceID string
+	CustomerEmail     string
+	Metadata          map[string]string
+	// IdempotencyKey makes retried creates return the same session
+	IdempotencyKey string
+}
+
+// CheckoutSession is the part of a Stripe Checkout Session we use
+type CheckoutSession struct {
+	ID        string
+	URL       string
+	ExpiresAt time.Time
+}
+
+// StripeClient creates Stripe Checkout sessions. StripeAPIClient is the
+// production implementation.
+type StripeClient interface {
+	CreateCheckoutSession(ctx context.Context, params CheckoutSessionParams) (*CheckoutSession, error)
+}
+
+// StripeAPIClient is a StripeClient for the Stripe REST API
+type StripeAPIClient struct {
+	SecretKey  string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewStripeAPIClient creates a client for the Stripe API
+func NewStripeAPIClient(secretKey string) *StripeAPIClient {
+	return &StripeAPIClient{
+		SecretKey:  secretKey,
+		BaseURL:    defaultStripeBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CreateCheckoutSession calls POST /checkout/sessions in payment mode
+func (c *StripeAPIClient) CreateCheckoutSession(ctx context.Context, params CheckoutSessionParams) (*CheckoutSession, error) {
+	if c.SecretKey == "" {
+		return nil, errors.New("Stripe secret key is not configured")
+	}
+
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", params.SuccessURL)
+	form.Set("cancel_url", params.CancelURL)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(params.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(params.UnitAmount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", params.ProductName)
+	if params.ClientReferenceID != "" {
+		form.Set("client_reference_id", params.ClientReferenceID)
+	}
+	if params.CustomerEmail != "" {
+		form.Set("customer_email", params.CustomerEmail)
+	}
+	for key, value := range params.Metadata {
+		form.Set("metadata["+key+"]", value)
+		// Copy onto the payment intent so charge events carry it too
+		form.Set("payment_intent_data[metadata]["+key+"]", value)
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = defaultStripeBaseURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(baseURL, "/")+"/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(c.SecretKey, "")
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if params.IdempotencyKey != "" {
+		httpReq.Header.Set("Idempotency-Key", params.IdempotencyKey)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("stripe request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		ID        string `json:"id"`
+		URL       string `json:"url"`
+		ExpiresAt int64  `json:"expires_at"`
+		Error     *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("stripe response: status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != nil {
+			return nil, fmt.Errorf("stripe response: status %d: %s", resp.StatusCode, body.Error.Message)
+		}
+		return nil, fmt.Errorf("stripe response: status %d", resp.StatusCode)
+	}
+
+	return &CheckoutSession{ID: body.ID, URL: body.URL, ExpiresAt: time.Unix(body.ExpiresAt, 0)}, nil
+}
+
+// StripeEvent is a verified Stripe webhook event
+type StripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Stripe webhook verification errors
+var (
+	ErrStripeSignature = errors.New("invalid Stripe signature")
+	ErrStripeTimestamp = errors.New("Stripe webhook timestamp outside tolerance")
+)
+
+// ConstructStripeEvent verifies payload against its Stripe-Signature header
+// and parses it. Events signed more than tolerance from now are rejected, so
+// captured requests can't be replayed.
+func ConstructStripeEvent(payload []byte, signatureHeader, secret string, tolerance time.Duration) (*StripeEvent, error) {
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 || secret == "" {
+		return nil, ErrStripeSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrStripeSignature
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return nil, ErrStripeTimestamp
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		if hmac.Equal(expected, signature) {
+			var event StripeEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				return nil, fmt.Errorf("parse Stripe event: %w", err)
+			}
+			return &event, nil
+		}
+	}
+	return nil, ErrStripeSignature
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// stripeSignature signs payload as Stripe does for a delivery at signedAt
+func stripeSignature(secret string, signedAt time.Time, payload []byte) string {
+	timestamp := fmt.Sprint(signedAt.Unix())
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestConstructStripeEvent(t *testing.T) {
+	payload := []byte(`{"id": "evt_1", "type": "checkout.session.completed", "data": {"object": {"id": "cs_1"}}}`)
+	now := time.Now()
+	valid := stripeSignature(testWebhookSecret, now, payload)
+	forged := stripeSignature("whsec_other", now, payload)
+
+	tests := []struct {
+		name    string
+		header  string
+		payload []byte
+		wantErr error
+	}{
+		{"valid", fmt.Sprintf("t=%d,v1=%s", now.Unix(), valid), payload, nil},
+		{"multiple v1 values", fmt.Sprintf("t=%d, v1=%s, v1=not-hex, v1=%s, v0=%s", now.Unix(), forged, valid, forged), payload, nil},
+		{"bad signature", fmt.Sprintf("t=%d,v1=%s", now.Unix(), forged), payload, ErrStripeSignature},
+		{"tampered payload", fmt.Sprintf("t=%d,v1=%s", now.Unix(), valid), []byte(`{"id": "evt_2"}`), ErrStripeSignature},
+		{"no v1 signature", fmt.Sprintf("t=%d,v0=%s", now.Unix(), valid), payload, ErrStripeSignature},
+		{"no timestamp", "v1=" + valid, payload, ErrStripeSignature},
+		{"malformed timestamp", "t=soon,v1=" + valid, payload, ErrStripeSignature},
+		{"empty header", "", payload, ErrStripeSignature},
+		{
+			"stale timestamp",
+			fmt.Sprintf("t=%d,v1=%s", now.Add(-10*time.Minute).Unix(), stripeSignature(testWebhookSecret, now.Add(-10*time.Minute), payload)),
+			payload, ErrStripeTimestamp,
+		},
+		{
+			"future timestamp",
+			fmt.Sprintf("t=%d,v1=%s", now.Add(10*time.Minute).Unix(), stripeSignature(testWebhookSecret, now.Add(10*time.Minute), payload)),
+			payload, ErrStripeTimestamp,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ConstructStripeEvent(tt.payload, tt.header, testWebhookSecret, 5*time.Minute)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConstructStripeEvent: %v", err)
+			}
+			if event.ID != "evt_1" || event.Type != "checkout.session.completed" || string(event.Data.Object) != `{"id": "cs_1"}` {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+
+	t.Run("no secret", func(t *testing.T) {
+		header := fmt.Sprintf("t=%d,v1=%s", now.Unix(), stripeSignature("", now, payload))
+		if _, err := ConstructStripeEvent(payload, header, "", 5*time.Minute); !errors.Is(err, ErrStripeSignature) {
+			t.Fatalf("error = %v, want %v", err, ErrStripeSignature)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+// Package testutil holds helpers shared by the packages' tests
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenDB opens a private in-memory SQLite database with models migrated, for
+// tests of code that only needs portable SQL. It's closed when t finishes.
+func OpenDB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	// Shared cache keeps one database across the pool's connections; the
+	// name keeps it private to this test
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", name)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
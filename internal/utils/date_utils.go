@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -26,7 +27,7 @@ func ParseUTCDateTime(dateStr string) (time.Time, error) {
 		}
 	}
 
-	return time.Time{}, nil
+	return time.Time{}, fmt.Errorf("unrecognised datetime format: %q", dateStr)
 }
 
 // FormatUTCDateTime formats a time.Time into ISO 8601 string
@@ -1,5 +1,11 @@
 package utils
 
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
 // DetermineDisplayHandle returns the most relevant social media handle for an event
 // Priority: IG > Discord > X > TikTok > FB > Other
 func DetermineDisplayHandle(igHandle, discordHandle, xHandle, tiktokHandle, fbHandle, otherHandle *string) string {
@@ -30,10 +36,27 @@ func DetermineDisplayHandle(igHandle, discordHandle, xHandle, tiktokHandle, fbHa
 
 // BuildEventURL constructs a full event URL
 func BuildEventURL(eventID uint, baseURL string) string {
-	// TODO: Implement URL construction
-	// Build full URL for event detail page
+	return fmt.Sprintf("%s/events/%d", strings.TrimRight(baseURL, "/"), eventID)
+}
 
-	return ""
+// NormalizeHandle reduces a social handle or profile URL to a bare lowercase
+// handle, e.g. "https://www.instagram.com/UWCSClub/" and "@uwcsclub" both
+// become "uwcsclub"
+func NormalizeHandle(handle string) string {
+	handle = strings.TrimSpace(handle)
+	if i := strings.Index(handle, "://"); i >= 0 {
+		handle = handle[i+3:]
+		if j := strings.Index(handle, "/"); j >= 0 {
+			handle = handle[j+1:]
+		} else {
+			handle = ""
+		}
+	}
+	if i := strings.IndexAny(handle, "/?#"); i >= 0 {
+		handle = handle[:i]
+	}
+
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
 }
 
 // TruncateText truncates text to a maximum length with ellipsis
@@ -47,3 +70,61 @@ func TruncateText(text string, maxLength int) string {
 
 	return text[:maxLength-3] + "..."
 }
+
+// NormalizeText lowercases text, drops punctuation, symbols and emoji, and
+// collapses whitespace, so "🎉 Games Night!!" and "games night" compare equal
+func NormalizeText(text string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// TextSimilarity scores how alike two strings are, from 0 to 1, as the Dice
+// coefficient of the character bigrams of their normalised words. It is
+// tolerant of word order, small typos and decoration.
+func TextSimilarity(a, b string) float64 {
+	bigramsA, bigramsB := textBigrams(NormalizeText(a)), textBigrams(NormalizeText(b))
+	total := len(bigramsA) + len(bigramsB)
+	if total == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(bigramsA))
+	for _, bigram := range bigramsA {
+		counts[bigram]++
+	}
+	shared := 0
+	for _, bigram := range bigramsB {
+		if counts[bigram] > 0 {
+			counts[bigram]--
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(total)
+}
+
+// textBigrams returns the character bigrams of each word, padded so single
+// letters and word boundaries count
+func textBigrams(text string) []string {
+	var bigrams []string
+	for _, word := range strings.Fields(text) {
+		runes := []rune(" " + word + " ")
+		for i := 0; i+1 < len(runes); i++ {
+			bigrams = append(bigrams, string(runes[i:i+2]))
+		}
+	}
+	return bigrams
+}
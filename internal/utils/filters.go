@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"encoding/json"
+
 	"gorm.io/gorm"
 )
 
@@ -9,10 +11,12 @@ type EventFilter struct {
 	Search       string
 	Categories   []string
 	ClubType     string
+	ClubTypes    []string // any of, e.g. from newsletter preferences
 	HasFood      *bool
 	IsFree       *bool
 	Registration *bool
 	School       string
+	Schools      []string // any of
 }
 
 // ApplyEventFilters applies filters to a GORM query
@@ -34,13 +38,27 @@ func (f *EventFilter) ApplyEventFilters(query *gorm.DB) *gorm.DB {
 	}
 
 	if len(f.Categories) > 0 {
-		// TODO: Filter by categories (JSONB array contains)
+		// Match events tagged with any of the requested categories
+		conds := query.Session(&gorm.Session{NewDB: true})
+		for i, category := range f.Categories {
+			encoded, _ := json.Marshal([]string{category})
+			if i == 0 {
+				conds = conds.Where("categories @> ?::jsonb", string(encoded))
+			} else {
+				conds = conds.Or("categories @> ?::jsonb", string(encoded))
+			}
+		}
+		query = query.Where(conds)
 	}
 
 	if f.ClubType != "" {
 		query = query.Where("club_type = ?", f.ClubType)
 	}
 
+	if len(f.ClubTypes) > 0 {
+		query = query.Where("club_type IN ?", f.ClubTypes)
+	}
+
 	if f.HasFood != nil && *f.HasFood {
 		query = query.Where("food IS NOT NULL AND food != ''")
 	}
@@ -57,6 +75,10 @@ func (f *EventFilter) ApplyEventFilters(query *gorm.DB) *gorm.DB {
 		query = query.Where("school = ?", f.School)
 	}
 
+	if len(f.Schools) > 0 {
+		query = query.Where("school IN ?", f.Schools)
+	}
+
 	return query
 }
 
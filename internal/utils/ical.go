@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	icalLineLimit = 75 // octets per content line, excluding CRLF (RFC 5545 §3.1)
+	icalCRLF      = "\r\n"
+
+	icalUTCLayout   = "20060102T150405Z"
+	icalLocalLayout = "20060102T150405"
+)
+
+// ICalBuilder incrementally writes an RFC 5545 iCalendar stream.
+// Every content line is folded at 75 octets and terminated with CRLF.
+type ICalBuilder struct {
+	sb strings.Builder
+}
+
+// NewICalBuilder starts a VCALENDAR with the given product identifier and display name
+func NewICalBuilder(prodID, calName string) *ICalBuilder {
+	b := &ICalBuilder{}
+	b.Begin("VCALENDAR")
+	b.Line("VERSION", "2.0")
+	b.Line("PRODID", prodID)
+	b.Line("CALSCALE", "GREGORIAN")
+	b.Line("METHOD", "PUBLISH")
+	if calName != "" {
+		b.Text("X-WR-CALNAME", calName)
+	}
+	return b
+}
+
+// Begin opens a component (VEVENT, VTIMEZONE, ...)
+func (b *ICalBuilder) Begin(component string) {
+	b.Line("BEGIN", component)
+}
+
+// End closes a component
+func (b *ICalBuilder) End(component string) {
+	b.Line("END", component)
+}
+
+// Line writes a property whose value is already in its iCalendar form.
+// name may carry parameters, e.g. "DTSTART;TZID=America/Toronto".
+func (b *ICalBuilder) Line(name, value string) {
+	b.sb.WriteString(FoldICalLine(name + ":" + value))
+	b.sb.WriteString(icalCRLF)
+}
+
+// Text writes a TEXT-valued property, escaping the value
+func (b *ICalBuilder) Text(name, value string) {
+	b.Line(name, EscapeICalText(value))
+}
+
+// Timezone writes a VTIMEZONE for loc covering every offset transition
+// between from and to, plus the observance in effect at from
+func (b *ICalBuilder) Timezone(loc *time.Location, from, to time.Time) {
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	end := time.Date(to.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	b.Begin("VTIMEZONE")
+	b.Line("TZID", loc.String())
+
+	name, offset := start.Zone()
+	b.observance(start.IsDST(), start.Add(time.Duration(offset)*time.Second), offset, offset, name)
+
+	for _, tr := range zoneTransitions(loc, start, end) {
+		b.observance(tr.isDST, tr.at.Add(time.Duration(tr.offsetFrom)*time.Second), tr.offsetFrom, tr.offsetTo, tr.name)
+	}
+
+	b.End("VTIMEZONE")
+}
+
+// observance writes a STANDARD or DAYLIGHT sub-component; onset is the
+// wall-clock time of the transition expressed in the previous offset
+func (b *ICalBuilder) observance(isDST bool, onset time.Time, offsetFrom, offsetTo int, name string) {
+	component := "STANDARD"
+	if isDST {
+		component = "DAYLIGHT"
+	}
+
+	b.Begin(component)
+	b.Line("DTSTART", onset.UTC().Format(icalLocalLayout))
+	b.Line("TZOFFSETFROM", formatICalOffset(offsetFrom))
+	b.Line("TZOFFSETTO", formatICalOffset(offsetTo))
+	if name != "" {
+		b.Text("TZNAME", name)
+	}
+	b.End(component)
+}
+
+// String closes the VCALENDAR and returns the complete stream
+func (b *ICalBuilder) String() string {
+	b.End("VCALENDAR")
+	return b.sb.String()
+}
+
+// EscapeICalText escapes a TEXT value per RFC 5545 §3.3.11
+func EscapeICalText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// FoldICalLine splits a content line into 75-octet chunks joined by CRLF and
+// a single space, never breaking a multi-byte UTF-8 sequence
+func FoldICalLine(line string) string {
+	if len(line) <= icalLineLimit {
+		return line
+	}
+
+	var sb strings.Builder
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString(icalCRLF + " ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = icalLineLimit - 1
+	}
+	sb.WriteString(line)
+
+	return sb.String()
+}
+
+// FormatICalUTC formats t as a UTC DATE-TIME value
+func FormatICalUTC(t time.Time) string {
+	return t.UTC().Format(icalUTCLayout)
+}
+
+// FormatICalLocal formats t as a floating DATE-TIME in loc, for use with a TZID parameter
+func FormatICalLocal(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(icalLocalLayout)
+}
+
+// FormatICalDuration formats d as an RFC 5545 DURATION value
+func FormatICalDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + FormatICalDuration(-d)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("P")
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		fmt.Fprintf(&sb, "%dD", days)
+	}
+
+	if d > 0 || days == 0 {
+		sb.WriteString("T")
+		hours := d / time.Hour
+		d -= hours * time.Hour
+		minutes := d / time.Minute
+		d -= minutes * time.Minute
+		seconds := d / time.Second
+
+		if hours > 0 {
+			fmt.Fprintf(&sb, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(&sb, "%dM", minutes)
+		}
+		if seconds > 0 || (hours == 0 && minutes == 0) {
+			fmt.Fprintf(&sb, "%dS", seconds)
+		}
+	}
+
+	return sb.String()
+}
+
+// formatICalOffset formats a UTC offset in seconds as ±HHMM[SS]
+func formatICalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	hours, minutes, seconds := offset/3600, (offset%3600)/60, offset%60
+	if seconds != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
+}
+
+// zoneTransition is a change of UTC offset in a location
+type zoneTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	isDST      bool
+}
+
+// zoneTransitions finds every offset change in loc between from and to.
+// Go doesn't expose tzdata rules, so offsets are sampled daily and each change
+// is narrowed down to the second with a binary search.
+func zoneTransitions(loc *time.Location, from, to time.Time) []zoneTransition {
+	var transitions []zoneTransition
+
+	_, prevOffset := from.In(loc).Zone()
+	for day := from; day.Before(to); {
+		next := day.Add(24 * time.Hour)
+		_, offset := next.In(loc).Zone()
+		if offset != prevOffset {
+			lo, hi := day.Unix(), next.Unix()
+			for hi-lo > 1 {
+				mid := lo + (hi-lo)/2
+				if _, o := time.Unix(mid, 0).In(loc).Zone(); o == prevOffset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+
+			at := time.Unix(hi, 0).In(loc)
+			name, _ := at.Zone()
+			transitions = append(transitions, zoneTransition{
+				at:         at.UTC(),
+				offsetFrom: prevOffset,
+				offsetTo:   offset,
+				name:       name,
+				isDST:      at.IsDST(),
+			})
+			prevOffset = offset
+		}
+		day = next
+	}
+
+	return transitions
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // the zones below don't depend on the host's zone database
+	"unicode/utf8"
+)
+
+func TestFoldICalLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines int
+	}{
+		{"short", "SUMMARY:Games night", 1},
+		{"exactly the limit", "SUMMARY:" + strings.Repeat("a", 67), 1},
+		{"one over", "SUMMARY:" + strings.Repeat("a", 68), 2},
+		{"ascii", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30), 5},
+		{"two-byte runes", "SUMMARY:" + strings.Repeat("é", 100), 3},
+		{"three-byte runes", "SUMMARY:" + strings.Repeat("€", 60), 3},
+		{"four-byte runes", "SUMMARY:" + strings.Repeat("🎉", 40), 3},
+		{"mixed", "LOCATION:" + strings.Repeat("Café ☕ 🎉 ", 20), 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := FoldICalLine(tt.line)
+			lines := strings.Split(folded, "\r\n")
+			if len(lines) != tt.lines {
+				t.Errorf("folded into %d lines, want %d:\n%s", len(lines), tt.lines, folded)
+			}
+			for i, line := range lines {
+				if len(line) > icalLineLimit {
+					t.Errorf("line %d is %d octets", i, len(line))
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d doesn't start with a space: %q", i, line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, line)
+				}
+			}
+			if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != tt.line {
+				t.Errorf("unfolds to %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestEscapeICalText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Games night", "Games night"},
+		{"Pizza, pop; and more", `Pizza\, pop\; and more`},
+		{`C:\Users`, `C:\\Users`},
+		{`\n is not a newline`, `\\n is not a newline`},
+		{"line one\nline two", `line one\nline two`},
+		{"windows\r\nline", `windows\nline`},
+		{"old mac\rline", `old mac\nline`},
+		{"Colons: and \"quotes\" are fine", "Colons: and \"quotes\" are fine"},
+		{"Café ☕", "Café ☕"},
+	}
+	for _, tt := range tests {
+		if got := EscapeICalText(tt.in); got != tt.want {
+			t.Errorf("EscapeICalText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatICalDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{90 * time.Minute, "PT1H30M"},
+		{time.Hour, "PT1H"},
+		{45 * time.Second, "PT45S"},
+		{24 * time.Hour, "P1D"},
+		{26*time.Hour + 5*time.Second, "P1DT2H5S"},
+		{-15 * time.Minute, "-PT15M"},
+	}
+	for _, tt := range tests {
+		if got := FormatICalDuration(tt.in); got != tt.want {
+			t.Errorf("FormatICalDuration(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatICalOffset(t *testing.T) {
+	tests := []struct {
+		in   int
+		want string
+	}{
+		{0, "+0000"},
+		{-5 * 3600, "-0500"},
+		{5*3600 + 30*60, "+0530"},
+		{-(3*3600 + 30*60), "-0330"},
+		{-(4*3600 + 56*60 + 2), "-045602"},
+	}
+	for _, tt := range tests {
+		if got := formatICalOffset(tt.in); got != tt.want {
+			t.Errorf("formatICalOffset(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// components returns the content lines of each BEGIN:name ... END:name block
+// in an unfolded stream
+func components(stream, name string) [][]string {
+	var found [][]string
+	var current []string
+	inside := false
+	for _, line := range strings.Split(strings.ReplaceAll(stream, "\r\n ", ""), "\r\n") {
+		switch {
+		case line == "BEGIN:"+name:
+			inside, current = true, nil
+		case line == "END:"+name:
+			inside = false
+			found = append(found, current)
+		case inside:
+			current = append(current, line)
+		}
+	}
+	return found
+}
+
+func TestICalBuilderTimezone(t *testing.T) {
+	tests := []struct {
+		zone     string
+		from, to time.Time
+		want     []string
+	}{
+		{
+			zone: "America/Toronto",
+			from: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"STANDARD DTSTART:20260101T000000 TZOFFSETFROM:-0500 TZOFFSETTO:-0500 TZNAME:EST",
+				"DAYLIGHT DTSTART:20260308T020000 TZOFFSETFROM:-0500 TZOFFSETTO:-0400 TZNAME:EDT",
+				"STANDARD DTSTART:20261101T020000 TZOFFSETFROM:-0400 TZOFFSETTO:-0500 TZNAME:EST",
+			},
+		},
+		{
+			// Southern hemisphere: the year starts in daylight time
+			zone: "Australia/Sydney",
+			from: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"DAYLIGHT DTSTART:20260101T000000 TZOFFSETFROM:+1100 TZOFFSETTO:+1100 TZNAME:AEDT",
+				"STANDARD DTSTART:20260405T030000 TZOFFSETFROM:+1100 TZOFFSETTO:+1000 TZNAME:AEST",
+				"DAYLIGHT DTSTART:20261004T020000 TZOFFSETFROM:+1000 TZOFFSETTO:+1100 TZNAME:AEDT",
+			},
+		},
+		{
+			zone: "Asia/Kolkata",
+			from: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"STANDARD DTSTART:20260101T000000 TZOFFSETFROM:+0530 TZOFFSETTO:+0530 TZNAME:IST",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := &ICalBuilder{}
+			b.Timezone(loc, tt.from, tt.to)
+			stream := b.sb.String()
+
+			timezones := components(stream, "VTIMEZONE")
+			if len(timezones) != 1 || timezones[0][0] != "TZID:"+tt.zone {
+				t.Fatalf("VTIMEZONE = %q, want one for %s", timezones, tt.zone)
+			}
+
+			// Observances in the order written, one line each
+			var got []string
+			for _, line := range timezones[0][1:] {
+				switch {
+				case strings.HasPrefix(line, "BEGIN:"):
+					got = append(got, strings.TrimPrefix(line, "BEGIN:"))
+				case strings.HasPrefix(line, "END:"):
+				default:
+					got[len(got)-1] += " " + line
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("observances:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestICalBuilderStream(t *testing.T) {
+	b := NewICalBuilder("-//Wat2Do//Events//EN", "Clubs, Events; More")
+	b.Begin("VEVENT")
+	b.Text("SUMMARY", strings.Repeat("Café night; ", 10))
+	b.End("VEVENT")
+	stream := b.String()
+
+	if !strings.HasPrefix(stream, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(stream, "END:VCALENDAR\r\n") {
+		t.Errorf("stream isn't a VCALENDAR:\n%s", stream)
+	}
+	if strings.Contains(strings.ReplaceAll(stream, "\r\n", ""), "\n") {
+		t.Error("stream has a bare LF")
+	}
+	if !strings.Contains(stream, "X-WR-CALNAME:Clubs\\, Events\\; More\r\n") {
+		t.Errorf("calendar name isn't escaped:\n%s", stream)
+	}
+	for _, line := range strings.Split(stream, "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+	}
+}
@@ -2,20 +2,29 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
+// maxOccurrences caps how many dates a single submitted event may carry
+const maxOccurrences = 100
+
+// EventOccurrence is a validated occurrence from event submission data
+type EventOccurrence struct {
+	DtstartUTC time.Time
+	DtendUTC   *time.Time
+	TZ         string
+}
+
 // ValidateEventData validates event submission data
-// Returns cleaned data or validation error
+// Returns cleaned data or validation error. Besides the required title and
+// location, the cleaned map may hold: description, food, school, club_type,
+// the *_handle fields, source_url and source_image_url (string), price
+// (float64), registration (bool), categories ([]string) and occurrences
+// ([]EventOccurrence, always present and non-empty).
 func ValidateEventData(data map[string]interface{}) (map[string]interface{}, error) {
-	// TODO: Implement event data validation
-	// 1. Check required fields (title, location, occurrences)
-	// 2. Validate field types and formats
-	// 3. Sanitize text fields
-	// 4. Validate occurrences array
-	// 5. Return cleaned data or error
-
 	cleaned := make(map[string]interface{})
 
 	// Validate title
@@ -32,11 +41,128 @@ func ValidateEventData(data map[string]interface{}) (map[string]interface{}, err
 	}
 	cleaned["location"] = strings.TrimSpace(location)
 
-	// TODO: Add more validation for other fields
+	// Optional text fields
+	for _, field := range []string{
+		"description", "food", "school", "club_type",
+		"ig_handle", "discord_handle", "x_handle", "tiktok_handle", "fb_handle", "other_handle",
+	} {
+		value, present := data[field]
+		if !present || value == nil {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", field)
+		}
+		if text = SanitizeString(text); text != "" {
+			cleaned[field] = text
+		}
+	}
+
+	for _, field := range []string{"source_url", "source_image_url"} {
+		value, present := data[field]
+		if !present || value == nil || value == "" {
+			continue
+		}
+		link, ok := value.(string)
+		if !ok || !ValidateURL(link) {
+			return nil, fmt.Errorf("%s must be an http(s) URL", field)
+		}
+		cleaned[field] = link
+	}
+
+	if value, present := data["price"]; present && value != nil {
+		price, ok := value.(float64)
+		if !ok || price < 0 {
+			return nil, errors.New("price must be a non-negative number")
+		}
+		cleaned["price"] = price
+	}
+
+	if value, present := data["registration"]; present && value != nil {
+		registration, ok := value.(bool)
+		if !ok {
+			return nil, errors.New("registration must be a boolean")
+		}
+		cleaned["registration"] = registration
+	}
+
+	if value, present := data["categories"]; present && value != nil {
+		raw, ok := value.([]interface{})
+		if !ok {
+			return nil, errors.New("categories must be an array of strings")
+		}
+		categories := make([]string, 0, len(raw))
+		for _, item := range raw {
+			category, ok := item.(string)
+			if !ok {
+				return nil, errors.New("categories must be an array of strings")
+			}
+			if category = SanitizeString(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+		cleaned["categories"] = categories
+	}
+
+	occurrences, err := validateOccurrences(data["occurrences"])
+	if err != nil {
+		return nil, err
+	}
+	cleaned["occurrences"] = occurrences
 
 	return cleaned, nil
 }
 
+// validateOccurrences checks the occurrences array: each entry needs a
+// dtstart_utc, and may carry a dtend_utc after it and an IANA tz name
+func validateOccurrences(value interface{}) ([]EventOccurrence, error) {
+	raw, ok := value.([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, errors.New("at least one occurrence is required")
+	}
+	if len(raw) > maxOccurrences {
+		return nil, fmt.Errorf("at most %d occurrences are allowed", maxOccurrences)
+	}
+
+	occurrences := make([]EventOccurrence, 0, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("occurrence %d must be an object", i+1)
+		}
+
+		startRaw, _ := entry["dtstart_utc"].(string)
+		start, err := ParseUTCDateTime(startRaw)
+		if err != nil {
+			return nil, fmt.Errorf("occurrence %d has an invalid dtstart_utc", i+1)
+		}
+		occurrence := EventOccurrence{DtstartUTC: start}
+
+		if endRaw, _ := entry["dtend_utc"].(string); endRaw != "" {
+			end, err := ParseUTCDateTime(endRaw)
+			if err != nil {
+				return nil, fmt.Errorf("occurrence %d has an invalid dtend_utc", i+1)
+			}
+			if !end.After(start) {
+				return nil, fmt.Errorf("occurrence %d ends before it starts", i+1)
+			}
+			occurrence.DtendUTC = &end
+		}
+
+		if tz, _ := entry["tz"].(string); tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				return nil, fmt.Errorf("occurrence %d has an unknown tz %q", i+1, tz)
+			}
+			occurrence.TZ = tz
+		}
+
+		occurrences = append(occurrences, occurrence)
+	}
+
+	return occurrences, nil
+}
+
 // ValidateEmail validates email format
 func ValidateEmail(email string) bool {
 	// TODO: Implement email validation
@@ -0,0 +1,4 @@
+-- Rollback calendar feed tokens
+-- Migration: 000002_calendar_feed_tokens
+
+DROP TABLE IF EXISTS calendar_feed_tokens;
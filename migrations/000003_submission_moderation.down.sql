@@ -0,0 +1,8 @@
+-- Rollback submission moderation
+-- Migration: 000003_submission_moderation
+
+DROP TABLE IF EXISTS event_submission_audits;
+DROP INDEX IF EXISTS idx_event_submissions_submitted_by;
+DROP INDEX IF EXISTS idx_event_submissions_status;
+ALTER TABLE event_submissions DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE event_submissions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,25 @@
+-- Moderation state and audit trail for event submissions
+-- Migration: 000003_submission_moderation
+
+ALTER TABLE event_submissions ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'pending';
+ALTER TABLE event_submissions ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+
+-- Backfill submissions reviewed before status was tracked
+UPDATE event_submissions s
+SET status = CASE e.status WHEN 'CONFIRMED' THEN 'approved' WHEN 'REJECTED' THEN 'rejected' ELSE 'pending' END
+FROM events e
+WHERE e.id = s.created_event_id AND s.reviewed_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_event_submissions_status ON event_submissions(status);
+CREATE INDEX IF NOT EXISTS idx_event_submissions_submitted_by ON event_submissions(submitted_by);
+
+CREATE TABLE IF NOT EXISTS event_submission_audits (
+    id SERIAL PRIMARY KEY,
+    submission_id INTEGER NOT NULL REFERENCES event_submissions(id) ON DELETE CASCADE,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_submission_audits_submission_id ON event_submission_audits(submission_id);
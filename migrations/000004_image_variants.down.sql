@@ -0,0 +1,8 @@
+-- Rollback processed image variants
+-- Migration: 000004_image_variants
+
+DROP TABLE IF EXISTS uploaded_images;
+
+DROP INDEX IF EXISTS idx_events_image_phash;
+ALTER TABLE events DROP COLUMN IF EXISTS image_phash;
+ALTER TABLE events DROP COLUMN IF EXISTS image_variants;
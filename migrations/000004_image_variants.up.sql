@@ -0,0 +1,18 @@
+-- Processed image variants and perceptual hashes
+-- Migration: 000004_image_variants
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS image_variants JSONB;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS image_phash VARCHAR(16);
+
+CREATE INDEX IF NOT EXISTS idx_events_image_phash ON events(image_phash);
+
+CREATE TABLE IF NOT EXISTS uploaded_images (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL UNIQUE,
+    variants JSONB NOT NULL,
+    phash VARCHAR(16) NOT NULL,
+    uploaded_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploaded_images_uploaded_by ON uploaded_images(uploaded_by);
@@ -0,0 +1,4 @@
+-- Rollback duplicate flags
+-- Migration: 000005_duplicate_flags
+
+DROP TABLE IF EXISTS event_duplicate_flags;
@@ -0,0 +1,19 @@
+-- Likely duplicate events awaiting admin review
+-- Migration: 000005_duplicate_flags
+
+CREATE TABLE IF NOT EXISTS event_duplicate_flags (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    duplicate_of_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL,
+    reasons JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    resolved_by VARCHAR(255),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, duplicate_of_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_duplicate_flags_duplicate_of_id ON event_duplicate_flags(duplicate_of_id);
+CREATE INDEX IF NOT EXISTS idx_event_duplicate_flags_status ON event_duplicate_flags(status);
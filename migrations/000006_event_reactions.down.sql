@@ -0,0 +1,4 @@
+-- Rollback event reactions
+-- Migration: 000006_event_reactions
+
+DROP TABLE IF EXISTS event_reactions;
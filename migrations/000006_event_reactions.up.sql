@@ -0,0 +1,13 @@
+-- Per-user emoji reactions; events.reactions keeps the totals
+-- Migration: 000006_event_reactions
+
+CREATE TABLE IF NOT EXISTS event_reactions (
+    id SERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_user_emoji ON event_reactions(event_id, user_id, emoji);
+CREATE INDEX IF NOT EXISTS idx_event_reactions_user_id ON event_reactions(user_id);
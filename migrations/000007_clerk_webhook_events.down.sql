@@ -0,0 +1,4 @@
+-- Rollback Clerk webhook events
+-- Migration: 000007_clerk_webhook_events
+
+DROP TABLE IF EXISTS clerk_webhook_events;
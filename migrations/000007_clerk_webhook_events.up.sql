@@ -0,0 +1,11 @@
+-- Processed Clerk webhook deliveries, keyed by svix-id for idempotency
+-- Migration: 000007_clerk_webhook_events
+
+CREATE TABLE IF NOT EXISTS clerk_webhook_events (
+    id SERIAL PRIMARY KEY,
+    svix_id VARCHAR(255) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clerk_webhook_events_svix_id ON clerk_webhook_events(svix_id);
@@ -0,0 +1,9 @@
+-- Rollback payment checkout
+-- Migration: 000008_payment_checkout
+
+DROP TABLE IF EXISTS stripe_webhook_events;
+
+DROP INDEX IF EXISTS idx_payments_stripe_session_id;
+DROP INDEX IF EXISTS idx_payments_event_id;
+
+ALTER TABLE payments DROP COLUMN IF EXISTS event_id;
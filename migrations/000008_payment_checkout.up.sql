@@ -0,0 +1,16 @@
+-- Event tickets paid through Stripe Checkout, and processed Stripe webhooks
+-- Migration: 000008_payment_checkout
+
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS event_id INTEGER REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_payments_event_id ON payments(event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_stripe_session_id ON payments(stripe_session_id);
+
+CREATE TABLE IF NOT EXISTS stripe_webhook_events (
+    id SERIAL PRIMARY KEY,
+    stripe_event_id VARCHAR(255) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stripe_webhook_events_stripe_event_id ON stripe_webhook_events(stripe_event_id);
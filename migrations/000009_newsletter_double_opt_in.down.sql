@@ -0,0 +1,6 @@
+-- Rollback newsletter double opt-in
+-- Migration: 000009_newsletter_double_opt_in
+
+ALTER TABLE newsletter_subscribers ALTER COLUMN active SET DEFAULT true;
+ALTER TABLE newsletter_subscribers DROP COLUMN IF EXISTS confirmation_sent_at;
+ALTER TABLE newsletter_subscribers DROP COLUMN IF EXISTS confirmed_at;
@@ -0,0 +1,9 @@
+-- Double opt-in: subscribers stay inactive until they confirm their address
+-- Migration: 000009_newsletter_double_opt_in
+
+ALTER TABLE newsletter_subscribers ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE newsletter_subscribers ADD COLUMN IF NOT EXISTS confirmation_sent_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE newsletter_subscribers ALTER COLUMN active SET DEFAULT false;
+
+-- Existing subscribers signed up before confirmation was required
+UPDATE newsletter_subscribers SET confirmed_at = created_at WHERE active AND confirmed_at IS NULL;
@@ -0,0 +1,5 @@
+-- Rollback digest runs
+-- Migration: 000010_digest_runs
+
+DROP TABLE IF EXISTS digest_deliveries;
+DROP TABLE IF EXISTS digest_runs;
@@ -0,0 +1,28 @@
+-- Weekly digest send runs and per-subscriber deliveries
+-- Migration: 000010_digest_runs
+
+CREATE TABLE IF NOT EXISTS digest_runs (
+    id SERIAL PRIMARY KEY,
+    scheduled_for TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    event_count INTEGER DEFAULT 0,
+    sent_count INTEGER DEFAULT 0,
+    failed_count INTEGER DEFAULT 0,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_runs_scheduled_for ON digest_runs(scheduled_for);
+
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES digest_runs(id) ON DELETE CASCADE,
+    subscriber_id INTEGER NOT NULL REFERENCES newsletter_subscribers(id) ON DELETE CASCADE,
+    status VARCHAR(32) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_run_subscriber ON digest_deliveries(run_id, subscriber_id);
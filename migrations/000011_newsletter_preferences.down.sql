@@ -0,0 +1,9 @@
+-- Rollback newsletter preferences
+-- Migration: 000011_newsletter_preferences
+
+DELETE FROM digest_runs WHERE frequency <> 'weekly';
+DROP INDEX IF EXISTS idx_digest_runs_frequency_scheduled;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_runs_scheduled_for ON digest_runs(scheduled_for);
+ALTER TABLE digest_runs DROP COLUMN IF EXISTS frequency;
+
+DROP TABLE IF EXISTS newsletter_preferences;
@@ -0,0 +1,21 @@
+-- Per-subscriber newsletter preferences and daily digest runs
+-- Migration: 000011_newsletter_preferences
+
+CREATE TABLE IF NOT EXISTS newsletter_preferences (
+    id SERIAL PRIMARY KEY,
+    subscriber_id INTEGER NOT NULL REFERENCES newsletter_subscribers(id) ON DELETE CASCADE,
+    categories JSONB DEFAULT '[]',
+    club_types JSONB DEFAULT '[]',
+    schools JSONB DEFAULT '[]',
+    free_food_only BOOLEAN DEFAULT FALSE,
+    frequency VARCHAR(16) NOT NULL DEFAULT 'weekly',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_newsletter_preferences_subscriber_id ON newsletter_preferences(subscriber_id);
+
+ALTER TABLE digest_runs ADD COLUMN IF NOT EXISTS frequency VARCHAR(16) NOT NULL DEFAULT 'weekly';
+
+DROP INDEX IF EXISTS idx_digest_runs_scheduled_for;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digest_runs_frequency_scheduled ON digest_runs(frequency, scheduled_for);
@@ -0,0 +1,4 @@
+-- Rollback email queue
+-- Migration: 000012_email_queue
+
+DROP TABLE IF EXISTS email_queue;
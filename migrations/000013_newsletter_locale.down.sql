@@ -0,0 +1,4 @@
+-- Rollback newsletter locale
+-- Migration: 000013_newsletter_locale
+
+ALTER TABLE newsletter_subscribers DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,4 @@
+-- Newsletter subscriber email language
+-- Migration: 000013_newsletter_locale
+
+ALTER TABLE newsletter_subscribers ADD COLUMN IF NOT EXISTS locale VARCHAR(8) NOT NULL DEFAULT 'en';
@@ -0,0 +1,5 @@
+-- Rollback one live calendar feed token per user
+-- Migration: 000014_calendar_feed_token_per_user
+
+DROP INDEX IF EXISTS idx_calendar_feed_tokens_live_user_id;
+CREATE INDEX IF NOT EXISTS idx_calendar_feed_tokens_user_id ON calendar_feed_tokens(user_id);
@@ -0,0 +1,17 @@
+-- One live calendar feed token per user
+-- Migration: 000014_calendar_feed_token_per_user
+
+-- Revoke all but each user's newest live token
+UPDATE calendar_feed_tokens t
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE t.deleted_at IS NULL
+  AND EXISTS (
+    SELECT 1 FROM calendar_feed_tokens newer
+    WHERE newer.user_id = t.user_id
+      AND newer.deleted_at IS NULL
+      AND (newer.created_at, newer.id) > (t.created_at, t.id)
+  );
+
+DROP INDEX IF EXISTS idx_calendar_feed_tokens_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feed_tokens_live_user_id
+    ON calendar_feed_tokens(user_id) WHERE deleted_at IS NULL;
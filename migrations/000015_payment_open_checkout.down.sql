@@ -0,0 +1,4 @@
+-- Rollback one open checkout per user and event
+-- Migration: 000015_payment_open_checkout
+
+DROP INDEX IF EXISTS idx_payments_open_checkout;
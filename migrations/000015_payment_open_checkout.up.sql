@@ -0,0 +1,20 @@
+-- One open checkout per user and event
+-- Migration: 000015_payment_open_checkout
+
+-- Expire all but each user's newest pending payment for an event
+UPDATE payments p
+SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+WHERE p.status = 'pending'
+  AND p.deleted_at IS NULL
+  AND p.event_id IS NOT NULL
+  AND EXISTS (
+    SELECT 1 FROM payments newer
+    WHERE newer.user_id = p.user_id
+      AND newer.event_id = p.event_id
+      AND newer.status = 'pending'
+      AND newer.deleted_at IS NULL
+      AND newer.id > p.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_open_checkout
+    ON payments(user_id, event_id) WHERE status = 'pending' AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+-- Rollback image variant sizes
+-- Migration: 000016_image_sizes
+
+ALTER TABLE uploaded_images DROP COLUMN IF EXISTS sizes;
+ALTER TABLE events DROP COLUMN IF EXISTS image_sizes;
@@ -0,0 +1,5 @@
+-- Byte sizes of processed image variants, for feed enclosures
+-- Migration: 000016_image_sizes
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS image_sizes JSONB;
+ALTER TABLE uploaded_images ADD COLUMN IF NOT EXISTS sizes JSONB;